@@ -2,19 +2,50 @@ package auth
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/middleware"
 	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/providers"
 	"github.com/kreasimaju/auth/utils"
 	"github.com/labstack/echo/v4"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// Handler milik instance default yang diuji langsung oleh pengujian API
+var (
+	registerHandler   = func(c echo.Context) error { return Default().registerHandler(c) }
+	loginHandler      = func(c echo.Context) error { return Default().loginHandler(c) }
+	requestOTPHandler = func(c echo.Context) error { return Default().requestOTPHandler(c) }
+	verifyOTPHandler  = func(c echo.Context) error { return Default().verifyOTPHandler(c) }
+)
+
 // setupAPITest menyiapkan server Echo dan database untuk pengujian API
 func setupAPITest(t *testing.T) (*echo.Echo, *gorm.DB) {
 	// Siapkan database
@@ -24,13 +55,14 @@ func setupAPITest(t *testing.T) (*echo.Echo, *gorm.DB) {
 	}
 
 	// Migrasi skema
-	err = db.AutoMigrate(&models.User{}, &models.OTPCode{}, &models.PasswordReset{}, &models.OAuth{})
+	err = db.AutoMigrate(&models.User{}, &models.OTPCode{}, &models.OTPRequest{}, &models.OTPQuotaLock{}, &models.PasswordReset{}, &models.OAuth{}, &models.RefreshToken{})
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
 
 	// Setel database global
 	utils.DB = db
+	resetDefault()
 
 	// Siapkan server Echo
 	e := echo.New()
@@ -188,7 +220,13 @@ func TestOTPAPI(t *testing.T) {
 	_, err := RegisterLocal("otptest@example.com", "password123", "OTP", "Test", "081234567890", "ID")
 	assert.NoError(t, err)
 
+	// Kode OTP hanya dapat dibaca dari pesan yang dikirim
 	var otpCode string
+	Default().SetOTPSender("email", OTPSenderFunc(func(ctx context.Context, to, message string) error {
+		otpCode = regexp.MustCompile(`\d{6}`).FindString(message)
+		return nil
+	}))
+	t.Cleanup(func() { delete(Default().senders, "email") })
 
 	// Test case: Request OTP berhasil
 	t.Run("Request OTP", func(t *testing.T) {
@@ -210,11 +248,12 @@ func TestOTPAPI(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
 
-		// Ambil kode OTP dari database untuk verifikasi selanjutnya
+		// Database hanya menyimpan hash kode OTP
 		var otpRecord models.OTPCode
 		err = db.Where("type = ? AND target = ? AND purpose = ?", "email", "otptest@example.com", "login").First(&otpRecord).Error
 		assert.NoError(t, err)
-		otpCode = otpRecord.Code
+		assert.NotEmpty(t, otpCode)
+		assert.NotContains(t, otpRecord.CodeHash, otpCode)
 	})
 
 	// Test case: Verifikasi OTP berhasil
@@ -246,3 +285,1848 @@ func TestOTPAPI(t *testing.T) {
 		assert.Contains(t, resp, "user")
 	})
 }
+
+// doJSON mengirim request JSON ke server Echo dan mengembalikan recorder beserta body yang di-decode
+func doJSON(t *testing.T, e *echo.Echo, method, path string, body interface{}, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		jsonData, _ := json.Marshal(body)
+		reader = bytes.NewReader(jsonData)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+// setupInstanceAPITest menyiapkan server Echo dengan rute dari instance Auth baru
+func setupInstanceAPITest(t *testing.T, cfg config.Config) (*echo.Echo, *Auth) {
+	t.Helper()
+
+	a := newTestAuth(t, cfg)
+	e := echo.New()
+	a.RegisterRoutes(e)
+
+	// Rute terlindungi untuk memeriksa token
+	e.GET("/api/me", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, c.Get("user"))
+	}, a.Middleware())
+
+	return e, a
+}
+
+// TestLogoutAPI menguji endpoint logout dan logout dari semua perangkat
+func TestLogoutAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{})
+	_, err := a.RegisterLocal("logout@example.com", "password123", "Logout", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	login := func() (string, string) {
+		rec, resp := doJSON(t, e, http.MethodPost, "/auth/login", map[string]string{
+			"identifier": "logout@example.com",
+			"password":   "password123",
+		}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return resp["token"].(string), resp["refresh_token"].(string)
+	}
+
+	t.Run("Logout Revokes Token", func(t *testing.T) {
+		token, refreshToken := login()
+
+		rec, _ := doJSON(t, e, http.MethodGet, "/api/me", nil, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec, _ = doJSON(t, e, http.MethodPost, "/auth/logout", map[string]string{
+			"refresh_token": refreshToken,
+		}, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec, resp := doJSON(t, e, http.MethodGet, "/api/me", nil, token)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Token has been revoked", resp["error"])
+
+		// Refresh token dari login yang sama ikut dicabut
+		_, err := a.RefreshTokens(refreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("Logout Everywhere", func(t *testing.T) {
+		a.SetRevocationStore(utils.NewMemoryRevocationStore())
+		e := echo.New()
+		a.RegisterRoutes(e)
+		e.GET("/api/me", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, a.Middleware())
+
+		phone, _ := login()
+		laptop, laptopRefresh := login()
+
+		rec, _ := doJSON(t, e, http.MethodPost, "/auth/logout-all", nil, phone)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec, _ = doJSON(t, e, http.MethodGet, "/api/me", nil, laptop)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		_, err := a.RefreshTokens(laptopRefresh)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+		// Login baru setelah logout tetap berlaku
+		fresh, _ := login()
+		rec, _ = doJSON(t, e, http.MethodGet, "/api/me", nil, fresh)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+// TestJWKSAndKeyRotation menguji publikasi JWKS, rotasi kunci dan verifikasi
+// token oleh layanan lain melalui JWKSVerifier
+func TestJWKSAndKeyRotation(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{JWT: config.JWT{Algorithm: "ES256"}})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	verifier := NewJWKSVerifier(server.URL+"/.well-known/jwks.json", WithJWKSRefreshInterval(0))
+	user := &models.User{Email: "jwks@example.com", Role: "user"}
+	user.ID = 3
+
+	oldToken, err := a.GenerateToken(user)
+	assert.NoError(t, err)
+	oldKID := a.KeyRing().SigningKey().ID
+
+	t.Run("JWKS Publishes Public Key", func(t *testing.T) {
+		rec, resp := doJSON(t, e, http.MethodGet, "/.well-known/jwks.json", nil, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Cache-Control"), "max-age")
+
+		keys := resp["keys"].([]interface{})
+		assert.Len(t, keys, 1)
+		jwk := keys[0].(map[string]interface{})
+		assert.Equal(t, oldKID, jwk["kid"])
+		assert.Equal(t, "EC", jwk["kty"])
+		assert.NotContains(t, jwk, "d")
+	})
+
+	t.Run("Verifier Accepts Token", func(t *testing.T) {
+		token, err := verifier.Validate(oldToken)
+		assert.NoError(t, err)
+		assert.True(t, token.Valid)
+	})
+
+	t.Run("Rotation Keeps Old Key For Verification", func(t *testing.T) {
+		assert.NoError(t, a.RotateSigningKey())
+		assert.NotEqual(t, oldKID, a.KeyRing().SigningKey().ID)
+
+		newToken, err := a.GenerateToken(user)
+		assert.NoError(t, err)
+
+		// Token lama dan baru tetap valid di instance maupun di verifier
+		_, err = a.ValidateToken(oldToken)
+		assert.NoError(t, err)
+		_, err = verifier.Validate(oldToken)
+		assert.NoError(t, err)
+		_, err = verifier.Validate(newToken)
+		assert.NoError(t, err)
+
+		assert.Len(t, a.JWKS().Keys, 2)
+	})
+
+	t.Run("Shared Key Dir Across Instances", func(t *testing.T) {
+		cfg := config.Config{JWT: config.JWT{Algorithm: "ES256", KeyDir: t.TempDir()}}
+		first := newTestAuth(t, cfg)
+		defer first.Close()
+
+		minted, err := first.GenerateToken(user)
+		assert.NoError(t, err)
+		assert.NoError(t, first.RotateSigningKey())
+
+		// Instance kedua dengan konfigurasi yang sama memuat kunci lama dan
+		// kunci hasil rotasi dari direktori
+		second := newTestAuth(t, cfg)
+		defer second.Close()
+
+		_, err = second.ValidateToken(minted)
+		assert.NoError(t, err)
+		assert.Equal(t, first.KeyRing().SigningKey().ID, second.KeyRing().SigningKey().ID)
+		assert.Len(t, first.JWKS().Keys, 2)
+		assert.Len(t, second.JWKS().Keys, 2)
+
+		fresh, err := second.GenerateToken(user)
+		assert.NoError(t, err)
+		_, err = first.ValidateToken(fresh)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Rotation Refused With Private Key File", func(t *testing.T) {
+		generated, err := utils.GenerateSigningKey("ES256")
+		assert.NoError(t, err)
+		pemData, err := utils.EncodePrivateKeyPEM(generated.Private)
+		assert.NoError(t, err)
+		path := t.TempDir() + "/jwt.pem"
+		assert.NoError(t, os.WriteFile(path, pemData, 0600))
+
+		fileKey := newTestAuth(t, config.Config{JWT: config.JWT{Algorithm: "ES256", PrivateKeyFile: path}})
+		assert.Error(t, fileKey.RotateSigningKey())
+		assert.Equal(t, generated.ID, fileKey.KeyRing().SigningKey().ID)
+	})
+
+	t.Run("JWKS Outage Does Not Refetch Per Token", func(t *testing.T) {
+		var fetches atomic.Int32
+		down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer down.Close()
+
+		outage := NewJWKSVerifier(down.URL, WithJWKSRefreshInterval(time.Minute))
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := outage.Validate(oldToken)
+				assert.Error(t, err)
+			}()
+		}
+		wg.Wait()
+
+		// Percobaan berikutnya menunggu refresh interval setelah kegagalan
+		_, err := outage.Validate(oldToken)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("HMAC Keys Are Not Published", func(t *testing.T) {
+		_, hmac := setupInstanceAPITest(t, config.Config{})
+		assert.Empty(t, hmac.JWKS().Keys)
+		assert.Error(t, hmac.RotateSigningKey())
+	})
+}
+
+// TestSessionAPI menguji login dengan sesi cookie, batas tidak aktif,
+// batas absolut dan logout
+func TestSessionAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{Session: config.Session{
+		Enabled:        true,
+		IdleTimeout:    3600,
+		TrustedOrigins: []string{"https://app.example.com"},
+	}})
+	e.GET("/api/session", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, c.Get("user"))
+	}, a.SessionMiddleware())
+
+	_, err := a.RegisterLocal("session@example.com", "password123", "Session", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	login := func() *http.Cookie {
+		rec, resp := doJSON(t, e, http.MethodPost, "/auth/login", map[string]string{
+			"identifier": "session@example.com",
+			"password":   "password123",
+		}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, resp, "token")
+		assert.Contains(t, resp, "session_expires_at")
+
+		cookies := rec.Result().Cookies()
+		assert.Len(t, cookies, 1)
+		return cookies[0]
+	}
+
+	withCookie := func(method, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Login Sets Secure Cookie", func(t *testing.T) {
+		cookie := login()
+		assert.Equal(t, config.DefaultSessionCookie, cookie.Name)
+		assert.True(t, cookie.HttpOnly)
+		assert.True(t, cookie.Secure)
+		assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+		rec := withCookie(http.MethodGet, "/api/session", cookie)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var claims map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &claims))
+		assert.Equal(t, "session@example.com", claims["email"])
+		assert.NotZero(t, claims["sid"])
+
+		// Database hanya menyimpan hash dari nilai cookie
+		var count int64
+		a.DB().Model(&models.Session{}).Where("token = ?", cookie.Value).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Idle Timeout", func(t *testing.T) {
+		cookie := login()
+		a.DB().Model(&models.Session{}).Where("token = ?", utils.HashToken(cookie.Value)).
+			Update("last_seen_at", time.Now().Add(-2*time.Hour))
+
+		rec := withCookie(http.MethodGet, "/api/session", cookie)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Sliding Expiry Extends Last Seen", func(t *testing.T) {
+		cookie := login()
+		old := time.Now().Add(-30 * time.Minute)
+		a.DB().Model(&models.Session{}).Where("token = ?", utils.HashToken(cookie.Value)).
+			Update("last_seen_at", old)
+
+		rec := withCookie(http.MethodGet, "/api/session", cookie)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var session models.Session
+		a.DB().Where("token = ?", utils.HashToken(cookie.Value)).First(&session)
+		assert.True(t, session.LastSeenAt.After(old.Add(time.Minute)))
+	})
+
+	t.Run("Absolute Expiry", func(t *testing.T) {
+		cookie := login()
+		a.DB().Model(&models.Session{}).Where("token = ?", utils.HashToken(cookie.Value)).
+			Update("expires_at", time.Now().Add(-time.Second))
+
+		rec := withCookie(http.MethodGet, "/api/session", cookie)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Logout Ends Session", func(t *testing.T) {
+		cookie := login()
+
+		rec := withCookie(http.MethodPost, "/auth/logout", cookie)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		cleared := rec.Result().Cookies()
+		assert.Len(t, cleared, 1)
+		assert.Empty(t, cleared[0].Value)
+
+		rec = withCookie(http.MethodGet, "/api/session", cookie)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Cross-Origin Request Rejected", func(t *testing.T) {
+		cookie := login()
+		withOrigin := func(header, value string) int {
+			req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+			req.Header.Set(header, value)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec.Code
+		}
+
+		// Formulir dari situs lain tidak dapat memakai cookie sesi
+		assert.Equal(t, http.StatusForbidden, withOrigin("Origin", "https://evil.example"))
+		assert.Equal(t, http.StatusForbidden, withOrigin("Sec-Fetch-Site", "cross-site"))
+		rec := withCookie(http.MethodGet, "/api/session", cookie)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Origin yang sama dan origin tepercaya diizinkan
+		assert.Equal(t, http.StatusOK, withOrigin("Origin", "http://example.com"))
+		cookie = login()
+		assert.Equal(t, http.StatusOK, withOrigin("Origin", "https://app.example.com"))
+	})
+}
+
+// TestSessionManagementAPI menguji daftar sesi dan pencabutan sesi perangkat lain
+func TestSessionManagementAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{Session: config.Session{Enabled: true}})
+
+	user, err := a.RegisterLocal("devices@example.com", "password123", "Device", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	const (
+		chromeWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+		safariIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	)
+
+	_, laptop, err := a.CreateSession(user.ID, chromeWindows, "10.0.0.1")
+	assert.NoError(t, err)
+	phoneSession, _, err := a.CreateSession(user.ID, safariIPhone, "10.0.0.2")
+	assert.NoError(t, err)
+
+	request := func(method, path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: config.DefaultSessionCookie, Value: laptop})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	t.Run("List Sessions", func(t *testing.T) {
+		rec, resp := request(http.MethodGet, "/auth/sessions")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		sessions := resp["sessions"].([]interface{})
+		assert.Len(t, sessions, 2)
+
+		names := map[string]bool{}
+		for _, s := range sessions {
+			session := s.(map[string]interface{})
+			names[session["device_name"].(string)] = session["current"].(bool)
+			assert.NotEmpty(t, session["last_seen_at"])
+		}
+		assert.Equal(t, map[string]bool{"Chrome di Windows": true, "Safari di iOS": false}, names)
+	})
+
+	t.Run("Cannot Revoke Other User Session", func(t *testing.T) {
+		other, err := a.RegisterLocal("other-device@example.com", "password123", "Other", "Test", "", "ID")
+		assert.NoError(t, err)
+		otherSession, _, err := a.CreateSession(other.ID, chromeWindows, "10.0.0.3")
+		assert.NoError(t, err)
+
+		rec, _ := request(http.MethodDelete, fmt.Sprintf("/auth/sessions/%d", otherSession.ID))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Revoke Other Sessions", func(t *testing.T) {
+		rec, _ := request(http.MethodDelete, "/auth/sessions")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		sessions, err := a.ListSessions(user.ID)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+		assert.NotEqual(t, phoneSession.ID, sessions[0].ID)
+	})
+}
+
+// TestPasswordResetAPI menguji lupa password dan reset password dengan token
+func TestPasswordResetAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{})
+
+	user, err := a.RegisterLocal("reset@example.com", "password123", "Reset", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	t.Run("Forgot Password Does Not Reveal Account", func(t *testing.T) {
+		rec, known := doJSON(t, e, http.MethodPost, "/auth/forgot-password", map[string]string{
+			"identifier": "reset@example.com",
+		}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec, unknown := doJSON(t, e, http.MethodPost, "/auth/forgot-password", map[string]string{
+			"identifier": "nobody@example.com",
+		}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, known, unknown)
+	})
+
+	t.Run("Reset Password", func(t *testing.T) {
+		pair, err := a.IssueTokens(user)
+		assert.NoError(t, err)
+
+		stale, _, err := a.issueUserToken(user.ID, passwordResetType, 3600)
+		assert.NoError(t, err)
+		token, _, err := a.issueUserToken(user.ID, passwordResetType, 3600)
+		assert.NoError(t, err)
+
+		// Token lama dibatalkan saat token baru diminta
+		rec, _ := doJSON(t, e, http.MethodPost, "/auth/reset-password", map[string]string{
+			"token": stale, "password": "newpassword123",
+		}, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec, resp := doJSON(t, e, http.MethodPost, "/auth/reset-password", map[string]string{
+			"token": token, "password": "short",
+		}, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, resp["error"], "at least")
+
+		rec, _ = doJSON(t, e, http.MethodPost, "/auth/reset-password", map[string]string{
+			"token": token, "password": "newpassword123",
+		}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		_, err = a.LoginLocal("reset@example.com", "newpassword123")
+		assert.NoError(t, err)
+		_, err = a.LoginLocal("reset@example.com", "password123")
+		assert.Error(t, err)
+
+		// Token hanya dapat dipakai sekali dan token lama pengguna dicabut
+		rec, resp = doJSON(t, e, http.MethodPost, "/auth/reset-password", map[string]string{
+			"token": token, "password": "anotherpassword",
+		}, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "Invalid or expired token", resp["error"])
+
+		_, err = a.RefreshTokens(pair.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
+
+// TestEmailVerificationAPI menguji verifikasi email, cooldown pengiriman
+// ulang dan middleware RequireVerified
+func TestEmailVerificationAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{
+		EmailVerification: config.EmailVerification{Enabled: true, ResendCooldown: 60},
+	})
+	e.GET("/api/verified", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, a.Middleware(middleware.RequireVerified()))
+
+	user, err := a.RegisterLocal("verify@example.com", "password123", "Verify", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	token, err := a.GenerateToken(user)
+	assert.NoError(t, err)
+
+	t.Run("Unverified User Is Rejected", func(t *testing.T) {
+		rec, _ := doJSON(t, e, http.MethodGet, "/api/me", nil, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec, resp := doJSON(t, e, http.MethodGet, "/api/verified", nil, token)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Email address is not verified", resp["error"])
+	})
+
+	t.Run("Resend Cooldown", func(t *testing.T) {
+		// Registrasi sudah mengirim token, sehingga pengiriman ulang ditolak
+		rec, resp := doJSON(t, e, http.MethodPost, "/auth/resend-verification", nil, token)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+		assert.Greater(t, resp["retry_after"], float64(0))
+	})
+
+	t.Run("Verify Email", func(t *testing.T) {
+		verifyToken, _, err := a.issueUserToken(user.ID, emailVerifyType, 3600)
+		assert.NoError(t, err)
+
+		rec, _ := doJSON(t, e, http.MethodPost, "/auth/verify-email", map[string]string{"token": "invalid"}, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec, _ = doJSON(t, e, http.MethodPost, "/auth/verify-email", map[string]string{"token": verifyToken}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Token hanya dapat dipakai sekali
+		rec, _ = doJSON(t, e, http.MethodPost, "/auth/verify-email", map[string]string{"token": verifyToken}, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		verified, err := a.FindUserByEmail("verify@example.com")
+		assert.NoError(t, err)
+		assert.True(t, verified.IsVerified)
+
+		fresh, err := a.GenerateToken(verified)
+		assert.NoError(t, err)
+		rec, _ = doJSON(t, e, http.MethodGet, "/api/verified", nil, fresh)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec, _ = doJSON(t, e, http.MethodPost, "/auth/resend-verification", nil, fresh)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+// TestOTPAttemptLimitAPI menguji pembatalan OTP setelah terlalu banyak percobaan salah
+func TestOTPAttemptLimitAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{OTP: config.OTP{MaxAttempts: 3}})
+
+	_, err := a.RegisterLocal("attempts@example.com", "password123", "Attempt", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	verify := func(code string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		return doJSON(t, e, http.MethodPost, "/auth/verify-otp", map[string]string{
+			"contact": "attempts@example.com",
+			"type":    "email",
+			"code":    code,
+		}, "")
+	}
+
+	otpCode, err := a.RequestOTPLogin("attempts@example.com", "email", "ID")
+	assert.NoError(t, err)
+	wrong := "0000000"[:len(otpCode.Code)]
+	if wrong == otpCode.Code {
+		wrong = "1111111"[:len(otpCode.Code)]
+	}
+
+	for i := 0; i < 2; i++ {
+		rec, _ := verify(wrong)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	rec, resp := verify(wrong)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, resp["error"], "Too many attempts")
+
+	// Kode yang benar pun ditolak setelah batas tercapai
+	rec, _ = verify(otpCode.Code)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// Kode baru dapat dipakai kembali
+	otpCode, err = a.RequestOTPLogin("attempts@example.com", "email", "ID")
+	assert.NoError(t, err)
+	rec, _ = verify(otpCode.Code)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// TestOTPQuotaAPI menguji cooldown dan kuota pengiriman OTP
+func TestOTPQuotaAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{OTP: config.OTP{Quota: config.OTPQuota{
+		ResendCooldown: 60,
+		HourlyLimit:    2,
+		IPHourlyLimit:  3,
+	}}})
+
+	_, err := a.RegisterLocal("quota@example.com", "password123", "Quota", "Test", "081234567890", "ID")
+	assert.NoError(t, err)
+
+	request := func(contact, otpType string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		return doJSON(t, e, http.MethodPost, "/auth/request-otp", map[string]string{
+			"contact": contact,
+			"type":    otpType,
+		}, "")
+	}
+	// backdate memindahkan semua catatan pengiriman keluar dari cooldown
+	backdate := func() {
+		err := a.DB().Model(&models.OTPRequest{}).Where("1 = 1").
+			Update("created_at", time.Now().Add(-2*time.Minute)).Error
+		assert.NoError(t, err)
+	}
+
+	rec, resp := request("+6281234567890", "sms")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, float64(60), resp["retry_after"])
+
+	// Penulisan nomor yang berbeda tetap dihitung sebagai target yang sama
+	rec, resp = request("081234567890", "sms")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.InDelta(t, 60, resp["retry_after"], 2)
+
+	backdate()
+	rec, _ = request("081234567890", "sms")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Kuota per jam untuk nomor ini sudah habis
+	backdate()
+	rec, resp = request("081234567890", "sms")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Greater(t, resp["retry_after"], float64(60))
+
+	// Target lain dari IP yang sama terkena kuota per IP
+	rec, _ = request("quota@example.com", "email")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec, _ = request("other@example.com", "email")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// Permintaan bersamaan untuk target yang sama hanya lolos satu kali
+	var wg sync.WaitGroup
+	var reserved, limited atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := a.ReserveOTPSend("race@example.com", fmt.Sprintf("198.51.100.%d", i))
+			var retry *RetryAfterError
+			switch {
+			case err == nil:
+				reserved.Add(1)
+			case errors.As(err, &retry):
+				limited.Add(1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), reserved.Load())
+	assert.Equal(t, int32(9), limited.Load())
+}
+
+// TestOTPRegistrationAPI menguji registrasi dengan OTP
+func TestOTPRegistrationAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{})
+
+	_, err := a.RegisterLocal("taken@example.com", "password123", "Taken", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	codes := map[string]string{}
+	a.SetOTPSender("sms", OTPSenderFunc(func(ctx context.Context, to, message string) error {
+		codes[to] = regexp.MustCompile(`\d{6}`).FindString(message)
+		return nil
+	}))
+
+	request := func(contact, otpType string) *httptest.ResponseRecorder {
+		rec, _ := doJSON(t, e, http.MethodPost, "/auth/request-otp", map[string]string{
+			"contact":    contact,
+			"type":       otpType,
+			"purpose":    "register",
+			"password":   "password123",
+			"first_name": "New",
+		}, "")
+		return rec
+	}
+	verify := func(contact, code string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		return doJSON(t, e, http.MethodPost, "/auth/verify-otp", map[string]string{
+			"contact": contact,
+			"type":    "sms",
+			"code":    code,
+			"purpose": "register",
+		}, "")
+	}
+
+	rec := request("taken@example.com", "email")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = request("081298765432", "sms")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	code := codes["+6281298765432"]
+	assert.NotEmpty(t, code)
+
+	// Pengguna belum dibuat sebelum OTP diverifikasi
+	_, err = a.FindUserByPhone("+6281298765432")
+	assert.Error(t, err)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	rec, _ = verify("081298765432", wrong)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, resp := verify("081298765432", code)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["token"])
+	user := resp["user"].(map[string]interface{})
+	assert.Equal(t, "+6281298765432", user["phone"])
+	assert.Equal(t, true, user["is_verified"])
+
+	// Kode dan registrasi tertunda hanya dapat dipakai sekali
+	rec, _ = verify("081298765432", code)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	_, err = a.RequestOTPRegistration("081298765432", "sms", "ID", "", "", "")
+	assert.ErrorIs(t, err, ErrContactRegistered)
+
+	// Pengguna dengan password dapat login dengan nomor teleponnya
+	_, err = a.LoginLocal("+6281298765432", "password123")
+	assert.NoError(t, err)
+
+	// Beberapa pengguna tanpa email dapat terdaftar
+	rec = request("081311112222", "sms")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec, _ = verify("081311112222", codes["+6281311112222"])
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Melengkapi email tidak mencabut verifikasi nomor telepon
+	a.SetOTPSender("email", OTPSenderFunc(func(ctx context.Context, to, message string) error { return nil }))
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/email", map[string]string{"email": "new@example.com"}, resp["token"].(string))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	stored, err := a.FindUserByEmail("new@example.com")
+	assert.NoError(t, err)
+	assert.True(t, stored.IsVerified)
+	assert.False(t, stored.EmailVerified)
+
+	verifyToken, _, err := a.issueUserToken(stored.ID, emailVerifyType, 3600)
+	assert.NoError(t, err)
+	verified, err := a.VerifyEmail(verifyToken)
+	assert.NoError(t, err)
+	assert.True(t, verified.IsVerified)
+	assert.True(t, verified.EmailVerified)
+}
+
+// TestTOTPAPI menguji pendaftaran TOTP dan login dua langkah
+func TestTOTPAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{})
+	_, err := a.RegisterLocal("totp@example.com", "password123", "TOTP", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	login := func() map[string]interface{} {
+		rec, resp := doJSON(t, e, http.MethodPost, "/auth/login", map[string]string{
+			"identifier": "totp@example.com",
+			"password":   "password123",
+		}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return resp
+	}
+	token := login()["token"].(string)
+
+	rec, resp := doJSON(t, e, http.MethodPost, "/auth/mfa/totp/enroll", nil, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	secret := resp["secret"].(string)
+	assert.Contains(t, resp["url"], "otpauth://totp/")
+	qr, err := base64.StdEncoding.DecodeString(resp["qr_code"].(string))
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(qr, []byte("\x89PNG")))
+
+	// Secret disimpan terenkripsi dan TOTP belum aktif sebelum dikonfirmasi
+	var user models.User
+	assert.NoError(t, a.DB().Where("email = ?", "totp@example.com").First(&user).Error)
+	assert.NotContains(t, user.TOTPSecret, secret)
+	assert.False(t, user.TOTPEnabled)
+	assert.NotEmpty(t, login()["token"])
+
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/totp/confirm", map[string]string{"code": "000000"}, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	assert.NoError(t, err)
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/totp/confirm", map[string]string{"code": code}, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Login kini menghasilkan tantangan MFA, bukan token
+	resp = login()
+	assert.Equal(t, true, resp["mfa_required"])
+	assert.Nil(t, resp["token"])
+	mfaToken := resp["mfa_token"].(string)
+
+	verify := func(code string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		return doJSON(t, e, http.MethodPost, "/auth/mfa/verify", map[string]string{
+			"mfa_token": mfaToken,
+			"code":      code,
+		}, "")
+	}
+
+	// Kode yang sudah dipakai saat konfirmasi ditolak
+	rec, _ = verify(code)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	code, err = totp.GenerateCode(secret, time.Now().Add(30*time.Second))
+	assert.NoError(t, err)
+	rec, resp = verify(code)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["token"])
+
+	// Tantangan hanya dapat dipakai sekali
+	rec, _ = verify(code)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Login dengan OTP juga meminta faktor kedua
+	var otp string
+	a.SetOTPSender("email", OTPSenderFunc(func(ctx context.Context, to, message string) error {
+		otp = regexp.MustCompile(`\d{6}`).FindString(message)
+		return nil
+	}))
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/request-otp", map[string]string{
+		"contact": "totp@example.com",
+		"type":    "email",
+	}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec, resp = doJSON(t, e, http.MethodPost, "/auth/verify-otp", map[string]string{
+		"contact": "totp@example.com",
+		"type":    "email",
+		"code":    otp,
+	}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, true, resp["mfa_required"])
+	assert.Nil(t, resp["token"])
+	assert.NotEmpty(t, resp["mfa_token"])
+
+	// Kode cadangan pengganti OTP sudah memenuhi faktor kedua
+	user = models.User{}
+	assert.NoError(t, a.DB().Where("email = ?", "totp@example.com").First(&user).Error)
+	backupCodes, err := a.GenerateBackupCodes(user.ID)
+	assert.NoError(t, err)
+	rec, resp = doJSON(t, e, http.MethodPost, "/auth/verify-otp", map[string]string{
+		"contact": "totp@example.com",
+		"type":    "email",
+		"code":    backupCodes[0],
+	}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, resp["mfa_required"])
+	assert.NotEmpty(t, resp["token"])
+
+	remaining, err := a.BackupCodesRemaining(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), remaining)
+}
+
+// TestBackupCodesAPI menguji kode cadangan sebagai pengganti OTP
+func TestBackupCodesAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{})
+	_, err := a.RegisterLocal("backup@example.com", "password123", "Backup", "Test", "081234567890", "ID")
+	assert.NoError(t, err)
+
+	rec, resp := doJSON(t, e, http.MethodPost, "/auth/login", map[string]string{
+		"identifier": "backup@example.com",
+		"password":   "password123",
+	}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	token := resp["token"].(string)
+
+	generate := func(body map[string]string) []string {
+		rec, resp := doJSON(t, e, http.MethodPost, "/auth/mfa/backup-codes", body, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var codes []string
+		for _, code := range resp["codes"].([]interface{}) {
+			codes = append(codes, code.(string))
+		}
+		return codes
+	}
+	remaining := func() interface{} {
+		rec, resp := doJSON(t, e, http.MethodGet, "/auth/security", nil, token)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return resp["backup_codes_remaining"]
+	}
+	loginWithCode := func(code string) int {
+		rec, _ := doJSON(t, e, http.MethodPost, "/auth/verify-otp", map[string]string{
+			"contact": "081234567890",
+			"type":    "sms",
+			"code":    code,
+		}, "")
+		return rec.Code
+	}
+
+	// Token saja tidak cukup untuk membuat kode cadangan
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/backup-codes", nil, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/backup-codes", map[string]string{"password": "wrong-password"}, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/backup-codes", map[string]string{"code": "123456"}, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	codes := generate(map[string]string{"password": "password123"})
+	assert.Len(t, codes, 10)
+	assert.Equal(t, float64(10), remaining())
+
+	// Database hanya menyimpan hash kode cadangan
+	var stored models.BackupCode
+	assert.NoError(t, a.DB().First(&stored).Error)
+	assert.NotContains(t, stored.CodeHash, strings.ReplaceAll(codes[0], "-", ""))
+
+	assert.Equal(t, http.StatusOK, loginWithCode(codes[0]))
+	assert.Equal(t, http.StatusUnauthorized, loginWithCode(codes[0]))
+
+	// Tanda hubung dan huruf besar diabaikan
+	assert.Equal(t, http.StatusOK, loginWithCode(strings.ToUpper(strings.ReplaceAll(codes[1], "-", ""))))
+	assert.Equal(t, float64(8), remaining())
+
+	// Kode cadangan yang sudah dipakai tidak dapat membuat set baru
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/backup-codes", map[string]string{"code": codes[0]}, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Set baru membatalkan kode lama
+	generate(map[string]string{"code": codes[3]})
+	assert.Equal(t, http.StatusUnauthorized, loginWithCode(codes[2]))
+	assert.Equal(t, float64(10), remaining())
+
+	// Pengguna yang mendaftar dengan OTP tanpa password memakai kode OTP baru
+	var otp string
+	a.SetOTPSender("sms", OTPSenderFunc(func(ctx context.Context, to, message string) error {
+		otp = regexp.MustCompile(`\d{6}`).FindString(message)
+		return nil
+	}))
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/request-otp", map[string]string{
+		"contact": "081355556666",
+		"type":    "sms",
+		"purpose": "register",
+	}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec, resp = doJSON(t, e, http.MethodPost, "/auth/verify-otp", map[string]string{
+		"contact": "081355556666",
+		"type":    "sms",
+		"code":    otp,
+		"purpose": "register",
+	}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	token = resp["token"].(string)
+
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/backup-codes", map[string]string{"password": ""}, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	_, err = a.RequestOTPLogin("081355556666", "sms", "ID")
+	assert.NoError(t, err)
+	wrong := "000000"
+	if otp == wrong {
+		wrong = "111111"
+	}
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/backup-codes", map[string]string{"code": wrong, "type": "sms"}, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	codes = generate(map[string]string{"code": otp, "type": "sms"})
+	assert.Len(t, codes, 10)
+
+	// Kode OTP yang sama tidak dapat dipakai dua kali
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/mfa/backup-codes", map[string]string{"code": otp, "type": "sms"}, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// softAuthenticator adalah authenticator WebAuthn berbasis software dengan
+// attestation "none" untuk menguji ceremony passkey tanpa browser
+type softAuthenticator struct {
+	t            *testing.T
+	rpID         string
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+// newSoftAuthenticator membuat authenticator dengan key ECDSA P-256 baru
+func newSoftAuthenticator(t *testing.T, rpID, origin string) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	assert.NoError(t, err)
+
+	return &softAuthenticator{t: t, rpID: rpID, origin: origin, key: key, credentialID: credentialID}
+}
+
+// create menjawab opsi navigator.credentials.create
+func (s *softAuthenticator) create(options map[string]interface{}) map[string]interface{} {
+	publicKey := options["publicKey"].(map[string]interface{})
+	user := publicKey["user"].(map[string]interface{})
+	userHandle, err := base64.RawURLEncoding.DecodeString(user["id"].(string))
+	assert.NoError(s.t, err)
+	s.userHandle = userHandle
+
+	coseKey, err := webauthncbor.Marshal(map[int]interface{}{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: s.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: s.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	assert.NoError(s.t, err)
+
+	// AAGUID nol, panjang credential ID, credential ID dan public key
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(s.credentialID)))
+	attested = append(attested, s.credentialID...)
+	attested = append(attested, coseKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": s.authData(0x45, attested), // UP, UV dan AT
+	})
+	assert.NoError(s.t, err)
+
+	id := base64.RawURLEncoding.EncodeToString(s.credentialID)
+	return map[string]interface{}{
+		"id":    id,
+		"rawId": id,
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    s.clientData("webauthn.create", publicKey["challenge"].(string)),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestation),
+		},
+	}
+}
+
+// get menjawab opsi navigator.credentials.get dengan signature baru
+func (s *softAuthenticator) get(options map[string]interface{}) map[string]interface{} {
+	publicKey := options["publicKey"].(map[string]interface{})
+	clientData := s.clientData("webauthn.get", publicKey["challenge"].(string))
+	clientDataJSON, _ := base64.RawURLEncoding.DecodeString(clientData)
+
+	s.signCount++
+	authData := s.authData(0x05, nil) // UP dan UV
+	clientHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+	assert.NoError(s.t, err)
+
+	id := base64.RawURLEncoding.EncodeToString(s.credentialID)
+	return map[string]interface{}{
+		"id":    id,
+		"rawId": id,
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    clientData,
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+			"userHandle":        base64.RawURLEncoding.EncodeToString(s.userHandle),
+		},
+	}
+}
+
+// authData menyusun authenticator data: hash RP ID, flag, sign count dan
+// data credential bila ada
+func (s *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(s.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, s.signCount)
+	return append(data, attested...)
+}
+
+// clientData menyusun clientDataJSON dalam base64url
+func (s *softAuthenticator) clientData(ceremony, challenge string) string {
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    s.origin,
+	})
+	assert.NoError(s.t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// TestPasskeyAPI menguji pendaftaran passkey, login tanpa password, passkey
+// sebagai langkah kedua dan deteksi authenticator yang digandakan
+func TestPasskeyAPI(t *testing.T) {
+	e, a := setupInstanceAPITest(t, config.Config{
+		WebAuthn: config.WebAuthn{
+			Enabled:   true,
+			RPID:      "example.com",
+			RPOrigins: []string{"https://example.com"},
+		},
+	})
+	_, err := a.RegisterLocal("passkey@example.com", "password123", "Passkey", "Test", "", "ID")
+	assert.NoError(t, err)
+
+	login := func() map[string]interface{} {
+		rec, resp := doJSON(t, e, http.MethodPost, "/auth/login", map[string]string{
+			"identifier": "passkey@example.com",
+			"password":   "password123",
+		}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return resp
+	}
+	token := login()["token"].(string)
+	authenticator := newSoftAuthenticator(t, "example.com", "https://example.com")
+
+	// Pendaftaran passkey
+	rec, resp := doJSON(t, e, http.MethodPost, "/auth/webauthn/register/begin", nil, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	registration := map[string]interface{}{
+		"session_token": resp["session_token"],
+		"name":          "Laptop",
+		"credential":    authenticator.create(resp["options"].(map[string]interface{})),
+	}
+	rec, resp = doJSON(t, e, http.MethodPost, "/auth/webauthn/register/finish", registration, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Laptop", resp["name"])
+	assert.Equal(t, "none", resp["attestation_type"])
+
+	// Token ceremony hanya berlaku sekali
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/webauthn/register/finish", registration, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Passkey menjadi langkah kedua untuk login dengan password
+	resp = login()
+	assert.Equal(t, true, resp["mfa_required"])
+	assert.Equal(t, []interface{}{"webauthn"}, resp["methods"])
+	mfaToken := resp["mfa_token"].(string)
+
+	rec, resp = doJSON(t, e, http.MethodPost, "/auth/mfa/webauthn/begin", map[string]string{"mfa_token": mfaToken}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec, resp = doJSON(t, e, http.MethodPost, "/auth/mfa/webauthn/verify", map[string]interface{}{
+		"mfa_token":     mfaToken,
+		"session_token": resp["session_token"],
+		"credential":    authenticator.get(resp["options"].(map[string]interface{})),
+	}, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["token"])
+
+	// Login tanpa password menemukan pengguna dari user handle
+	passwordless := func() (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec, resp := doJSON(t, e, http.MethodPost, "/auth/webauthn/login/begin", nil, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return doJSON(t, e, http.MethodPost, "/auth/webauthn/login/finish", map[string]interface{}{
+			"session_token": resp["session_token"],
+			"credential":    authenticator.get(resp["options"].(map[string]interface{})),
+		}, "")
+	}
+	rec, resp = passwordless()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["token"])
+	assert.Equal(t, "passkey@example.com", resp["user"].(map[string]interface{})["email"])
+
+	var stored models.WebAuthnCredential
+	assert.NoError(t, a.DB().First(&stored).Error)
+	assert.Equal(t, uint32(2), stored.SignCount)
+	assert.NotNil(t, stored.LastUsedAt)
+
+	// Sign count yang mundur menandakan authenticator digandakan
+	authenticator.signCount = 0
+	rec, _ = passwordless()
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec, resp = doJSON(t, e, http.MethodGet, "/auth/webauthn/credentials", nil, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	passkeys := resp["passkeys"].([]interface{})
+	assert.Len(t, passkeys, 1)
+	assert.Equal(t, true, passkeys[0].(map[string]interface{})["clone_warning"])
+
+	rec, resp = doJSON(t, e, http.MethodGet, "/auth/security", nil, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, float64(1), resp["passkeys"])
+
+	// Menghapus passkey terakhir mengembalikan login satu langkah
+	rec, _ = doJSON(t, e, http.MethodDelete, fmt.Sprintf("/auth/webauthn/credentials/%d", stored.ID), nil, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, login()["token"])
+
+	// Endpoint passkey tidak tersedia jika webauthn tidak diaktifkan
+	e, _ = setupInstanceAPITest(t, config.Config{})
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/webauthn/login/begin", nil, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// oauthLogin menjalankan alur redirect dan callback OAuth untuk provider
+// dengan code yang diberikan, termasuk cookie state dari langkah redirect
+func oauthLogin(t *testing.T, e *echo.Echo, provider, code string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/"+provider, nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if !assert.Equal(t, http.StatusTemporaryRedirect, rec.Code) {
+		return rec, nil
+	}
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	assert.NoError(t, err)
+	query := url.Values{"code": {code}, "state": {location.Query().Get("state")}}
+
+	req = httptest.NewRequest(http.MethodGet, "/auth/"+provider+"/callback?"+query.Encode(), nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+// TestGitHubOAuthAPI menguji login GitHub terhadap server GitHub tiruan
+func TestGitHubOAuthAPI(t *testing.T) {
+	verified := true
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			assert.NoError(t, r.ParseForm())
+			fmt.Fprintf(w, `{"access_token":"token-%s","token_type":"bearer"}`, r.Form.Get("code"))
+		case "/user":
+			id := 4242
+			if r.Header.Get("Authorization") == "Bearer token-other" {
+				id = 99
+			}
+			fmt.Fprintf(w, `{"id":%d,"login":"octocat","name":"Mona Lisa"}`, id)
+		case "/user/emails":
+			fmt.Fprintf(w, `[{"email":"old@example.com","primary":false,"verified":true},
+				{"email":"github@example.com","primary":true,"verified":%t}]`, verified)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer github.Close()
+
+	e, a := setupInstanceAPITest(t, config.Config{
+		Providers: config.Providers{
+			GitHub: config.OAuth{
+				Enabled:      true,
+				ClientID:     "github-client",
+				ClientSecret: "github-secret",
+				CallbackURL:  "http://localhost/auth/github/callback",
+				AuthURL:      github.URL + "/login/oauth/authorize",
+				TokenURL:     github.URL + "/login/oauth/access_token",
+				APIURL:       github.URL,
+			},
+		},
+	})
+	existing, err := a.RegisterLocal("github@example.com", "password123", "Mona", "Lisa", "", "ID")
+	assert.NoError(t, err)
+
+	// Redirect ke GitHub membawa client ID dan state acak
+	req := httptest.NewRequest(http.MethodGet, "/auth/github", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+	assert.Contains(t, rec.Header().Get("Location"), github.URL+"/login/oauth/authorize?")
+	assert.Contains(t, rec.Header().Get("Location"), "client_id=github-client")
+
+	// Callback tanpa cookie state ditolak
+	rec, _ = doJSON(t, e, http.MethodGet, "/auth/github/callback?code=good&state=forged", nil, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Pengguna yang belum memverifikasi email tidak dihubungkan otomatis
+	rec, _ = oauthLogin(t, e, "github", "good")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.NoError(t, a.DB().Model(existing).Updates(map[string]interface{}{"is_verified": true, "email_verified": true}).Error)
+
+	// Email utama yang terverifikasi menghubungkan akun GitHub dengan pengguna yang ada
+	rec, resp := oauthLogin(t, e, "github", "good")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["token"])
+	assert.Equal(t, float64(existing.ID), resp["user"].(map[string]interface{})["id"])
+
+	rec, _ = oauthLogin(t, e, "github", "good")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var links []models.UserProvider
+	assert.NoError(t, a.DB().Where("provider_name = ?", "github").Find(&links).Error)
+	assert.Len(t, links, 1)
+	assert.Equal(t, "4242", links[0].ProviderID)
+	assert.Equal(t, "token-good", links[0].AccessToken)
+
+	// Pengguna dengan TOTP harus menyelesaikan faktor kedua setelah callback
+	enrollment, err := a.EnrollTOTP(existing.ID)
+	assert.NoError(t, err)
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, a.ConfirmTOTP(existing.ID, code))
+
+	rec, resp = oauthLogin(t, e, "github", "good")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, true, resp["mfa_required"])
+	assert.Equal(t, []interface{}{"totp"}, resp["methods"])
+	assert.Nil(t, resp["token"])
+
+	// Email utama yang belum diverifikasi memakai email terverifikasi lain
+	verified = false
+	rec, resp = oauthLogin(t, e, "github", "other")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "old@example.com", resp["user"].(map[string]interface{})["email"])
+	assert.Equal(t, "Mona", resp["user"].(map[string]interface{})["first_name"])
+
+	// Provider yang tidak diaktifkan tidak memiliki halaman login
+	e, _ = setupInstanceAPITest(t, config.Config{})
+	rec, _ = doJSON(t, e, http.MethodGet, "/auth/github", nil, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestFacebookOAuthAPI menguji Facebook Login terhadap Graph API tiruan,
+// termasuk akun tanpa email
+func TestFacebookOAuthAPI(t *testing.T) {
+	graph := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oauth/access_token":
+			assert.NoError(t, r.ParseForm())
+			fmt.Fprintf(w, `{"access_token":"token-%s","token_type":"bearer","expires_in":5183944}`, r.Form.Get("code"))
+		case "/me":
+			assert.Contains(t, r.URL.Query().Get("fields"), "email")
+			assert.NotEmpty(t, r.URL.Query().Get("appsecret_proof"))
+			if r.Header.Get("Authorization") == "Bearer token-phone" {
+				fmt.Fprint(w, `{"id":"2002","name":"Budi Santoso"}`)
+				return
+			}
+			fmt.Fprint(w, `{"id":"1001","name":"Siti Rahma","first_name":"Siti","last_name":"Rahma",
+				"email":"siti@example.com","picture":{"data":{"url":"https://example.com/siti.jpg"}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer graph.Close()
+
+	facebook := config.OAuth{
+		Enabled:      true,
+		ClientID:     "facebook-app",
+		ClientSecret: "facebook-secret",
+		CallbackURL:  "http://localhost/auth/facebook/callback",
+		AuthURL:      graph.URL + "/dialog/oauth",
+		TokenURL:     graph.URL + "/oauth/access_token",
+		APIURL:       graph.URL,
+	}
+
+	// Tanpa trust_email, email dari Facebook tidak dipakai untuk menghubungkan akun
+	e, a := setupInstanceAPITest(t, config.Config{Providers: config.Providers{Facebook: facebook}})
+	existing, err := a.RegisterLocal("siti@example.com", "password123", "Siti", "Rahma", "", "ID")
+	assert.NoError(t, err)
+	assert.NoError(t, a.DB().Model(existing).Updates(map[string]interface{}{"is_verified": true, "email_verified": true}).Error)
+
+	rec, resp := oauthLogin(t, e, "facebook", "email")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, float64(existing.ID), resp["user"].(map[string]interface{})["id"])
+	assert.Equal(t, "", resp["user"].(map[string]interface{})["email"])
+	assert.Equal(t, true, resp["user"].(map[string]interface{})["email_missing"])
+
+	facebook.TrustEmail = true
+	e, a = setupInstanceAPITest(t, config.Config{Providers: config.Providers{Facebook: facebook}})
+
+	rec, resp = oauthLogin(t, e, "facebook", "email")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["token"])
+	assert.Equal(t, "siti@example.com", resp["user"].(map[string]interface{})["email"])
+
+	user, err := a.FindUserByEmail("siti@example.com")
+	assert.NoError(t, err)
+	assert.True(t, user.IsVerified)
+	assert.Equal(t, "Rahma", user.LastName)
+
+	var link models.UserProvider
+	assert.NoError(t, a.DB().Where("provider_name = ? AND provider_id = ?", "facebook", "1001").First(&link).Error)
+	assert.Contains(t, link.Data, "siti.jpg")
+	assert.NotNil(t, link.ExpiresAt)
+
+	// Akun tanpa email dibuat dan dikenali kembali dari ID Facebook
+	rec, resp = oauthLogin(t, e, "facebook", "phone")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	first := resp["user"].(map[string]interface{})
+	assert.Equal(t, "", first["email"])
+	assert.Equal(t, "Budi", first["first_name"])
+	assert.Equal(t, "Santoso", first["last_name"])
+
+	rec, resp = oauthLogin(t, e, "facebook", "phone")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, first["id"], resp["user"].(map[string]interface{})["id"])
+
+	var users int64
+	assert.NoError(t, a.DB().Model(&models.User{}).Count(&users).Error)
+	assert.Equal(t, int64(2), users)
+}
+
+// TestTwitterOAuthAPI menguji login Twitter dengan PKCE terhadap server tiruan
+// dan melengkapi email pengguna setelahnya
+func TestTwitterOAuthAPI(t *testing.T) {
+	var challenge string
+	twitter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/2/oauth2/token":
+			assert.NoError(t, r.ParseForm())
+			clientID, secret, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "twitter-client", clientID)
+			assert.Equal(t, "twitter-secret", secret)
+
+			// Code verifier harus cocok dengan code challenge saat redirect
+			verifier := r.Form.Get("code_verifier")
+			assert.NotEmpty(t, verifier)
+			assert.Equal(t, challenge, oauth2.S256ChallengeFromVerifier(verifier))
+			fmt.Fprintf(w, `{"access_token":"token-%s","token_type":"bearer","expires_in":7200}`, r.Form.Get("code"))
+		case "/2/users/me":
+			assert.Equal(t, "Bearer token-good", r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"data":{"id":"1337","name":"Andi Wijaya","username":"andiw"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer twitter.Close()
+
+	e, a := setupInstanceAPITest(t, config.Config{
+		Providers: config.Providers{
+			Twitter: config.OAuth{
+				Enabled:      true,
+				ClientID:     "twitter-client",
+				ClientSecret: "twitter-secret",
+				CallbackURL:  "http://localhost/auth/twitter/callback",
+				AuthURL:      twitter.URL + "/i/oauth2/authorize",
+				TokenURL:     twitter.URL + "/2/oauth2/token",
+				APIURL:       twitter.URL,
+			},
+		},
+	})
+
+	// login mengikuti redirect ke Twitter, mencatat code challenge S256 lalu
+	// memanggil callback dengan cookie state dan verifier
+	login := func() (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, "/auth/twitter", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+
+		location, err := url.Parse(rec.Header().Get("Location"))
+		assert.NoError(t, err)
+		assert.Equal(t, "S256", location.Query().Get("code_challenge_method"))
+		challenge = location.Query().Get("code_challenge")
+
+		query := url.Values{"code": {"good"}, "state": {location.Query().Get("state")}}
+		req = httptest.NewRequest(http.MethodGet, "/auth/twitter/callback?"+query.Encode(), nil)
+		for _, cookie := range rec.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	// Callback tanpa cookie state dan verifier ditolak
+	rec, _ := doJSON(t, e, http.MethodGet, "/auth/twitter/callback?code=good&state=forged", nil, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Pengguna baru dibuat tanpa email
+	rec, resp := login()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	user := resp["user"].(map[string]interface{})
+	assert.Equal(t, "", user["email"])
+	assert.Equal(t, true, user["email_missing"])
+	assert.Equal(t, "Andi", user["first_name"])
+	token := resp["token"].(string)
+
+	// Login berikutnya dikenali dari ID Twitter
+	rec, resp = login()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, user["id"], resp["user"].(map[string]interface{})["id"])
+
+	// Email dilengkapi sekali dan harus diverifikasi
+	_, err := a.RegisterLocal("taken@example.com", "password123", "Taken", "User", "", "ID")
+	assert.NoError(t, err)
+
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/email", map[string]string{"email": "not an email"}, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/email", map[string]string{"email": "taken@example.com"}, token)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/email", map[string]string{"email": "andi@example.com"}, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec, _ = doJSON(t, e, http.MethodPost, "/auth/email", map[string]string{"email": "other@example.com"}, token)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	stored, err := a.FindUserByEmail("andi@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, user["id"], float64(stored.ID))
+	assert.False(t, stored.EmailVerified)
+
+	var tokens int64
+	assert.NoError(t, a.DB().Model(&models.Token{}).Where("owner_id = ? AND type = ?", stored.ID, emailVerifyType).Count(&tokens).Error)
+	assert.Equal(t, int64(1), tokens)
+}
+
+// TestOIDCAPI menguji provider OpenID Connect generik terhadap IdP tiruan
+// dengan dua realm, masing-masing terdaftar sebagai provider terpisah
+func TestOIDCAPI(t *testing.T) {
+	key, err := utils.GenerateSigningKey("RS256")
+	assert.NoError(t, err)
+	jwk, err := utils.PublicJWK(key)
+	assert.NoError(t, err)
+
+	var nonce, challenge string
+	claims := func(issuer string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            issuer,
+			"sub":            "sub-123",
+			"aud":            "app",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          nonce,
+			"email":          "oidc@example.com",
+			"email_verified": true,
+			"given_name":     "Rina",
+			"family_name":    "Putri",
+		}
+	}
+	// mutate mengubah klaim ID token untuk menguji penolakan
+	var mutate func(jwt.MapClaims)
+
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/realms/"), "/", 2)
+		issuer := idp.URL + "/realms/" + parts[0]
+		switch "/" + parts[len(parts)-1] {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"issuer":                           issuer,
+				"authorization_endpoint":           issuer + "/auth",
+				"token_endpoint":                   issuer + "/token",
+				"jwks_uri":                         issuer + "/certs",
+				"code_challenge_methods_supported": []string{"plain", "S256"},
+			})
+		case "/certs":
+			json.NewEncoder(w).Encode(utils.JWKS{Keys: []utils.JWK{jwk}})
+		case "/token":
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, challenge, oauth2.S256ChallengeFromVerifier(r.Form.Get("code_verifier")))
+
+			c := claims(issuer)
+			if mutate != nil {
+				mutate(c)
+			}
+			idToken, err := utils.SignJWT(c, key)
+			assert.NoError(t, err)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access", "token_type": "Bearer", "expires_in": 300, "id_token": idToken,
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer idp.Close()
+
+	realm := func(name string, trustEmail bool) config.OAuth {
+		return config.OAuth{
+			Enabled:      true,
+			ClientID:     "app",
+			ClientSecret: "secret",
+			CallbackURL:  "http://localhost/auth/" + name + "/callback",
+			Issuer:       idp.URL + "/realms/" + name,
+			TrustEmail:   trustEmail,
+		}
+	}
+	e, a := setupInstanceAPITest(t, config.Config{
+		Providers: config.Providers{
+			OIDC: map[string]config.OAuth{
+				"keycloak": realm("keycloak", true),
+				"okta":     realm("okta", true),
+				"partner":  realm("partner", false),
+			},
+		},
+	})
+
+	// login mengikuti redirect ke IdP, mencatat nonce dan code challenge lalu
+	// memanggil callback dengan cookie dari redirect
+	login := func(name string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, "/auth/"+name, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
+
+		location, err := url.Parse(rec.Header().Get("Location"))
+		assert.NoError(t, err)
+		assert.Equal(t, idp.URL+"/realms/"+name+"/auth", location.Scheme+"://"+location.Host+location.Path)
+		assert.Contains(t, location.Query().Get("scope"), "openid")
+		nonce = location.Query().Get("nonce")
+		challenge = location.Query().Get("code_challenge")
+
+		query := url.Values{"code": {"good"}, "state": {location.Query().Get("state")}}
+		req = httptest.NewRequest(http.MethodGet, "/auth/"+name+"/callback?"+query.Encode(), nil)
+		for _, cookie := range rec.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resp map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	rec, resp := login("keycloak")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["token"])
+	user := resp["user"].(map[string]interface{})
+	assert.Equal(t, "oidc@example.com", user["email"])
+	assert.Equal(t, "Rina", user["first_name"])
+
+	stored, err := a.FindUserByEmail("oidc@example.com")
+	assert.NoError(t, err)
+	assert.True(t, stored.IsVerified)
+
+	// Email terverifikasi yang sama dari realm lain menghubungkan pengguna yang sama
+	rec, resp = login("okta")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, user["id"], resp["user"].(map[string]interface{})["id"])
+
+	var links []models.UserProvider
+	assert.NoError(t, a.DB().Where("user_id = ?", stored.ID).Order("provider_name").Find(&links).Error)
+	assert.Len(t, links, 2)
+	assert.Equal(t, "keycloak", links[0].ProviderName)
+	assert.Equal(t, "okta", links[1].ProviderName)
+
+	// Tanpa trust_email, email milik pengguna yang ada tidak menghubungkan akun
+	rec, resp = login("partner")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, user["id"], resp["user"].(map[string]interface{})["id"])
+	assert.Equal(t, "", resp["user"].(map[string]interface{})["email"])
+
+	var partner models.UserProvider
+	assert.NoError(t, a.DB().Where("provider_name = ?", "partner").First(&partner).Error)
+	assert.NotEqual(t, stored.ID, partner.UserID)
+
+	// ID token dengan nonce, audience, issuer atau masa berlaku yang salah ditolak
+	rejected := map[string]func(jwt.MapClaims){
+		"Nonce":    func(c jwt.MapClaims) { c["nonce"] = "replayed" },
+		"Audience": func(c jwt.MapClaims) { c["aud"] = "other-app" },
+		"Issuer":   func(c jwt.MapClaims) { c["iss"] = idp.URL + "/realms/okta" },
+		"Expired":  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+	}
+	for name, m := range rejected {
+		t.Run("Rejects "+name, func(t *testing.T) {
+			mutate = m
+			defer func() { mutate = nil }()
+
+			// Detail validasi hanya dicatat di log, bukan dikirim ke klien
+			rec, resp := login("keycloak")
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Equal(t, "Failed to authenticate with keycloak", resp["error"])
+		})
+	}
+
+	// Callback tanpa cookie ditolak dan nama yang tidak terdaftar tidak ada
+	rec, _ = doJSON(t, e, http.MethodGet, "/auth/keycloak/callback?code=good&state=forged", nil, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = doJSON(t, e, http.MethodGet, "/auth/auth0", nil, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestOIDCDiscoveryConcurrent menguji bahwa permintaan bersamaan berbagi satu
+// pembacaan dokumen discovery, termasuk saat pembacaan tersebut gagal
+func TestOIDCDiscoveryConcurrent(t *testing.T) {
+	var hits atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/auth",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/certs",
+		})
+	}))
+	defer idp.Close()
+
+	p := providers.NewOIDC("sso", config.OAuth{ClientID: "app", Issuer: idp.URL},
+		func(string) jwt.Keyfunc { return nil })
+
+	authURLs := func() []error {
+		errs := make([]error, 10)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = p.AuthURL("state")
+			}(i)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	// Kegagalan tidak disimpan, tetapi pemanggil yang menunggu berbagi hasilnya
+	for _, err := range authURLs() {
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int32(1), hits.Load())
+
+	fail.Store(false)
+	for _, err := range authURLs() {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), hits.Load())
+
+	// Dokumen yang berhasil dibaca disimpan
+	_, err := p.AuthURL("state")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+// TestOAuthProviderTimeout menguji bahwa provider yang menggantung tidak
+// menahan callback melewati batas waktu http.Client
+func TestOAuthProviderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+
+	p, err := providers.New("github", config.OAuth{
+		ClientID: "github-client",
+		AuthURL:  hung.URL + "/login/oauth/authorize",
+		TokenURL: hung.URL + "/login/oauth/access_token",
+		APIURL:   hung.URL,
+	}, nil)
+	assert.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 100 * time.Millisecond})
+	start := time.Now()
+	_, err = providers.Authenticate(ctx, nil, p, "code")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// fakeProvider adalah provider OAuth custom dalam memori untuk pengujian registry
+type fakeProvider struct {
+	name string
+	cfg  config.OAuth
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) AuthURL(state string, opts ...oauth2.AuthCodeOption) (string, error) {
+	oauthCfg := &oauth2.Config{ClientID: p.cfg.ClientID, Endpoint: oauth2.Endpoint{AuthURL: "https://gitlab.example.com/oauth/authorize"}}
+	return oauthCfg.AuthCodeURL(state, opts...), nil
+}
+
+func (p *fakeProvider) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	if code != "good" {
+		return nil, fmt.Errorf("invalid code")
+	}
+	return &oauth2.Token{AccessToken: "gitlab-token"}, nil
+}
+
+func (p *fakeProvider) FetchProfile(ctx context.Context, token *oauth2.Token) (*providers.Profile, error) {
+	return &providers.Profile{
+		ID:            "77",
+		Email:         "gitlab@example.com",
+		EmailVerified: true,
+		FirstName:     "Gita",
+		LastName:      "Lab",
+		Raw:           map[string]string{"username": "gita"},
+	}, nil
+}
+
+// TestProviderRegistryAPI menguji provider custom dari aplikasi dan provider
+// bawaan yang didaftarkan dengan nama lain melalui providers.oauth
+func TestProviderRegistryAPI(t *testing.T) {
+	ghe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			fmt.Fprint(w, `{"access_token":"ghe-token","token_type":"bearer"}`)
+		case "/api/v3/user":
+			fmt.Fprint(w, `{"id":5,"login":"hubot","name":"Hubot"}`)
+		case "/api/v3/user/emails":
+			fmt.Fprint(w, `[{"email":"hubot@example.com","primary":true,"verified":true}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ghe.Close()
+
+	a := newTestAuth(t, config.Config{
+		Providers: config.Providers{
+			OAuth: map[string]config.OAuth{
+				"gitlab": {Enabled: true, Type: "custom", ClientID: "gitlab-app", ClientSecret: "secret"},
+				"github-enterprise": {
+					Enabled:      true,
+					Type:         "github",
+					ClientID:     "ghe-app",
+					ClientSecret: "secret",
+					AuthURL:      ghe.URL + "/login/oauth/authorize",
+					TokenURL:     ghe.URL + "/login/oauth/access_token",
+					APIURL:       ghe.URL + "/api/v3",
+				},
+			},
+		},
+	})
+
+	// Entri bertipe custom tidak dibuat otomatis; aplikasi mendaftarkannya sendiri
+	_, ok := a.Provider("gitlab")
+	assert.False(t, ok)
+	assert.NoError(t, a.RegisterProvider(&fakeProvider{name: "gitlab", cfg: a.Config().Providers.OAuth["gitlab"]}))
+	assert.Error(t, a.RegisterProvider(&fakeProvider{name: "gitlab"}))
+	assert.Error(t, a.RegisterProvider(&fakeProvider{name: "gitlab_nonce"}))
+
+	e := echo.New()
+	a.RegisterRoutes(e)
+
+	rec, resp := oauthLogin(t, e, "gitlab", "good")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, resp["token"])
+	assert.Equal(t, "gitlab@example.com", resp["user"].(map[string]interface{})["email"])
+	assert.Equal(t, false, resp["user"].(map[string]interface{})["email_missing"])
+
+	rec, _ = oauthLogin(t, e, "gitlab", "bad")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Provider bawaan dengan nama lain disimpan dengan nama tersebut
+	rec, resp = oauthLogin(t, e, "github-enterprise", "good")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "hubot@example.com", resp["user"].(map[string]interface{})["email"])
+
+	var link models.UserProvider
+	assert.NoError(t, a.DB().Where("provider_name = ?", "github-enterprise").First(&link).Error)
+	assert.Equal(t, "5", link.ProviderID)
+
+	// Hanya provider yang terdaftar yang memiliki rute
+	rec, _ = doJSON(t, e, http.MethodGet, "/auth/github", nil, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
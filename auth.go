@@ -1,15 +1,20 @@
 package auth
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/kreasimaju/auth/config"
 	"github.com/kreasimaju/auth/middleware"
 	"github.com/kreasimaju/auth/models"
@@ -19,149 +24,308 @@ import (
 	"gorm.io/gorm"
 )
 
-var (
-	configuration config.Config
-)
+// LoadConfig memuat konfigurasi dari file JSON atau YAML, menimpanya dengan
+// variabel environment KREASIMAJU_AUTH_* dan menerapkan nilai default
+func LoadConfig(path string) (config.Config, error) {
+	return config.Load(path)
+}
 
-// Init menginisialisasi package auth dengan konfigurasi yang diberikan
-func Init(cfg config.Config) error {
-	configuration = cfg
+// Auth adalah satu instance layanan autentikasi yang memiliki konfigurasi,
+// koneksi database, kunci penandatanganan dan provider OAuth sendiri.
+// Beberapa instance dengan konfigurasi berbeda dapat berjalan dalam satu
+// aplikasi, misalnya untuk realm pelanggan dan staf.
+type Auth struct {
+	config      config.Config
+	db          *gorm.DB
+	registry    *providers.Registry
+	revocations utils.RevocationStore
+	keys        *utils.KeyRing
+	enricher    ClaimsEnricher
+	senders     map[string]OTPSender
+	webAuthn    *webauthn.WebAuthn
+
+	otpKeyOnce sync.Once
+	otpHMACKey []byte
+
+	otpQuotaMu  sync.Mutex
+	otpPrunedAt time.Time
+
+	keyDirMu sync.Mutex
+
+	stopRotation context.CancelFunc
+}
 
-	// Set JWT secret
-	utils.SetJWTSecret(cfg.JWT.Secret)
+// New membuat instance Auth baru dengan konfigurasi yang diberikan.
+// Konfigurasi diperiksa dengan Config.Validate terlebih dahulu.
+func New(cfg config.Config) (*Auth, error) {
+	// Tolak konfigurasi yang salah sebelum membuka koneksi apa pun
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
 	// Menginisialisasi koneksi database
-	_, err := utils.InitDB(cfg.Database)
+	db, err := utils.OpenDB(cfg.Database)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Inisialisasi provider auth
-	initProviders(cfg.Providers)
+	return newAuth(cfg, db)
+}
+
+// NewWithDB membuat instance Auth baru yang memakai koneksi database yang sudah ada
+func NewWithDB(cfg config.Config, db *gorm.DB) (*Auth, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if db == nil {
+		return nil, fmt.Errorf("database connection is nil")
+	}
+
+	if cfg.Database.AutoMigrate {
+		if err := utils.MigrateDB(db); err != nil {
+			return nil, err
+		}
+	}
 
-	return nil
+	return newAuth(cfg, db)
 }
 
-// initProviders mengatur provider auth berdasarkan konfigurasi
-func initProviders(providers config.Providers) {
-	// Inisialisasi provider OAuth
-	if providers.Google.Enabled {
-		initGoogleProvider(providers.Google)
+// newAuth merakit instance Auth dari konfigurasi dan koneksi database
+func newAuth(cfg config.Config, db *gorm.DB) (*Auth, error) {
+	// Muat atau buat kunci penandatanganan JWT. Dengan jwt.key_dir, kunci
+	// dibaca dari direktori yang dipakai bersama oleh semua instance.
+	var signingKey *utils.SigningKey
+	var dirKeys *keyDirSet
+	var err error
+	if cfg.JWT.KeyDir != "" {
+		if dirKeys, err = readKeyDir(cfg.JWT); err != nil {
+			return nil, err
+		}
+		signingKey = dirKeys.active
+	} else if signingKey, err = utils.NewSigningKeyFromConfig(cfg.JWT); err != nil {
+		return nil, err
+	}
+
+	// Kunci lama tetap diterima agar token yang sudah terbit tidak langsung ditolak
+	var retiredKeys []*utils.SigningKey
+	for _, path := range cfg.JWT.RetiredKeyFiles {
+		key, err := utils.LoadVerificationKeyFile(signingKey.Method.Alg(), path)
+		if err != nil {
+			return nil, err
+		}
+		retiredKeys = append(retiredKeys, key)
+	}
+
+	a := &Auth{
+		config:      cfg,
+		db:          db,
+		revocations: utils.NewDBRevocationStore(db),
+		keys:        utils.NewKeyRing(signingKey, retiredKeys...),
+	}
+	if dirKeys != nil {
+		dirKeys.addTo(a.keys)
 	}
 
-	if providers.Twitter.Enabled {
-		initTwitterProvider(providers.Twitter)
+	// Inisialisasi provider auth
+	if err := a.initProviders(cfg.Providers); err != nil {
+		return nil, err
+	}
+
+	if cfg.WebAuthn.Enabled {
+		if a.webAuthn, err = newWebAuthn(cfg.WebAuthn); err != nil {
+			return nil, err
+		}
 	}
 
-	if providers.GitHub.Enabled {
-		initGitHubProvider(providers.GitHub)
+	if cfg.JWT.RotationInterval > 0 || cfg.JWT.KeyDir != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		a.stopRotation = cancel
+		if cfg.JWT.KeyDir != "" {
+			a.startKeyDirSync(ctx)
+		}
+		if cfg.JWT.RotationInterval > 0 {
+			a.StartKeyRotation(ctx, time.Duration(cfg.JWT.RotationInterval)*time.Second)
+		}
 	}
 
-	if providers.Facebook.Enabled {
-		initFacebookProvider(providers.Facebook)
+	return a, nil
+}
+
+// Close menghentikan proses latar belakang milik instance, seperti rotasi
+// kunci otomatis dan pembacaan ulang jwt.key_dir. Koneksi database tidak ditutup.
+func (a *Auth) Close() {
+	if a.stopRotation != nil {
+		a.stopRotation()
 	}
 }
 
-// initGoogleProvider menginisialisasi provider Google
-func initGoogleProvider(config config.OAuth) {
-	providers.InitGoogle(config)
+// Config mengembalikan konfigurasi instance
+func (a *Auth) Config() config.Config {
+	return a.config
 }
 
-// GoogleLoginURL mengembalikan URL untuk login Google
-func GoogleLoginURL(state string) string {
-	return providers.GoogleLoginURL(state)
+// DB mengembalikan koneksi database instance. Instance default yang belum
+// diinisialisasi melalui Init memakai utils.DB.
+func (a *Auth) DB() *gorm.DB {
+	if a.db != nil {
+		return a.db
+	}
+	return utils.DB
 }
 
-// HandleGoogleCallback menangani callback dari Google OAuth
-func HandleGoogleCallback(code string) (*models.User, error) {
-	return providers.HandleGoogleCallback(code)
+// RevocationStore mengembalikan penyimpanan token yang dicabut
+func (a *Auth) RevocationStore() utils.RevocationStore {
+	if a.revocations != nil {
+		return a.revocations
+	}
+	return utils.NewDBRevocationStore(a.DB())
 }
 
-func initTwitterProvider(config config.OAuth) {
-	// Implementasi inisialisasi Twitter OAuth provider
+// SetRevocationStore mengganti penyimpanan token yang dicabut, misalnya
+// dengan utils.NewMemoryRevocationStore untuk aplikasi satu instance.
+// Panggil sebelum membuat middleware atau mendaftarkan rute.
+func (a *Auth) SetRevocationStore(store utils.RevocationStore) {
+	a.revocations = store
 }
 
-func initGitHubProvider(config config.OAuth) {
-	// Implementasi inisialisasi GitHub OAuth provider
+// ValidateToken memvalidasi token JWT yang diterbitkan oleh instance ini
+func (a *Auth) ValidateToken(tokenString string) (*jwt.Token, error) {
+	if a.keys == nil {
+		// Instance default sebelum Init memakai secret global
+		return utils.ValidateJWT(tokenString)
+	}
+	return utils.ValidateJWTWithRules(tokenString, a.keys, utils.ValidationRulesFromConfig(a.config.JWT))
 }
 
-func initFacebookProvider(config config.OAuth) {
-	// Implementasi inisialisasi Facebook OAuth provider
+// GenerateToken menghasilkan token JWT untuk pengguna yang ditandatangani
+// dengan kunci aktif instance. Klaim dapat ditambah melalui SetClaimsEnricher.
+func (a *Auth) GenerateToken(user *models.User) (string, error) {
+	claims, err := a.newClaims(user)
+	if err != nil {
+		return "", err
+	}
+
+	if a.keys == nil {
+		// Instance default sebelum Init memakai HS256 dengan secret konfigurasi
+		return utils.SignJWT(claims, &utils.SigningKey{
+			ID:      a.config.JWT.KeyID,
+			Method:  jwt.SigningMethodHS256,
+			Private: []byte(a.config.JWT.Secret),
+			Public:  []byte(a.config.JWT.Secret),
+		})
+	}
+	return utils.SignJWT(claims, a.keys.SigningKey())
 }
 
-// GetConfig mengembalikan konfigurasi saat ini
-func GetConfig() config.Config {
-	return configuration
+// KeyRing mengembalikan kunci penandatanganan JWT milik instance
+func (a *Auth) KeyRing() *utils.KeyRing {
+	return a.keys
 }
 
 // Middleware functions
 
-// Middleware mengembalikan handler middleware otentikasi untuk Echo
-func Middleware() echo.MiddlewareFunc {
-	return middleware.EchoAuthMiddleware()
+// middlewareOptions mengembalikan option middleware yang terikat pada instance ini
+func (a *Auth) middlewareOptions() []middleware.Option {
+	return []middleware.Option{
+		middleware.WithValidator(a.ValidateToken),
+		middleware.WithRevocationStore(a.RevocationStore()),
+	}
+}
+
+// Middleware mengembalikan handler middleware otentikasi untuk Echo. Option
+// tambahan seperti middleware.RequireVerified() dapat diberikan.
+func (a *Auth) Middleware(opts ...middleware.Option) echo.MiddlewareFunc {
+	return middleware.EchoAuthMiddleware(append(a.middlewareOptions(), opts...)...)
 }
 
 // RoleMiddleware mengembalikan handler middleware peran untuk Echo
-func RoleMiddleware(roles ...string) echo.MiddlewareFunc {
+func (a *Auth) RoleMiddleware(roles ...string) echo.MiddlewareFunc {
 	return middleware.EchoRoleMiddleware(roles...)
 }
 
 // GinMiddleware mengembalikan handler middleware otentikasi untuk Gin
-func GinMiddleware() gin.HandlerFunc {
-	return middleware.GinAuthMiddleware()
+func (a *Auth) GinMiddleware(opts ...middleware.Option) gin.HandlerFunc {
+	return middleware.GinAuthMiddleware(append(a.middlewareOptions(), opts...)...)
 }
 
 // GinRoleMiddleware mengembalikan handler middleware peran untuk Gin
-func GinRoleMiddleware(roles ...string) gin.HandlerFunc {
+func (a *Auth) GinRoleMiddleware(roles ...string) gin.HandlerFunc {
 	return middleware.GinRoleMiddleware(roles...)
 }
 
 // FiberMiddleware mengembalikan handler middleware otentikasi untuk Fiber
-func FiberMiddleware() fiber.Handler {
-	return middleware.FiberAuthMiddleware()
+func (a *Auth) FiberMiddleware(opts ...middleware.Option) fiber.Handler {
+	return middleware.FiberAuthMiddleware(append(a.middlewareOptions(), opts...)...)
 }
 
 // FiberRoleMiddleware mengembalikan handler middleware peran untuk Fiber
-func FiberRoleMiddleware(roles ...string) fiber.Handler {
+func (a *Auth) FiberRoleMiddleware(roles ...string) fiber.Handler {
 	return middleware.FiberRoleMiddleware(roles...)
 }
 
 // Route registration
 
 // RegisterRoutes mendaftarkan rute otentikasi untuk Echo
-func RegisterRoutes(e *echo.Echo) {
+func (a *Auth) RegisterRoutes(e *echo.Echo) {
 	// Registrasi rute autentikasi
 	auth := e.Group("/auth")
 
 	// Rute local auth
-	auth.POST("/register", registerHandler)
-	auth.POST("/login", loginHandler)
-	auth.POST("/verify-email", verifyEmailHandler)
-	auth.POST("/forgot-password", forgotPasswordHandler)
-	auth.POST("/reset-password", resetPasswordHandler)
+	auth.POST("/register", a.registerHandler)
+	auth.POST("/login", a.loginHandler)
+	auth.POST("/refresh", a.refreshHandler)
+	auth.POST("/verify-email", a.verifyEmailHandler)
+	auth.POST("/resend-verification", a.resendVerificationHandler, a.SessionMiddleware())
+	auth.POST("/email", a.setEmailHandler, a.SessionMiddleware())
+	auth.POST("/forgot-password", a.forgotPasswordHandler)
+	auth.POST("/reset-password", a.resetPasswordHandler)
 
 	// Rute OTP
-	auth.POST("/request-otp", requestOTPHandler)
-	auth.POST("/verify-otp", verifyOTPHandler)
-
-	// Rute OAuth
-	auth.GET("/google", googleAuthHandler)
-	auth.GET("/google/callback", googleCallbackHandler)
-	auth.GET("/twitter", twitterAuthHandler)
-	auth.GET("/twitter/callback", twitterCallbackHandler)
-	auth.GET("/github", githubAuthHandler)
-	auth.GET("/github/callback", githubCallbackHandler)
-	auth.GET("/facebook", facebookAuthHandler)
-	auth.GET("/facebook/callback", facebookCallbackHandler)
+	auth.POST("/request-otp", a.requestOTPHandler)
+	auth.POST("/verify-otp", a.verifyOTPHandler)
+
+	// Autentikasi dua langkah
+	auth.POST("/mfa/verify", a.verifyMFAHandler)
+	auth.POST("/mfa/totp/enroll", a.enrollTOTPHandler, a.SessionMiddleware())
+	auth.POST("/mfa/totp/confirm", a.confirmTOTPHandler, a.SessionMiddleware())
+	auth.POST("/mfa/totp/disable", a.disableTOTPHandler, a.SessionMiddleware())
+	auth.POST("/mfa/backup-codes", a.regenerateBackupCodesHandler, a.SessionMiddleware())
+	auth.GET("/security", a.securityOverviewHandler, a.SessionMiddleware())
+	auth.POST("/mfa/webauthn/begin", a.beginWebAuthnMFAHandler)
+	auth.POST("/mfa/webauthn/verify", a.verifyWebAuthnMFAHandler)
+
+	// Passkey (WebAuthn)
+	auth.POST("/webauthn/register/begin", a.beginPasskeyRegistrationHandler, a.SessionMiddleware())
+	auth.POST("/webauthn/register/finish", a.finishPasskeyRegistrationHandler, a.SessionMiddleware())
+	auth.GET("/webauthn/credentials", a.listPasskeysHandler, a.SessionMiddleware())
+	auth.DELETE("/webauthn/credentials/:id", a.deletePasskeyHandler, a.SessionMiddleware())
+	auth.POST("/webauthn/login/begin", a.beginPasskeyLoginHandler)
+	auth.POST("/webauthn/login/finish", a.finishPasskeyLoginHandler)
+
+	// Rute OAuth untuk setiap provider yang terdaftar
+	for _, name := range a.registry.Names() {
+		auth.GET("/"+name, a.oauthAuthHandler(name))
+		auth.GET("/"+name+"/callback", a.oauthCallbackHandler(name))
+	}
 
 	// Logout
-	auth.POST("/logout", logoutHandler)
+	auth.POST("/logout", a.logoutHandler, a.SessionMiddleware())
+	auth.POST("/logout-all", a.logoutAllHandler, a.SessionMiddleware())
+
+	// Manajemen sesi aktif
+	auth.GET("/sessions", a.listSessionsHandler, a.SessionMiddleware())
+	auth.DELETE("/sessions", a.revokeOtherSessionsHandler, a.SessionMiddleware())
+	auth.DELETE("/sessions/:id", a.revokeSessionHandler, a.SessionMiddleware())
+
+	// Public key untuk layanan lain yang memverifikasi token
+	e.GET("/.well-known/jwks.json", a.jwksHandler)
 }
 
 // ===== Autentikasi Lokal dan OTP =====
 
 // RegisterLocal mendaftarkan pengguna dengan email dan password
-func RegisterLocal(email, password, firstName, lastName, phone, defaultRegion string) (*models.User, error) {
+func (a *Auth) RegisterLocal(email, password, firstName, lastName, phone, defaultRegion string) (*models.User, error) {
 	// Validasi email
 	if email == "" {
 		return nil, fmt.Errorf("email wajib diisi")
@@ -174,7 +338,7 @@ func RegisterLocal(email, password, firstName, lastName, phone, defaultRegion st
 
 	// Cek apakah email sudah terdaftar
 	var existingUser models.User
-	err := utils.DB.Where("email = ?", email).First(&existingUser).Error
+	err := a.DB().Where("email = ?", email).First(&existingUser).Error
 	if err == nil {
 		return nil, fmt.Errorf("email sudah terdaftar")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -190,7 +354,7 @@ func RegisterLocal(email, password, firstName, lastName, phone, defaultRegion st
 		}
 
 		// Cek apakah nomor telepon sudah terdaftar
-		err = utils.DB.Where("phone = ?", formattedPhone).First(&existingUser).Error
+		err = a.DB().Where("phone = ?", formattedPhone).First(&existingUser).Error
 		if err == nil {
 			return nil, fmt.Errorf("nomor telepon sudah terdaftar")
 		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -215,7 +379,7 @@ func RegisterLocal(email, password, firstName, lastName, phone, defaultRegion st
 	}
 
 	// Simpan ke database
-	if err := utils.DB.Create(&user).Error; err != nil {
+	if err := a.DB().Create(&user).Error; err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint") {
 			if strings.Contains(err.Error(), "users.email") {
 				return nil, fmt.Errorf("email sudah terdaftar")
@@ -227,18 +391,28 @@ func RegisterLocal(email, password, firstName, lastName, phone, defaultRegion st
 		return nil, err
 	}
 
+	// Kegagalan pengiriman tidak membatalkan registrasi; pengguna dapat
+	// meminta pengiriman ulang
+	if a.config.EmailVerification.Enabled {
+		if err := a.SendEmailVerification(user.ID); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
 	return &user, nil
 }
 
-// LoginLocal melakukan autentikasi pengguna dengan email/phone dan password
-func LoginLocal(identifier, password string) (*models.User, error) {
+// LoginLocal melakukan autentikasi pengguna dengan email/phone dan password.
+// Jika pengguna mengaktifkan TOTP atau mendaftarkan passkey,
+// *MFARequiredError dikembalikan sebagai pengganti pengguna.
+func (a *Auth) LoginLocal(identifier, password string) (*models.User, error) {
 	var user models.User
 
 	// Coba cari dengan email
-	err := utils.DB.Where("email = ?", identifier).First(&user).Error
+	err := a.DB().Where("email = ?", identifier).First(&user).Error
 	if err != nil {
 		// Jika tidak ditemukan, coba cari dengan nomor telepon
-		err = utils.DB.Where("phone = ?", identifier).First(&user).Error
+		err = a.DB().Where("phone = ?", identifier).First(&user).Error
 		if err != nil {
 			return nil, fmt.Errorf("email atau password tidak valid")
 		}
@@ -249,133 +423,121 @@ func LoginLocal(identifier, password string) (*models.User, error) {
 		return nil, fmt.Errorf("email atau password tidak valid")
 	}
 
+	// Pengguna dengan TOTP atau passkey menyelesaikan login melalui VerifyMFA
+	// atau VerifyWebAuthnMFA
+	if err := a.requireMFA(&user); err != nil {
+		return nil, err
+	}
+
 	// Update last login time
 	now := time.Now()
 	user.LastLogin = &now
-	utils.DB.Save(&user)
+	a.DB().Model(&user).Update("last_login", now)
 
 	return &user, nil
 }
 
-// GenerateOTP membuat kode OTP baru
-func GenerateOTP(userID uint, otpType, target, purpose string) (*models.OTPCode, error) {
+// GenerateOTP membuat kode OTP baru. Hanya hash kode yang disimpan, sehingga
+// setiap permintaan membuat kode baru dan menggantikan kode sebelumnya.
+func (a *Auth) GenerateOTP(userID uint, otpType, target, purpose string) (*models.OTPCode, error) {
 	// Validasi tipe OTP
 	if otpType != "sms" && otpType != "email" && otpType != "whatsapp" {
 		return nil, fmt.Errorf("tipe OTP tidak valid")
 	}
 
-	// Cek apakah sudah ada OTP yang masih valid
-	var existingOTP models.OTPCode
-	err := utils.DB.Where("user_id = ? AND type = ? AND purpose = ? AND valid = ?",
-		userID, otpType, purpose, true).First(&existingOTP).Error
-
-	// Jika ada OTP yang masih aktif, gunakan kembali
-	if err == nil && existingOTP.IsValid() {
-		return &existingOTP, nil
-	}
-
-	// Tentukan panjang kode OTP
-	codeLength := 6
-	if configuration.OTP.Length > 0 {
-		codeLength = configuration.OTP.Length
-	}
-
-	// Generate kode OTP acak
-	code := utils.GenerateOTP(codeLength)
-
-	// Hitung waktu kedaluwarsa (default 5 menit jika tidak ada konfigurasi)
-	expirySeconds := int64(300)
-	if configuration.OTP.ExpiresIn > 0 {
-		expirySeconds = configuration.OTP.ExpiresIn
+	cfg := a.config.OTP
+	if cfg.Length <= 0 {
+		cfg.Length = config.DefaultOTPLength
 	}
-	expiresAt := time.Now().Add(time.Duration(expirySeconds) * time.Second)
-
-	// Buat OTP baru
-	otpCode := models.OTPCode{
-		UserID:    userID,
-		Code:      code,
-		Type:      otpType,
-		Target:    target,
-		Purpose:   purpose,
-		ExpiresAt: expiresAt,
-		Valid:     true,
+	if cfg.ExpiresIn <= 0 {
+		cfg.ExpiresIn = config.DefaultOTPExpiresIn
 	}
 
-	// Simpan ke database
-	if err := utils.DB.Create(&otpCode).Error; err != nil {
+	key, err := a.otpKey()
+	if err != nil {
 		return nil, err
 	}
-
-	return &otpCode, nil
+	return utils.CreateOTP(a.DB(), key, userID, otpType, target, purpose, cfg)
 }
 
-// VerifyOTP memverifikasi kode OTP
-func VerifyOTP(userID uint, code, otpType, purpose string) (bool, error) {
-	var otpCode models.OTPCode
-	err := utils.DB.Where("user_id = ? AND code = ? AND type = ? AND purpose = ? AND valid = ?",
-		userID, code, otpType, purpose, true).First(&otpCode).Error
+// ErrTooManyOTPAttempts dikembalikan saat kode OTP dibatalkan karena terlalu
+// banyak percobaan yang salah; handler menjawabnya dengan status 429
+var ErrTooManyOTPAttempts = utils.ErrTooManyOTPAttempts
 
+// VerifyOTP memverifikasi kode OTP. ErrTooManyOTPAttempts dikembalikan jika
+// kode sudah dibatalkan karena terlalu banyak percobaan yang salah.
+func (a *Auth) VerifyOTP(userID uint, code, otpType, purpose string) (bool, error) {
+	key, err := a.otpKey()
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return false, fmt.Errorf("kode OTP tidak valid atau sudah kedaluwarsa")
-		}
 		return false, err
 	}
-
-	// Periksa validitas OTP
-	if !otpCode.IsValid() {
+	valid, err := utils.VerifyOTP(a.DB(), key, userID, code, otpType, purpose, a.otpMaxAttempts())
+	if err != nil {
+		return false, err
+	}
+	if !valid {
 		return false, fmt.Errorf("kode OTP tidak valid atau sudah kedaluwarsa")
 	}
 
-	// Tandai sebagai digunakan
-	if err := otpCode.MarkAsUsed(utils.DB); err != nil {
-		return false, err
+	return true, nil
+}
+
+// otpMaxAttempts mengembalikan batas percobaan salah per kode OTP
+func (a *Auth) otpMaxAttempts() int {
+	if a.config.OTP.MaxAttempts > 0 {
+		return a.config.OTP.MaxAttempts
 	}
+	return config.DefaultOTPMaxAttempts
+}
 
-	return true, nil
+// ErrOTPSecretRequired dikembalikan saat instance tidak memiliki otp.secret
+// maupun jwt.secret untuk hash kode OTP, misalnya instance default sebelum
+// Init. Config.Validate menolak konfigurasi seperti ini saat startup.
+var ErrOTPSecretRequired = errors.New("otp.secret wajib diatur jika jwt.secret kosong")
+
+// otpKey mengembalikan key HMAC untuk hash kode OTP: otp.secret, atau
+// turunan jwt.secret
+func (a *Auth) otpKey() ([]byte, error) {
+	a.otpKeyOnce.Do(func() {
+		switch {
+		case a.config.OTP.Secret != "":
+			a.otpHMACKey = []byte(a.config.OTP.Secret)
+		case a.config.JWT.Secret != "":
+			// Key diturunkan agar secret JWT tidak dipakai langsung untuk keperluan lain
+			mac := hmac.New(sha256.New, []byte(a.config.JWT.Secret))
+			mac.Write([]byte("kreasimaju-auth otp"))
+			a.otpHMACKey = mac.Sum(nil)
+		}
+	})
+	if a.otpHMACKey == nil {
+		return nil, ErrOTPSecretRequired
+	}
+	return a.otpHMACKey, nil
 }
 
 // SendOTP mengirim kode OTP melalui channel yang dipilih
-func SendOTP(otpCode *models.OTPCode) error {
+func (a *Auth) SendOTP(otpCode *models.OTPCode) error {
 	if otpCode == nil {
 		return fmt.Errorf("OTP code is nil")
 	}
 
 	// Hitung waktu kedaluwarsa dalam detik
 	expirySeconds := int64(300) // default 5 menit
-	if configuration.OTP.ExpiresIn > 0 {
-		expirySeconds = configuration.OTP.ExpiresIn
+	if a.config.OTP.ExpiresIn > 0 {
+		expirySeconds = a.config.OTP.ExpiresIn
 	}
 
 	// Siapkan pesan OTP
 	message := fmt.Sprintf("Kode OTP Anda adalah: %s. Kode berlaku selama %d detik.",
 		otpCode.Code, expirySeconds)
 
-	// Implementasi pengiriman OTP
-	switch otpCode.Type {
-	case "email":
-		// Implementasi pengiriman email
-		// Untuk saat ini, tampilkan saja di log
-		log.Printf("Sending OTP %s to email %s (Message: %s)", otpCode.Code, otpCode.Target, message)
-	case "sms":
-		// Implementasi pengiriman SMS
-		// Untuk saat ini, tampilkan saja di log
-		log.Printf("Sending OTP %s to SMS %s (Message: %s)", otpCode.Code, otpCode.Target, message)
-	case "whatsapp":
-		// Implementasi pengiriman WhatsApp
-		// Untuk saat ini, tampilkan saja di log
-		log.Printf("Sending OTP %s to WhatsApp %s (Message: %s)", otpCode.Code, otpCode.Target, message)
-	default:
-		return fmt.Errorf("tipe OTP tidak didukung")
-	}
-
-	return nil
+	return a.deliver(otpCode.Type, otpCode.Target, message)
 }
 
 // FindUserByEmail mencari pengguna berdasarkan email
-func FindUserByEmail(email string) (*models.User, error) {
+func (a *Auth) FindUserByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := utils.DB.Where("email = ?", email).First(&user).Error
+	err := a.DB().Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -383,9 +545,9 @@ func FindUserByEmail(email string) (*models.User, error) {
 }
 
 // FindUserByPhone mencari pengguna berdasarkan nomor telepon
-func FindUserByPhone(phone string) (*models.User, error) {
+func (a *Auth) FindUserByPhone(phone string) (*models.User, error) {
 	var user models.User
-	err := utils.DB.Where("phone = ?", phone).First(&user).Error
+	err := a.DB().Where("phone = ?", phone).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -393,14 +555,14 @@ func FindUserByPhone(phone string) (*models.User, error) {
 }
 
 // RequestOTPLogin meminta OTP untuk login
-func RequestOTPLogin(contact, otpType, defaultRegion string) (*models.OTPCode, error) {
+func (a *Auth) RequestOTPLogin(contact, otpType, defaultRegion string) (*models.OTPCode, error) {
 	var user models.User
 	var err error
 	formattedContact := contact
 
 	// Cari pengguna berdasarkan kontak (email atau telepon)
 	if otpType == "email" {
-		err = utils.DB.Where("email = ?", contact).First(&user).Error
+		err = a.DB().Where("email = ?", contact).First(&user).Error
 	} else { // sms atau whatsapp
 		// Format nomor telepon jika perlu
 		if utils.IsPhoneNumber(contact) {
@@ -409,7 +571,7 @@ func RequestOTPLogin(contact, otpType, defaultRegion string) (*models.OTPCode, e
 				return nil, fmt.Errorf("format nomor telepon tidak valid: %v", err)
 			}
 		}
-		err = utils.DB.Where("phone = ?", formattedContact).First(&user).Error
+		err = a.DB().Where("phone = ?", formattedContact).First(&user).Error
 	}
 
 	if err != nil {
@@ -417,13 +579,13 @@ func RequestOTPLogin(contact, otpType, defaultRegion string) (*models.OTPCode, e
 	}
 
 	// Buat OTP
-	otpCode, err := GenerateOTP(user.ID, otpType, formattedContact, "login")
+	otpCode, err := a.GenerateOTP(user.ID, otpType, formattedContact, "login")
 	if err != nil {
 		return nil, err
 	}
 
 	// Kirim OTP
-	err = SendOTP(otpCode)
+	err = a.SendOTP(otpCode)
 	if err != nil {
 		return nil, err
 	}
@@ -431,15 +593,18 @@ func RequestOTPLogin(contact, otpType, defaultRegion string) (*models.OTPCode, e
 	return otpCode, nil
 }
 
-// VerifyOTPLogin memverifikasi OTP untuk login
-func VerifyOTPLogin(contact, otpType, code, defaultRegion string) (*models.User, error) {
+// VerifyOTPLogin memverifikasi OTP untuk login. code juga dapat berupa kode
+// cadangan dari GenerateBackupCodes. Seperti LoginLocal, *MFARequiredError
+// dikembalikan jika pengguna mengaktifkan TOTP atau mendaftarkan passkey,
+// kecuali login memakai kode cadangan yang sudah menjadi faktor kedua.
+func (a *Auth) VerifyOTPLogin(contact, otpType, code, defaultRegion string) (*models.User, error) {
 	var user models.User
 	var err error
 	formattedContact := contact
 
 	// Cari pengguna berdasarkan kontak (email atau telepon)
 	if otpType == "email" {
-		err = utils.DB.Where("email = ?", contact).First(&user).Error
+		err = a.DB().Where("email = ?", contact).First(&user).Error
 	} else { // sms atau whatsapp
 		// Format nomor telepon jika perlu
 		if utils.IsPhoneNumber(contact) {
@@ -448,37 +613,55 @@ func VerifyOTPLogin(contact, otpType, code, defaultRegion string) (*models.User,
 				return nil, fmt.Errorf("format nomor telepon tidak valid: %v", err)
 			}
 		}
-		err = utils.DB.Where("phone = ?", formattedContact).First(&user).Error
+		err = a.DB().Where("phone = ?", formattedContact).First(&user).Error
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Verifikasi OTP
-	valid, err := VerifyOTP(user.ID, code, otpType, "login")
-	if err != nil {
-		return nil, err
+	// Kode cadangan dapat dipakai sebagai pengganti OTP saat telepon hilang
+	backup := isBackupCode(code)
+	if backup {
+		if err := a.UseBackupCode(user.ID, code); err != nil {
+			return nil, err
+		}
+	} else {
+		valid, err := a.VerifyOTP(user.ID, code, otpType, "login")
+		if err != nil {
+			return nil, err
+		}
+
+		if !valid {
+			return nil, fmt.Errorf("kode OTP tidak valid")
+		}
 	}
 
-	if !valid {
-		return nil, fmt.Errorf("kode OTP tidak valid")
+	// OTP hanya menggantikan password; faktor kedua tetap diminta. Kode
+	// cadangan sudah merupakan faktor kedua, sehingga satu login tidak
+	// menghabiskan dua kode.
+	if !backup {
+		if err := a.requireMFA(&user); err != nil {
+			return nil, err
+		}
 	}
 
 	// Update last login time
 	now := time.Now()
 	user.LastLogin = &now
-	utils.DB.Save(&user)
+	a.DB().Model(&user).Update("last_login", now)
 
 	return &user, nil
 }
 
-// Login melakukan autentikasi pengguna dengan email dan password
-func Login(email, password string) (*models.User, error) {
+// Login melakukan autentikasi pengguna dengan email dan password. Seperti
+// LoginLocal, *MFARequiredError dikembalikan jika pengguna mengaktifkan TOTP
+// atau mendaftarkan passkey.
+func (a *Auth) Login(email, password string) (*models.User, error) {
 	var user models.User
 
 	// Cari pengguna berdasarkan email
-	err := utils.DB.Where("email = ?", email).First(&user).Error
+	err := a.DB().Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, fmt.Errorf("email atau password tidak valid")
 	}
@@ -488,386 +671,16 @@ func Login(email, password string) (*models.User, error) {
 		return nil, fmt.Errorf("email atau password tidak valid")
 	}
 
+	// Pengguna dengan TOTP atau passkey menyelesaikan login melalui VerifyMFA
+	// atau VerifyWebAuthnMFA
+	if err := a.requireMFA(&user); err != nil {
+		return nil, err
+	}
+
 	// Update last login time
 	now := time.Now()
 	user.LastLogin = &now
-	utils.DB.Save(&user)
+	a.DB().Model(&user).Update("last_login", now)
 
 	return &user, nil
 }
-
-// ===== Implementasi Handler =====
-
-// Handler Google OAuth
-var googleAuthHandler = func(c echo.Context) error {
-	// Generate state untuk keamanan
-	state := "random-state" // Idealnya gunakan random string dan simpan di session
-
-	// Redirect ke URL login Google
-	url := GoogleLoginURL(state)
-	return c.Redirect(http.StatusTemporaryRedirect, url)
-}
-
-// Handler Google OAuth callback
-var googleCallbackHandler = func(c echo.Context) error {
-	// Dapatkan code dari query parameter
-	code := c.QueryParam("code")
-	if code == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Code parameter is required",
-		})
-	}
-
-	// Dapatkan user dari Google callback
-	user, err := HandleGoogleCallback(code)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to authenticate with Google: " + err.Error(),
-		})
-	}
-
-	// Generate JWT token
-	token, err := utils.GenerateJWT(*user, configuration.JWT)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate token: " + err.Error(),
-		})
-	}
-
-	// Return token
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"token": token,
-		"user": map[string]interface{}{
-			"id":         user.ID,
-			"email":      user.Email,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-		},
-	})
-}
-
-// Handler untuk registrasi lokal
-var registerHandler = func(c echo.Context) error {
-	// Parse request
-	var req struct {
-		Email         string `json:"email"`
-		Password      string `json:"password"`
-		FirstName     string `json:"first_name"`
-		LastName      string `json:"last_name"`
-		Phone         string `json:"phone"`
-		DefaultRegion string `json:"default_region"` // Kode negara 2 huruf, misal: "ID", "US", dll
-	}
-
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
-	}
-
-	// Validasi input
-	if req.Email == "" || req.Password == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Email and password are required",
-		})
-	}
-
-	// Cek apakah email sudah terdaftar
-	_, err := FindUserByEmail(req.Email)
-	if err == nil {
-		return c.JSON(http.StatusConflict, map[string]string{
-			"error": "Email already registered",
-		})
-	}
-
-	// Jika nomor telepon disediakan, validasi formatnya
-	if req.Phone != "" {
-		// Validasi format telepon
-		if !utils.IsValidPhoneNumber(req.Phone, req.DefaultRegion) {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid phone number format",
-			})
-		}
-
-		// Format telepon untuk penyimpanan
-		formattedPhone, err := utils.FormatPhoneNumber(req.Phone, req.DefaultRegion)
-		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid phone number: " + err.Error(),
-			})
-		}
-
-		// Cek apakah nomor telepon sudah terdaftar
-		_, err = FindUserByPhone(formattedPhone)
-		if err == nil {
-			return c.JSON(http.StatusConflict, map[string]string{
-				"error": "Phone number already registered",
-			})
-		}
-	}
-
-	// Registrasi pengguna
-	user, err := RegisterLocal(req.Email, req.Password, req.FirstName, req.LastName, req.Phone, req.DefaultRegion)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to register user: " + err.Error(),
-		})
-	}
-
-	// Generate JWT token
-	token, err := utils.GenerateJWT(*user, configuration.JWT)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate token: " + err.Error(),
-		})
-	}
-
-	// Return token
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"token": token,
-		"user": map[string]interface{}{
-			"id":         user.ID,
-			"email":      user.Email,
-			"phone":      user.Phone,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-		},
-	})
-}
-
-// Handler untuk login lokal
-var loginHandler = func(c echo.Context) error {
-	// Parse request
-	var req struct {
-		Identifier string `json:"identifier"` // Email atau nomor telepon
-		Password   string `json:"password"`
-	}
-
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
-	}
-
-	// Validasi input
-	if req.Identifier == "" || req.Password == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Identifier (email or phone) and password are required",
-		})
-	}
-
-	// Login pengguna
-	user, err := LoginLocal(req.Identifier, req.Password)
-	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Invalid identifier or password",
-		})
-	}
-
-	// Generate JWT token
-	token, err := utils.GenerateJWT(*user, configuration.JWT)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to generate token: " + err.Error(),
-		})
-	}
-
-	// Return token
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"token": token,
-		"user": map[string]interface{}{
-			"id":         user.ID,
-			"email":      user.Email,
-			"phone":      user.Phone,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-		},
-	})
-}
-
-var (
-	verifyEmailHandler      = func(c echo.Context) error { return nil }
-	forgotPasswordHandler   = func(c echo.Context) error { return nil }
-	resetPasswordHandler    = func(c echo.Context) error { return nil }
-	twitterAuthHandler      = func(c echo.Context) error { return nil }
-	twitterCallbackHandler  = func(c echo.Context) error { return nil }
-	githubAuthHandler       = func(c echo.Context) error { return nil }
-	githubCallbackHandler   = func(c echo.Context) error { return nil }
-	facebookAuthHandler     = func(c echo.Context) error { return nil }
-	facebookCallbackHandler = func(c echo.Context) error { return nil }
-	logoutHandler           = func(c echo.Context) error { return nil }
-
-	// Handler untuk request OTP
-	requestOTPHandler = func(c echo.Context) error {
-		// Parse request
-		var req struct {
-			Contact       string `json:"contact"`        // email atau nomor telepon
-			Type          string `json:"type"`           // "sms", "email", "whatsapp"
-			Purpose       string `json:"purpose"`        // "login", "register", "reset_password"
-			DefaultRegion string `json:"default_region"` // Kode negara 2 huruf, default "ID"
-		}
-
-		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid request body",
-			})
-		}
-
-		// Validasi input
-		if req.Contact == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Contact is required",
-			})
-		}
-
-		if req.Type == "" {
-			// Gunakan tipe default dari konfigurasi
-			req.Type = configuration.OTP.DefaultType
-		}
-
-		if req.Type != "sms" && req.Type != "email" && req.Type != "whatsapp" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid OTP type",
-			})
-		}
-
-		if req.Purpose == "" {
-			req.Purpose = "login"
-		}
-
-		// Gunakan default region Indonesia jika tidak disediakan
-		if req.DefaultRegion == "" {
-			req.DefaultRegion = "ID"
-		}
-
-		// Jika untuk login, cek apakah pengguna sudah terdaftar
-		if req.Purpose == "login" {
-			// Request OTP untuk login
-			_, err := RequestOTPLogin(req.Contact, req.Type, req.DefaultRegion)
-			if err != nil {
-				if err == gorm.ErrRecordNotFound {
-					return c.JSON(http.StatusNotFound, map[string]string{
-						"error": "User not found",
-					})
-				}
-				return c.JSON(http.StatusInternalServerError, map[string]string{
-					"error": "Failed to request OTP: " + err.Error(),
-				})
-			}
-
-			// Berhasil mengirim OTP
-			return c.JSON(http.StatusOK, map[string]string{
-				"message": "OTP has been sent",
-			})
-		} else if req.Purpose == "register" {
-			// Untuk registrasi, cek apakah email/nomor sudah terdaftar
-			var exists bool
-			if req.Type == "email" {
-				_, err := FindUserByEmail(req.Contact)
-				exists = err == nil
-			} else {
-				// Format nomor telepon
-				formattedPhone, err := utils.FormatPhoneNumber(req.Contact, req.DefaultRegion)
-				if err != nil {
-					return c.JSON(http.StatusBadRequest, map[string]string{
-						"error": "Invalid phone number: " + err.Error(),
-					})
-				}
-				_, err = FindUserByPhone(formattedPhone)
-				exists = err == nil
-			}
-
-			if exists {
-				return c.JSON(http.StatusConflict, map[string]string{
-					"error": "Contact already registered",
-				})
-			}
-
-			// Buat pengguna sementara atau gunakan session untuk menyimpan data OTP
-			// Implementasi bergantung pada kebutuhan spesifik
-			// ...
-
-			return c.JSON(http.StatusOK, map[string]string{
-				"message": "OTP has been sent for registration",
-			})
-		}
-
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid purpose",
-		})
-	}
-
-	// Handler untuk verifikasi OTP
-	verifyOTPHandler = func(c echo.Context) error {
-		// Parse request
-		var req struct {
-			Contact       string `json:"contact"`        // email atau nomor telepon
-			Type          string `json:"type"`           // "sms", "email", "whatsapp"
-			Code          string `json:"code"`           // kode OTP
-			Purpose       string `json:"purpose"`        // "login", "register", "reset_password"
-			DefaultRegion string `json:"default_region"` // Kode negara 2 huruf, default "ID"
-		}
-
-		if err := c.Bind(&req); err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid request body",
-			})
-		}
-
-		// Validasi input
-		if req.Contact == "" || req.Code == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Contact and code are required",
-			})
-		}
-
-		if req.Type == "" {
-			req.Type = configuration.OTP.DefaultType
-		}
-
-		if req.Purpose == "" {
-			req.Purpose = "login"
-		}
-
-		// Gunakan default region Indonesia jika tidak disediakan
-		if req.DefaultRegion == "" {
-			req.DefaultRegion = "ID"
-		}
-
-		// Verifikasi OTP untuk login
-		if req.Purpose == "login" {
-			user, err := VerifyOTPLogin(req.Contact, req.Type, req.Code, req.DefaultRegion)
-			if err != nil {
-				return c.JSON(http.StatusUnauthorized, map[string]string{
-					"error": "Invalid OTP: " + err.Error(),
-				})
-			}
-
-			// Generate JWT token
-			token, err := utils.GenerateJWT(*user, configuration.JWT)
-			if err != nil {
-				return c.JSON(http.StatusInternalServerError, map[string]string{
-					"error": "Failed to generate token: " + err.Error(),
-				})
-			}
-
-			// Return token
-			return c.JSON(http.StatusOK, map[string]interface{}{
-				"token": token,
-				"user": map[string]interface{}{
-					"id":         user.ID,
-					"email":      user.Email,
-					"phone":      user.Phone,
-					"first_name": user.FirstName,
-					"last_name":  user.LastName,
-				},
-			})
-		}
-
-		// Implementasi verifikasi untuk registrasi dan reset password
-		// ...
-
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid purpose",
-		})
-	}
-)
@@ -1,9 +1,15 @@
 package auth
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/config"
 	"github.com/kreasimaju/auth/models"
 	"github.com/kreasimaju/auth/utils"
 	"github.com/stretchr/testify/assert"
@@ -19,17 +25,34 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// Migrasi skema untuk pengujian
-	err = db.AutoMigrate(&models.User{}, &models.OTPCode{}, &models.PasswordReset{}, &models.OAuth{})
+	err = db.AutoMigrate(&models.User{}, &models.OTPCode{}, &models.PasswordReset{}, &models.OAuth{}, &models.RefreshToken{})
 	if err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
 
 	// Setel database global untuk pengujian
 	utils.DB = db
+	resetDefault()
 
 	return db
 }
 
+// resetDefault mengganti instance default dengan instance sebelum Init yang
+// hanya memiliki otp.secret dan OTPSender yang membuang pesan
+func resetDefault() {
+	SetDefault(&Auth{config: config.Config{OTP: config.OTP{Secret: "test-otp-secret-for-unit-tests-32b"}}})
+	discardMessages(Default())
+}
+
+// discardMessages memasang OTPSender yang membuang pesan untuk semua
+// channel; pengujian dapat menggantinya dengan SetOTPSender
+func discardMessages(a *Auth) {
+	discard := OTPSenderFunc(func(ctx context.Context, to, message string) error { return nil })
+	for _, channel := range []string{"email", "sms", "whatsapp"} {
+		a.SetOTPSender(channel, discard)
+	}
+}
+
 // TestRegisterLocal menguji fungsi RegisterLocal
 func TestRegisterLocal(t *testing.T) {
 	db := setupTestDB(t)
@@ -178,3 +201,289 @@ func TestPhoneValidation(t *testing.T) {
 		})
 	}
 }
+
+// testDBCounter membedakan nama database in-memory antar instance pengujian
+var testDBCounter atomic.Int64
+
+// newTestAuth membuat instance Auth dengan database SQLite in-memory tersendiri
+func newTestAuth(t *testing.T, cfg config.Config) *Auth {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s-%d?mode=memory&cache=shared", t.Name(), testDBCounter.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		dbSQL, _ := db.DB()
+		dbSQL.Close()
+	})
+
+	if cfg.JWT.Secret == "" {
+		cfg.JWT.Secret = "test-secret-for-unit-tests-only-32b"
+	}
+	if cfg.JWT.ExpiresIn == 0 {
+		cfg.JWT.ExpiresIn = 3600
+	}
+	cfg.Database.AutoMigrate = true
+
+	a, err := NewWithDB(cfg, db)
+	if err != nil {
+		t.Fatalf("Failed to create auth instance: %v", err)
+	}
+	discardMessages(a)
+	return a
+}
+
+// TestInstanceIsolation menguji bahwa dua instance Auth tidak berbagi state
+func TestInstanceIsolation(t *testing.T) {
+	customers := newTestAuth(t, config.Config{JWT: config.JWT{Secret: "customer-realm-secret-0123456789abc"}})
+	staff := newTestAuth(t, config.Config{JWT: config.JWT{Secret: "staff-realm-secret-0123456789abcdef"}})
+
+	// Email yang sama dapat didaftarkan di masing-masing realm
+	customer, err := customers.RegisterLocal("same@example.com", "password123", "Cust", "Omer", "", "ID")
+	assert.NoError(t, err)
+	_, err = staff.RegisterLocal("same@example.com", "password123", "Staff", "Member", "", "ID")
+	assert.NoError(t, err)
+
+	// Token dari satu realm tidak berlaku di realm lain
+	token, err := customers.GenerateToken(customer)
+	assert.NoError(t, err)
+
+	_, err = customers.ValidateToken(token)
+	assert.NoError(t, err)
+
+	_, err = staff.ValidateToken(token)
+	assert.Error(t, err)
+}
+
+// TestNewRejectsInvalidConfig menguji bahwa konfigurasi salah ditolak saat inisialisasi
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	err := Init(config.Config{
+		Database: config.Database{Type: "sqlite", Database: ":memory:"},
+		JWT:      config.JWT{Secret: "too-short"},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "jwt.secret")
+	assert.Contains(t, err.Error(), "jwt.expires_in")
+}
+
+// TestRefreshTokenRotation menguji rotasi refresh token dan deteksi pemakaian ulang
+func TestRefreshTokenRotation(t *testing.T) {
+	a := newTestAuth(t, config.Config{})
+
+	user, err := a.RegisterLocal("refresh@example.com", "password123", "Refresh", "User", "", "ID")
+	assert.NoError(t, err)
+
+	first, err := a.IssueTokens(user)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, first.AccessToken)
+	assert.NotEmpty(t, first.RefreshToken)
+
+	// Refresh token hanya disimpan dalam bentuk hash
+	var stored models.RefreshToken
+	assert.NoError(t, a.DB().First(&stored).Error)
+	assert.NotEqual(t, first.RefreshToken, stored.TokenHash)
+
+	// Test case 1: Rotasi menghasilkan refresh token baru
+	second, err := a.RefreshTokens(first.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	// Test case 2: Token yang sudah dirotasi dipakai lagi mencabut seluruh family
+	_, err = a.RefreshTokens(first.RefreshToken)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+	_, err = a.RefreshTokens(second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Test case 3: Family lain milik pengguna yang sama tidak terpengaruh
+	other, err := a.IssueTokens(user)
+	assert.NoError(t, err)
+	_, err = a.RefreshTokens(other.RefreshToken)
+	assert.NoError(t, err)
+
+	// Test case 4: Token tidak dikenal
+	_, err = a.RefreshTokens("unknown-token")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+// TestAsymmetricSigning menguji penandatanganan JWT dengan berbagai algoritma dan kid
+func TestAsymmetricSigning(t *testing.T) {
+	for _, alg := range []string{"HS256", "RS256", "ES256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			a := newTestAuth(t, config.Config{JWT: config.JWT{Algorithm: alg}})
+			user := &models.User{Email: "keys@example.com", Role: "user"}
+			user.ID = 7
+
+			tokenString, err := a.GenerateToken(user)
+			assert.NoError(t, err)
+
+			token, err := a.ValidateToken(tokenString)
+			assert.NoError(t, err)
+			assert.Equal(t, alg, token.Method.Alg())
+			assert.Equal(t, a.KeyRing().SigningKey().ID, token.Header["kid"])
+
+			// Token dari instance lain dengan algoritma yang sama ditolak
+			other := newTestAuth(t, config.Config{JWT: config.JWT{Algorithm: alg, Secret: "another-secret-0123456789abcdefghij"}})
+			_, err = other.ValidateToken(tokenString)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Private Key From PEM File", func(t *testing.T) {
+		generated, err := utils.GenerateSigningKey("ES256")
+		assert.NoError(t, err)
+		pemData, err := utils.EncodePrivateKeyPEM(generated.Private)
+		assert.NoError(t, err)
+
+		path := t.TempDir() + "/jwt.pem"
+		assert.NoError(t, os.WriteFile(path, pemData, 0600))
+
+		a := newTestAuth(t, config.Config{JWT: config.JWT{Algorithm: "ES256", PrivateKeyFile: path}})
+		assert.Equal(t, generated.ID, a.KeyRing().SigningKey().ID)
+
+		tokenString, err := a.GenerateToken(&models.User{Email: "pem@example.com"})
+		assert.NoError(t, err)
+
+		// Layanan lain cukup memegang public key untuk memverifikasi
+		verifier, err := utils.NewVerificationKey("ES256", generated.Public, "")
+		assert.NoError(t, err)
+		_, err = utils.ValidateJWTWithKeyRing(tokenString, utils.NewKeyRing(verifier))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Algorithm Confusion Rejected", func(t *testing.T) {
+		a := newTestAuth(t, config.Config{JWT: config.JWT{Algorithm: "RS256"}})
+		key := a.KeyRing().SigningKey()
+
+		// Token HS256 yang memakai kid kunci RSA harus ditolak
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1})
+		forged.Header["kid"] = key.ID
+		forgedString, err := forged.SignedString([]byte("attacker-controlled-secret"))
+		assert.NoError(t, err)
+
+		_, err = a.ValidateToken(forgedString)
+		assert.Error(t, err)
+	})
+}
+
+// TestTypedClaims menguji klaim terdaftar, issuer/audience dan ClaimsEnricher
+func TestTypedClaims(t *testing.T) {
+	cfg := config.Config{JWT: config.JWT{
+		Issuer:   "https://auth.example.com",
+		Audience: []string{"orders-api", "billing-api"},
+		Leeway:   30,
+	}}
+	a := newTestAuth(t, cfg)
+	a.SetClaimsEnricher(func(user *models.User, claims *Claims) error {
+		claims.Set("tenant_id", "tenant-42")
+		claims.Set("role", "admin") // klaim standar tidak boleh ditimpa
+		return nil
+	})
+
+	user := &models.User{Email: "claims@example.com", Role: "user"}
+	user.ID = 9
+
+	tokenString, err := a.GenerateToken(user)
+	assert.NoError(t, err)
+
+	t.Run("Registered And Custom Claims", func(t *testing.T) {
+		token, err := a.ValidateToken(tokenString)
+		assert.NoError(t, err)
+
+		claims := token.Claims.(*Claims)
+		assert.Equal(t, uint(9), claims.UserID)
+		assert.Equal(t, "9", claims.Subject)
+		assert.Equal(t, "https://auth.example.com", claims.Issuer)
+		assert.Equal(t, jwt.ClaimStrings{"orders-api", "billing-api"}, claims.Audience)
+		assert.NotEmpty(t, claims.ID)
+		assert.NotNil(t, claims.NotBefore)
+		assert.Equal(t, "user", claims.Role)
+		assert.Equal(t, "tenant-42", claims.Custom["tenant_id"])
+
+		// iat mempertahankan presisi sub-detik
+		assert.NotZero(t, claims.IssuedAt.Nanosecond())
+
+		// Bentuk lama di konteks "user" tetap tersedia
+		m := claims.Map()
+		assert.Equal(t, float64(9), m["user_id"])
+		assert.Equal(t, "tenant-42", m["tenant_id"])
+	})
+
+	t.Run("Issuer And Audience Enforced", func(t *testing.T) {
+		otherIssuer := newTestAuth(t, config.Config{JWT: config.JWT{Issuer: "https://evil.example.com"}})
+		otherIssuer.keys = a.keys
+		forged, err := otherIssuer.GenerateToken(user)
+		assert.NoError(t, err)
+		_, err = a.ValidateToken(forged)
+		assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+		otherAudience := newTestAuth(t, config.Config{JWT: config.JWT{Issuer: cfg.JWT.Issuer, Audience: []string{"admin-api"}}})
+		otherAudience.keys = a.keys
+		wrongAud, err := otherAudience.GenerateToken(user)
+		assert.NoError(t, err)
+		_, err = a.ValidateToken(wrongAud)
+		assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	})
+
+	t.Run("Leeway Accepts Small Clock Skew", func(t *testing.T) {
+		claims, err := utils.NewClaims(*user, a.config.JWT)
+		assert.NoError(t, err)
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+		expired, err := utils.SignJWT(claims, a.KeyRing().SigningKey())
+		assert.NoError(t, err)
+
+		_, err = a.ValidateToken(expired)
+		assert.NoError(t, err)
+
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		expired, err = utils.SignJWT(claims, a.KeyRing().SigningKey())
+		assert.NoError(t, err)
+		_, err = a.ValidateToken(expired)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("Enricher Error Aborts Issuance", func(t *testing.T) {
+		b := newTestAuth(t, config.Config{})
+		b.SetClaimsEnricher(func(*models.User, *Claims) error { return fmt.Errorf("tenant tidak ditemukan") })
+		_, err := b.GenerateToken(user)
+		assert.Error(t, err)
+	})
+}
+
+// TestOTPSender menguji pengiriman OTP melalui OTPSender yang terdaftar
+func TestOTPSender(t *testing.T) {
+	a := newTestAuth(t, config.Config{})
+	user, err := a.RegisterLocal("sender@example.com", "password123", "Sender", "Test", "081234567890", "ID")
+	assert.NoError(t, err)
+
+	var sentTo, sentMessage string
+	a.SetOTPSender("email", OTPSenderFunc(func(ctx context.Context, to, message string) error {
+		sentTo, sentMessage = to, message
+		return nil
+	}))
+	a.SetOTPSender("sms", OTPSenderFunc(func(ctx context.Context, to, message string) error {
+		return fmt.Errorf("saldo tidak cukup")
+	}))
+
+	t.Run("Delivered", func(t *testing.T) {
+		otpCode, err := a.RequestOTPLogin(user.Email, "email", "ID")
+		assert.NoError(t, err)
+		assert.Equal(t, user.Email, sentTo)
+		assert.Contains(t, sentMessage, otpCode.Code)
+	})
+
+	t.Run("Delivery Error", func(t *testing.T) {
+		_, err := a.RequestOTPLogin("081234567890", "sms", "ID")
+		assert.ErrorIs(t, err, ErrOTPDelivery)
+		assert.Contains(t, err.Error(), "saldo tidak cukup")
+	})
+
+	t.Run("No Sender Registered", func(t *testing.T) {
+		delete(a.senders, "whatsapp")
+		_, err := a.RequestOTPLogin("081234567890", "whatsapp", "ID")
+		assert.ErrorIs(t, err, ErrNoOTPSender)
+		assert.ErrorIs(t, err, ErrOTPDelivery)
+	})
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"gorm.io/gorm"
+)
+
+// Error yang dikembalikan oleh fungsi kode cadangan
+var (
+	ErrInvalidBackupCode = errors.New("kode cadangan tidak valid atau sudah dipakai")
+	ErrReauthRequired    = errors.New("kode OTP, kode TOTP, kode cadangan atau password wajib diisi")
+	ErrInvalidPassword   = errors.New("password tidak valid")
+	ErrInvalidOTPCode    = errors.New("kode OTP tidak valid atau sudah kedaluwarsa")
+)
+
+// Format kode cadangan: huruf kecil tanpa i, l dan o agar tidak tertukar
+// dengan angka, ditampilkan sebagai dua kelompok lima huruf
+const (
+	backupCodeCount    = 10
+	backupCodeLength   = 10
+	backupCodeAlphabet = "abcdefghjkmnpqrstuvwxyz"
+)
+
+// GenerateBackupCodes membuat set kode cadangan baru untuk pengguna dan
+// membatalkan set sebelumnya. Kode hanya dikembalikan sekali; database
+// menyimpan hash-nya.
+func (a *Auth) GenerateBackupCodes(userID uint) ([]string, error) {
+	key, err := a.mfaKey()
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, backupCodeCount)
+	records := make([]models.BackupCode, backupCodeCount)
+	for i := range codes {
+		code, err := randomBackupCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code[:backupCodeLength/2] + "-" + code[backupCodeLength/2:]
+		records[i] = models.BackupCode{UserID: userID, CodeHash: utils.HashOTP(key, code)}
+	}
+
+	err = a.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.BackupCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RegenerateBackupCodes membuat set kode cadangan baru seperti
+// GenerateBackupCodes setelah pengguna membuktikan identitasnya dengan kode
+// TOTP, kode cadangan yang belum dipakai, password, atau kode OTP login yang
+// baru dikirim jika otpType ("sms", "whatsapp" atau "email") diisi. Token
+// yang dicuri saja tidak cukup untuk mengganti kode cadangan.
+func (a *Auth) RegenerateBackupCodes(userID uint, code, otpType, password string) ([]string, error) {
+	var user models.User
+	if err := a.DB().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code != "" && isBackupCode(code):
+		if err := a.UseBackupCode(user.ID, code); err != nil {
+			return nil, err
+		}
+	case code != "" && otpType != "":
+		// Pengguna OTP tanpa password maupun TOTP membuktikan kepemilikan
+		// kontaknya dengan kode dari POST /auth/request-otp
+		key, err := a.otpKey()
+		if err != nil {
+			return nil, err
+		}
+		valid, err := utils.VerifyOTP(a.DB(), key, user.ID, code, otpType, "login", a.otpMaxAttempts())
+		if err != nil {
+			return nil, err
+		}
+		if !valid {
+			return nil, ErrInvalidOTPCode
+		}
+	case code != "":
+		if !user.TOTPEnabled {
+			return nil, ErrInvalidTOTPCode
+		}
+		if err := a.checkTOTP(&user, code); err != nil {
+			return nil, err
+		}
+	case password != "":
+		if user.Password == "" || !utils.CheckPasswordHash(password, user.Password) {
+			return nil, ErrInvalidPassword
+		}
+	default:
+		return nil, ErrReauthRequired
+	}
+
+	return a.GenerateBackupCodes(user.ID)
+}
+
+// UseBackupCode memakai satu kode cadangan milik pengguna. Spasi, tanda
+// hubung dan huruf besar diabaikan.
+func (a *Auth) UseBackupCode(userID uint, code string) error {
+	key, err := a.mfaKey()
+	if err != nil {
+		return err
+	}
+
+	// Update bersyarat agar satu kode tidak dapat dipakai dua kali secara bersamaan
+	res := a.DB().Model(&models.BackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashOTP(key, normalizeBackupCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidBackupCode
+	}
+	return nil
+}
+
+// BackupCodesRemaining menghitung kode cadangan pengguna yang belum dipakai
+func (a *Auth) BackupCodesRemaining(userID uint) (int64, error) {
+	var count int64
+	err := a.DB().Model(&models.BackupCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// isBackupCode membedakan kode cadangan dari kode OTP dan TOTP yang hanya
+// berisi angka
+func isBackupCode(code string) bool {
+	return strings.ContainsAny(normalizeBackupCode(code), backupCodeAlphabet)
+}
+
+// normalizeBackupCode menghapus spasi dan tanda hubung serta mengubah kode
+// menjadi huruf kecil
+func normalizeBackupCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+// randomBackupCode membuat backupCodeLength huruf acak dari backupCodeAlphabet
+func randomBackupCode() (string, error) {
+	max := big.NewInt(int64(len(backupCodeAlphabet)))
+	b := make([]byte, backupCodeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = backupCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
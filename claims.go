@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+)
+
+// Claims adalah klaim access token, lihat utils.Claims
+type Claims = utils.Claims
+
+// ClaimsEnricher dipanggil setiap kali access token diterbitkan, sehingga
+// aplikasi dapat menambahkan klaim seperti tenant_id atau daftar izin:
+//
+//	a.SetClaimsEnricher(func(user *models.User, claims *auth.Claims) error {
+//		claims.Set("tenant_id", tenantOf(user))
+//		return nil
+//	})
+//
+// Error dari enricher membatalkan penerbitan token.
+type ClaimsEnricher func(user *models.User, claims *Claims) error
+
+// SetClaimsEnricher mengatur hook untuk menambahkan klaim ke access token
+func (a *Auth) SetClaimsEnricher(enricher ClaimsEnricher) {
+	a.enricher = enricher
+}
+
+// newClaims membuat klaim token untuk pengguna dan menjalankan enricher
+func (a *Auth) newClaims(user *models.User) (*Claims, error) {
+	claims, err := utils.NewClaims(*user, a.config.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.enricher != nil {
+		if err := a.enricher(user, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
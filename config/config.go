@@ -7,6 +7,11 @@ type Config struct {
 	JWT       JWT       `json:"jwt"`
 	Session   Session   `json:"session"`
 	OTP       OTP       `json:"otp"`
+
+	PasswordReset     PasswordReset     `json:"password_reset"`
+	EmailVerification EmailVerification `json:"email_verification"`
+	MFA               MFA               `json:"mfa"`
+	WebAuthn          WebAuthn          `json:"webauthn"`
 }
 
 // Database adalah konfigurasi untuk koneksi database
@@ -20,7 +25,9 @@ type Database struct {
 	AutoMigrate bool   `json:"auto_migrate"`
 }
 
-// Providers berisi konfigurasi untuk berbagai provider OAuth
+// Providers berisi konfigurasi untuk berbagai provider OAuth. Provider dapat
+// diatur melalui field bernama (Google, Twitter, GitHub, Facebook), map OIDC,
+// atau map OAuth yang menerima semua tipe provider termasuk provider custom.
 type Providers struct {
 	Google   OAuth `json:"google"`
 	Twitter  OAuth `json:"twitter"`
@@ -28,27 +35,154 @@ type Providers struct {
 	Facebook OAuth `json:"facebook"`
 	Local    bool  `json:"local"`    // Aktifkan autentikasi lokal (email/password)
 	OTPAuth  bool  `json:"otp_auth"` // Aktifkan autentikasi OTP
+
+	// OAuth berisi provider berdasarkan nama, misalnya "github-enterprise"
+	// atau "gitlab". Tipe implementasinya ditentukan oleh OAuth.Type.
+	OAuth map[string]OAuth `json:"oauth"`
+
+	// OIDC berisi provider OpenID Connect generik (Keycloak, Okta, Azure AD,
+	// Auth0, dst.) berdasarkan nama, misalnya "keycloak" atau "okta". Setiap
+	// provider wajib mengisi Issuer.
+	OIDC map[string]OAuth `json:"oidc"`
+}
+
+// OAuthProviders menggabungkan semua bentuk konfigurasi provider menjadi satu
+// map berdasarkan nama, dengan Type yang sudah diisi. Seperti field bernama,
+// entri map OIDC dan OAuth hanya disertakan jika diaktifkan.
+func (p Providers) OAuthProviders() map[string]OAuth {
+	all := make(map[string]OAuth)
+	named := []struct {
+		name string
+		cfg  OAuth
+	}{
+		{ProviderGoogle, p.Google},
+		{ProviderTwitter, p.Twitter},
+		{ProviderGitHub, p.GitHub},
+		{ProviderFacebook, p.Facebook},
+	}
+	for _, n := range named {
+		if n.cfg.Enabled {
+			n.cfg.Type = n.name
+			all[n.name] = n.cfg
+		}
+	}
+	for name, cfg := range p.OIDC {
+		if !cfg.Enabled {
+			continue
+		}
+		cfg.Type = ProviderOIDC
+		all[name] = cfg
+	}
+	for name, cfg := range p.OAuth {
+		if !cfg.Enabled {
+			continue
+		}
+		cfg.Type = cfg.ProviderType(name)
+		all[name] = cfg
+	}
+	return all
 }
 
+// Tipe provider untuk OAuth.Type
+const (
+	ProviderGoogle   = "google"
+	ProviderTwitter  = "twitter"
+	ProviderGitHub   = "github"
+	ProviderFacebook = "facebook"
+	ProviderOIDC     = "oidc"
+	ProviderCustom   = "custom" // implementasi didaftarkan aplikasi dengan Auth.RegisterProvider
+)
+
 // OAuth berisi konfigurasi untuk provider OAuth
 type OAuth struct {
 	Enabled      bool     `json:"enabled"`
+	Type         string   `json:"type"` // tipe provider di Providers.OAuth; lihat ProviderType
 	ClientID     string   `json:"client_id"`
 	ClientSecret string   `json:"client_secret"`
 	CallbackURL  string   `json:"callback_url"`
 	Scopes       []string `json:"scopes"`
+	AuthURL      string   `json:"auth_url"`    // menimpa endpoint otorisasi, misalnya untuk GitHub Enterprise
+	TokenURL     string   `json:"token_url"`   // menimpa endpoint penukaran code
+	APIURL       string   `json:"api_url"`     // menimpa base URL API profil pengguna
+	Issuer       string   `json:"issuer"`      // issuer OpenID Connect; endpoint dibaca dari /.well-known/openid-configuration
+	TrustEmail   bool     `json:"trust_email"` // hubungkan dengan pengguna yang ada berdasarkan email dari provider; wajib untuk Facebook dan OIDC
+}
+
+// ProviderType mengembalikan tipe provider bernama name: Type jika diisi,
+// "oidc" jika Issuer diisi, atau name itu sendiri
+func (o OAuth) ProviderType(name string) string {
+	switch {
+	case o.Type != "":
+		return o.Type
+	case o.Issuer != "":
+		return ProviderOIDC
+	}
+	return name
 }
 
 // JWT berisi konfigurasi untuk token JWT
 type JWT struct {
-	Secret    string `json:"secret"`
-	ExpiresIn int64  `json:"expires_in"` // dalam detik
+	Secret           string   `json:"secret"`
+	ExpiresIn        int64    `json:"expires_in"`         // dalam detik
+	RefreshExpiresIn int64    `json:"refresh_expires_in"` // masa berlaku refresh token dalam detik
+	Algorithm        string   `json:"algorithm"`          // HS256 (default), HS384, HS512, RS256, RS384, RS512, ES256, ES384, ES512, EdDSA
+	PrivateKeyFile   string   `json:"private_key_file"`   // file PEM untuk algoritma asimetris; kosong berarti dibuat otomatis saat startup
+	KeyID            string   `json:"key_id"`             // kid pada header token; kosong berarti diturunkan dari kunci
+	KeyDir           string   `json:"key_dir"`            // direktori kunci bersama untuk beberapa instance; kunci hasil rotasi disimpan di sini
+	RetiredKeyFiles  []string `json:"retired_key_files"`  // file PEM kunci lama yang masih diterima untuk verifikasi
+	RotationInterval int64    `json:"rotation_interval"`  // rotasi kunci otomatis dalam detik; 0 berarti nonaktif. Tanpa key_dir hanya untuk satu instance
+	Issuer           string   `json:"issuer"`             // klaim iss; jika diisi, token dengan iss lain ditolak
+	Audience         []string `json:"audience"`           // klaim aud; jika diisi, token harus memuat salah satunya
+	Leeway           int64    `json:"leeway"`             // toleransi selisih jam dalam detik untuk exp, nbf dan iat
 }
 
 // Session berisi konfigurasi untuk pengelolaan sesi
 type Session struct {
-	Secret    string `json:"secret"`
-	ExpiresIn int64  `json:"expires_in"` // dalam detik
+	Secret         string `json:"secret"`
+	ExpiresIn      int64  `json:"expires_in"`       // masa berlaku absolut dalam detik
+	Enabled        bool   `json:"enabled"`          // login membuat sesi cookie sebagai pengganti bearer token
+	IdleTimeout    int64  `json:"idle_timeout"`     // sesi berakhir jika tidak aktif selama ini (detik); 0 berarti nonaktif
+	CookieName     string `json:"cookie_name"`      // default "kreasimaju_session"
+	CookieDomain   string `json:"cookie_domain"`    // kosong berarti host saat ini
+	CookiePath     string `json:"cookie_path"`      // default "/"
+	CookieSameSite string `json:"cookie_same_site"` // "lax" (default), "strict" atau "none"
+	CookieInsecure bool   `json:"cookie_insecure"`  // kirim cookie tanpa atribut Secure, hanya untuk pengembangan lokal via HTTP
+
+	// TrustedOrigins adalah origin frontend di domain lain yang boleh
+	// mengirim permintaan POST/PUT/PATCH/DELETE dengan cookie sesi, misalnya
+	// "https://app.example.com". Permintaan lintas origin lain ditolak.
+	TrustedOrigins []string `json:"trusted_origins"`
+}
+
+// PasswordReset berisi konfigurasi untuk lupa password
+type PasswordReset struct {
+	Method    string `json:"method"`     // "link" (default) atau "otp"
+	ExpiresIn int64  `json:"expires_in"` // masa berlaku token reset dalam detik
+	URL       string `json:"url"`        // halaman reset password di frontend; token ditambahkan sebagai query ?token=
+}
+
+// EmailVerification berisi konfigurasi untuk verifikasi email
+type EmailVerification struct {
+	Enabled        bool   `json:"enabled"`         // kirim token verifikasi saat registrasi
+	ExpiresIn      int64  `json:"expires_in"`      // masa berlaku token verifikasi dalam detik
+	ResendCooldown int64  `json:"resend_cooldown"` // jarak minimum antar pengiriman ulang dalam detik
+	URL            string `json:"url"`             // halaman verifikasi di frontend; token ditambahkan sebagai query ?token=
+}
+
+// MFA berisi konfigurasi autentikasi dua langkah
+type MFA struct {
+	Issuer             string `json:"issuer"`               // nama aplikasi yang tampil di authenticator
+	EncryptionKey      string `json:"encryption_key"`       // key enkripsi secret TOTP; kosong berarti diturunkan dari jwt.secret, wajib jika jwt.secret kosong
+	ChallengeExpiresIn int64  `json:"challenge_expires_in"` // masa berlaku tantangan MFA saat login dalam detik
+}
+
+// WebAuthn berisi konfigurasi relying party untuk login dengan passkey
+type WebAuthn struct {
+	Enabled       bool     `json:"enabled"`
+	RPID          string   `json:"rp_id"`           // domain aplikasi tanpa skema dan port, misalnya "example.com"
+	RPDisplayName string   `json:"rp_display_name"` // nama aplikasi yang tampil saat membuat passkey
+	RPOrigins     []string `json:"rp_origins"`      // origin frontend yang diizinkan, misalnya "https://app.example.com"
+	Timeout       int64    `json:"timeout"`         // batas waktu ceremony registrasi dan login dalam detik
 }
 
 // OTP berisi konfigurasi untuk One-Time Password
@@ -57,11 +191,23 @@ type OTP struct {
 	DefaultType string      `json:"default_type"` // "sms", "email", "whatsapp"
 	Length      int         `json:"length"`       // jumlah digit OTP
 	ExpiresIn   int64       `json:"expires_in"`   // dalam detik
+	Secret      string      `json:"secret"`       // key HMAC untuk hash kode OTP; kosong berarti diturunkan dari jwt.secret, wajib jika jwt.secret kosong
+	MaxAttempts int         `json:"max_attempts"` // percobaan salah sebelum kode OTP dibatalkan
+	Quota       OTPQuota    `json:"quota"`
 	SMS         OTPProvider `json:"sms"`
 	Email       OTPProvider `json:"email"`
 	WhatsApp    OTPProvider `json:"whatsapp"`
 }
 
+// OTPQuota membatasi pengiriman OTP per email/nomor telepon dan per IP klien
+type OTPQuota struct {
+	ResendCooldown int64 `json:"resend_cooldown"` // jarak minimum antar pengiriman ke target yang sama (detik)
+	HourlyLimit    int   `json:"hourly_limit"`    // pengiriman per jam per target
+	DailyLimit     int   `json:"daily_limit"`     // pengiriman per 24 jam per target
+	IPHourlyLimit  int   `json:"ip_hourly_limit"` // pengiriman per jam per IP klien
+	IPDailyLimit   int   `json:"ip_daily_limit"`  // pengiriman per 24 jam per IP klien
+}
+
 // OTPProvider berisi konfigurasi untuk provider OTP
 type OTPProvider struct {
 	Enabled  bool   `json:"enabled"`
@@ -0,0 +1,359 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix adalah awalan nama variabel environment yang dibaca oleh Load.
+// Nama variabel dibentuk dari tag json setiap field, misalnya JWT.Secret
+// menjadi KREASIMAJU_AUTH_JWT_SECRET dan Providers.Google.ClientID menjadi
+// KREASIMAJU_AUTH_PROVIDERS_GOOGLE_CLIENT_ID. Untuk provider bernama di
+// Providers.OIDC dan Providers.OAuth, nama provider ditulis dengan huruf besar
+// dan "-" diganti "_", misalnya KREASIMAJU_AUTH_PROVIDERS_OIDC_KEYCLOAK_CLIENT_SECRET.
+const EnvPrefix = "KREASIMAJU_AUTH_"
+
+// fileSuffix menandai variabel environment yang berisi path ke file secret,
+// misalnya KREASIMAJU_AUTH_JWT_SECRET_FILE=/run/secrets/jwt_secret
+const fileSuffix = "_FILE"
+
+// Nilai default yang diterapkan oleh ApplyDefaults
+const (
+	DefaultOTPLength      = 6
+	DefaultOTPExpiresIn   = 300 // 5 menit
+	DefaultOTPMaxAttempts = 5
+
+	DefaultOTPResendCooldown = 60 // 1 menit
+	DefaultOTPHourlyLimit    = 5
+	DefaultOTPDailyLimit     = 10
+	DefaultOTPIPHourlyLimit  = 20
+	DefaultOTPIPDailyLimit   = 100
+
+	DefaultJWTExpiresIn = 86400 // 24 jam
+
+	DefaultRefreshExpiresIn = 30 * 86400 // 30 hari
+	DefaultSessionExpiresIn = 7 * 86400  // 7 hari
+	DefaultSessionCookie    = "kreasimaju_session"
+
+	DefaultPasswordResetExpiresIn = 3600 // 1 jam
+
+	DefaultEmailVerificationExpiresIn = 86400 // 24 jam
+	DefaultEmailVerificationCooldown  = 60    // 1 menit
+
+	DefaultMFAIssuer             = "KreasiMaju"
+	DefaultMFAChallengeExpiresIn = 300 // 5 menit
+
+	DefaultWebAuthnRPDisplayName = "KreasiMaju"
+	DefaultWebAuthnTimeout       = 300 // 5 menit
+)
+
+// Load membaca konfigurasi dari file JSON atau YAML (berdasarkan ekstensi),
+// menimpanya dengan variabel environment KREASIMAJU_AUTH_*, lalu menerapkan
+// nilai default. Jika path kosong, konfigurasi hanya dibaca dari environment.
+func Load(path string) (Config, error) {
+	var cfg Config
+
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := ApplyEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	cfg.ApplyDefaults()
+
+	return cfg, nil
+}
+
+// loadFile membaca file konfigurasi JSON atau YAML ke dalam cfg
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("gagal membaca file konfigurasi: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return fmt.Errorf("gagal mem-parse konfigurasi JSON %s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		// YAML diubah ke JSON terlebih dahulu agar nama field cukup
+		// didefinisikan sekali melalui tag json
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("gagal mem-parse konfigurasi YAML %s: %w", path, err)
+		}
+		converted, err := json.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("gagal mem-parse konfigurasi YAML %s: %w", path, err)
+		}
+		if err := json.Unmarshal(converted, cfg); err != nil {
+			return fmt.Errorf("gagal mem-parse konfigurasi YAML %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("format file konfigurasi tidak didukung: %s", path)
+	}
+
+	return nil
+}
+
+// ApplyEnv menimpa nilai cfg dengan variabel environment KREASIMAJU_AUTH_*.
+// Untuk setiap variabel, varian dengan akhiran _FILE dapat dipakai untuk
+// membaca nilai dari file (misalnya Docker atau Kubernetes secret).
+func ApplyEnv(cfg *Config) error {
+	return applyEnv(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(EnvPrefix, "_"))
+}
+
+// applyEnv menelusuri field struct secara rekursif dan mengisi nilainya dari environment
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := envName(field)
+		if name == "" {
+			continue
+		}
+		key := prefix + "_" + name
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnv(fv, key); err != nil {
+				return err
+			}
+			continue
+		}
+		if fv.Kind() == reflect.Map {
+			if err := applyEnvMap(fv, key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok, err := lookupEnv(key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setValue(fv, value); err != nil {
+			return fmt.Errorf("nilai %s tidak valid: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// applyEnvMap mengisi map bernama (misalnya Providers.OIDC) dari environment.
+// Entri yang sudah ada dapat ditimpa dan entri baru dibuat dari variabel
+// PREFIX_<NAMA>_<FIELD>. Map dengan nilai selain struct diabaikan.
+func applyEnvMap(fv reflect.Value, prefix string) error {
+	t := fv.Type()
+	if t.Key().Kind() != reflect.String || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	// Nama entri di environment dipetakan ke key map; key yang sudah ada
+	// diutamakan agar penulisan di file konfigurasi dipertahankan
+	keys := make(map[string]string)
+	for _, name := range envMapNames(t.Elem(), prefix) {
+		keys[name] = strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+	}
+	for _, k := range fv.MapKeys() {
+		keys[envMapName(k.String())] = k.String()
+	}
+
+	for name, key := range keys {
+		elem := reflect.New(t.Elem()).Elem()
+		if existing := fv.MapIndex(reflect.ValueOf(key)); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := applyEnv(elem, prefix+"_"+name); err != nil {
+			return err
+		}
+
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMap(t))
+		}
+		fv.SetMapIndex(reflect.ValueOf(key), elem)
+	}
+	return nil
+}
+
+// envMapNames mencari nama entri map dari variabel environment berawalan
+// prefix. Nama adalah bagian di antara prefix dan nama field elem.
+func envMapNames(elem reflect.Type, prefix string) []string {
+	var fields []string
+	for i := 0; i < elem.NumField(); i++ {
+		if name := envName(elem.Field(i)); name != "" && elem.Field(i).IsExported() {
+			fields = append(fields, name)
+		}
+	}
+
+	var names []string
+	for _, env := range os.Environ() {
+		key, _, _ := strings.Cut(env, "=")
+		rest, ok := strings.CutPrefix(key, prefix+"_")
+		if !ok {
+			continue
+		}
+		rest = strings.TrimSuffix(rest, fileSuffix)
+
+		// Field terpanjang dipilih agar CLIENT_ID tidak terbaca sebagai ID
+		match := ""
+		for _, field := range fields {
+			if strings.HasSuffix(rest, "_"+field) && len(field) > len(match) {
+				match = field
+			}
+		}
+		if match != "" {
+			names = append(names, strings.TrimSuffix(rest, "_"+match))
+		}
+	}
+	return names
+}
+
+// envMapName mengubah key map menjadi bagian nama variabel environment
+func envMapName(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// envName mengubah tag json field menjadi bagian nama variabel environment
+func envName(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	if tag == "-" {
+		return ""
+	}
+	if tag == "" {
+		tag = field.Name
+	}
+	return strings.ToUpper(tag)
+}
+
+// lookupEnv membaca variabel environment beserta varian _FILE-nya
+func lookupEnv(key string) (string, bool, error) {
+	value, hasValue := os.LookupEnv(key)
+	path, hasFile := os.LookupEnv(key + fileSuffix)
+
+	if hasValue && hasFile {
+		return "", false, fmt.Errorf("%s dan %s tidak boleh diisi bersamaan", key, key+fileSuffix)
+	}
+
+	if hasFile {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("gagal membaca %s: %w", key+fileSuffix, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+
+	return value, hasValue, nil
+}
+
+// setValue mengisi field dengan nilai string dari environment
+func setValue(fv reflect.Value, value string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+// ApplyDefaults mengisi nilai default untuk field yang belum diatur
+func (c *Config) ApplyDefaults() {
+	if c.OTP.Length <= 0 {
+		c.OTP.Length = DefaultOTPLength
+	}
+	if c.OTP.ExpiresIn <= 0 {
+		c.OTP.ExpiresIn = DefaultOTPExpiresIn
+	}
+	if c.OTP.MaxAttempts <= 0 {
+		c.OTP.MaxAttempts = DefaultOTPMaxAttempts
+	}
+	if c.OTP.Quota.ResendCooldown <= 0 {
+		c.OTP.Quota.ResendCooldown = DefaultOTPResendCooldown
+	}
+	if c.OTP.Quota.HourlyLimit <= 0 {
+		c.OTP.Quota.HourlyLimit = DefaultOTPHourlyLimit
+	}
+	if c.OTP.Quota.DailyLimit <= 0 {
+		c.OTP.Quota.DailyLimit = DefaultOTPDailyLimit
+	}
+	if c.OTP.Quota.IPHourlyLimit <= 0 {
+		c.OTP.Quota.IPHourlyLimit = DefaultOTPIPHourlyLimit
+	}
+	if c.OTP.Quota.IPDailyLimit <= 0 {
+		c.OTP.Quota.IPDailyLimit = DefaultOTPIPDailyLimit
+	}
+	if c.JWT.ExpiresIn <= 0 {
+		c.JWT.ExpiresIn = DefaultJWTExpiresIn
+	}
+	if c.JWT.RefreshExpiresIn <= 0 {
+		c.JWT.RefreshExpiresIn = DefaultRefreshExpiresIn
+	}
+	if c.Session.ExpiresIn <= 0 {
+		c.Session.ExpiresIn = DefaultSessionExpiresIn
+	}
+	if c.Session.CookieName == "" {
+		c.Session.CookieName = DefaultSessionCookie
+	}
+	if c.PasswordReset.ExpiresIn <= 0 {
+		c.PasswordReset.ExpiresIn = DefaultPasswordResetExpiresIn
+	}
+	if c.EmailVerification.ExpiresIn <= 0 {
+		c.EmailVerification.ExpiresIn = DefaultEmailVerificationExpiresIn
+	}
+	if c.EmailVerification.ResendCooldown <= 0 {
+		c.EmailVerification.ResendCooldown = DefaultEmailVerificationCooldown
+	}
+	if c.MFA.Issuer == "" {
+		c.MFA.Issuer = DefaultMFAIssuer
+	}
+	if c.MFA.ChallengeExpiresIn <= 0 {
+		c.MFA.ChallengeExpiresIn = DefaultMFAChallengeExpiresIn
+	}
+	if c.WebAuthn.RPDisplayName == "" {
+		c.WebAuthn.RPDisplayName = DefaultWebAuthnRPDisplayName
+	}
+	if c.WebAuthn.Timeout <= 0 {
+		c.WebAuthn.Timeout = DefaultWebAuthnTimeout
+	}
+}
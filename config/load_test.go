@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFile menulis konten ke file sementara dan mengembalikan path-nya
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	return path
+}
+
+// TestLoad menguji pemuatan konfigurasi dari file dan environment
+func TestLoad(t *testing.T) {
+	t.Run("JSON File", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{
+			"database": {"type": "sqlite", "database": "auth.db", "auto_migrate": true},
+			"providers": {"google": {"enabled": true, "client_id": "gid", "scopes": ["email"]}},
+			"jwt": {"secret": "from-json"}
+		}`)
+
+		cfg, err := Load(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "sqlite", cfg.Database.Type)
+		assert.True(t, cfg.Database.AutoMigrate)
+		assert.Equal(t, "gid", cfg.Providers.Google.ClientID)
+		assert.Equal(t, []string{"email"}, cfg.Providers.Google.Scopes)
+		assert.Equal(t, "from-json", cfg.JWT.Secret)
+	})
+
+	t.Run("YAML File", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+database:
+  type: postgres
+  port: 5432
+providers:
+  github:
+    enabled: true
+    client_id: gh-id
+otp:
+  default_type: sms
+  length: 8
+`)
+
+		cfg, err := Load(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "postgres", cfg.Database.Type)
+		assert.Equal(t, 5432, cfg.Database.Port)
+		assert.Equal(t, "gh-id", cfg.Providers.GitHub.ClientID)
+		assert.Equal(t, "sms", cfg.OTP.DefaultType)
+		assert.Equal(t, 8, cfg.OTP.Length)
+	})
+
+	t.Run("Environment Overrides File", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{"jwt": {"secret": "from-json", "expires_in": 60}}`)
+		t.Setenv("KREASIMAJU_AUTH_JWT_SECRET", "from-env")
+		t.Setenv("KREASIMAJU_AUTH_DATABASE_AUTO_MIGRATE", "true")
+		t.Setenv("KREASIMAJU_AUTH_PROVIDERS_GOOGLE_SCOPES", "email, profile")
+
+		cfg, err := Load(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "from-env", cfg.JWT.Secret)
+		assert.Equal(t, int64(60), cfg.JWT.ExpiresIn)
+		assert.True(t, cfg.Database.AutoMigrate)
+		assert.Equal(t, []string{"email", "profile"}, cfg.Providers.Google.Scopes)
+	})
+
+	t.Run("Secret From File", func(t *testing.T) {
+		secretPath := writeFile(t, "jwt_secret", "from-secret-file\n")
+		t.Setenv("KREASIMAJU_AUTH_JWT_SECRET_FILE", secretPath)
+
+		cfg, err := Load("")
+		assert.NoError(t, err)
+		assert.Equal(t, "from-secret-file", cfg.JWT.Secret)
+	})
+
+	t.Run("Named Providers From Environment", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{"providers": {
+			"oidc": {"keycloak": {"enabled": true, "client_id": "kc", "issuer": "https://sso.example.com"}}
+		}}`)
+		secretPath := writeFile(t, "kc_secret", "from-secret-file\n")
+		t.Setenv("KREASIMAJU_AUTH_PROVIDERS_OIDC_KEYCLOAK_CLIENT_SECRET_FILE", secretPath)
+		t.Setenv("KREASIMAJU_AUTH_PROVIDERS_OAUTH_GITHUB_ENTERPRISE_ENABLED", "true")
+		t.Setenv("KREASIMAJU_AUTH_PROVIDERS_OAUTH_GITHUB_ENTERPRISE_TYPE", "github")
+		t.Setenv("KREASIMAJU_AUTH_PROVIDERS_OAUTH_GITHUB_ENTERPRISE_CLIENT_ID", "ghe")
+		t.Setenv("KREASIMAJU_AUTH_PROVIDERS_OAUTH_GITHUB_ENTERPRISE_CLIENT_SECRET", "ghe-secret")
+
+		cfg, err := Load(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "kc", cfg.Providers.OIDC["keycloak"].ClientID)
+		assert.Equal(t, "from-secret-file", cfg.Providers.OIDC["keycloak"].ClientSecret)
+		assert.Len(t, cfg.Providers.OIDC, 1)
+		assert.Equal(t, OAuth{Enabled: true, Type: "github", ClientID: "ghe", ClientSecret: "ghe-secret"}, cfg.Providers.OAuth["github-enterprise"])
+		assert.Len(t, cfg.Providers.OAuth, 1)
+	})
+
+	t.Run("Value And File Both Set", func(t *testing.T) {
+		secretPath := writeFile(t, "jwt_secret", "from-secret-file")
+		t.Setenv("KREASIMAJU_AUTH_JWT_SECRET", "from-env")
+		t.Setenv("KREASIMAJU_AUTH_JWT_SECRET_FILE", secretPath)
+
+		_, err := Load("")
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid Environment Value", func(t *testing.T) {
+		t.Setenv("KREASIMAJU_AUTH_OTP_LENGTH", "six")
+
+		_, err := Load("")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "KREASIMAJU_AUTH_OTP_LENGTH")
+	})
+
+	t.Run("Unsupported Extension", func(t *testing.T) {
+		path := writeFile(t, "config.toml", `secret = "x"`)
+
+		_, err := Load(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := Load("")
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultOTPLength, cfg.OTP.Length)
+		assert.Equal(t, int64(DefaultOTPExpiresIn), cfg.OTP.ExpiresIn)
+		assert.Equal(t, int64(DefaultJWTExpiresIn), cfg.JWT.ExpiresIn)
+	})
+}
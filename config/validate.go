@@ -0,0 +1,434 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MinJWTSecretLength adalah panjang minimum secret HMAC dalam byte.
+// RFC 7518 mensyaratkan kunci HS256 minimal sepanjang output hash (256 bit).
+const MinJWTSecretLength = 32
+
+// Batas panjang kode OTP yang dapat disimpan oleh models.OTPCode
+const (
+	MinOTPLength = 4
+	MaxOTPLength = 10
+)
+
+// FieldError menjelaskan satu masalah pada field konfigurasi
+type FieldError struct {
+	Field  string // path field sesuai tag json, misalnya "jwt.secret"
+	Reason string
+}
+
+// Error mengimplementasikan interface error
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// ValidationError berisi seluruh masalah yang ditemukan oleh Validate
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error mengimplementasikan interface error
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "konfigurasi tidak valid (%d masalah):", len(e.Errors))
+	for _, fe := range e.Errors {
+		b.WriteString("\n  - ")
+		b.WriteString(fe.Error())
+	}
+	return b.String()
+}
+
+// validator mengumpulkan masalah konfigurasi
+type validator struct {
+	errors []FieldError
+}
+
+// add mencatat satu masalah konfigurasi
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+// Validate memeriksa konfigurasi dan mengembalikan *ValidationError yang
+// berisi semua masalah sekaligus, atau nil jika konfigurasi valid
+func (c Config) Validate() error {
+	v := &validator{}
+
+	c.Database.validate(v)
+	c.JWT.validate(v)
+	c.Session.validate(v)
+	c.OTP.validate(v)
+	c.PasswordReset.validate(v)
+	c.EmailVerification.validate(v)
+	c.MFA.validate(v)
+	c.WebAuthn.validate(v)
+	c.Providers.validate(v)
+	c.validateSecrets(v)
+
+	if len(v.errors) > 0 {
+		return &ValidationError{Errors: v.errors}
+	}
+	return nil
+}
+
+// validateSecrets memeriksa key yang diturunkan dari jwt.secret jika tidak
+// diatur sendiri. Tanpa jwt.secret (algoritma asimetris), key tersebut wajib
+// diisi agar sama di semua instance dan setelah restart.
+func (c Config) validateSecrets(v *validator) {
+	if c.JWT.Secret != "" {
+		return
+	}
+	if c.OTP.Secret == "" {
+		v.add("otp.secret", "wajib diisi jika jwt.secret kosong")
+	}
+	if c.MFA.EncryptionKey == "" {
+		v.add("mfa.encryption_key", "wajib diisi jika jwt.secret kosong")
+	}
+}
+
+// validate memeriksa konfigurasi database
+func (d Database) validate(v *validator) {
+	switch d.Type {
+	case "":
+		// Koneksi dapat diberikan langsung, misalnya melalui auth.NewWithDB
+	case "mysql", "postgres":
+		if d.Host == "" {
+			v.add("database.host", "wajib diisi untuk database %s", d.Type)
+		}
+		if d.Database == "" {
+			v.add("database.database", "wajib diisi untuk database %s", d.Type)
+		}
+		if d.Port < 0 || d.Port > 65535 {
+			v.add("database.port", "harus berada di antara 0 dan 65535")
+		}
+	case "sqlite":
+		if d.Database == "" {
+			v.add("database.database", "path file SQLite wajib diisi")
+		}
+	default:
+		v.add("database.type", "harus salah satu dari mysql, postgres atau sqlite, bukan %q", d.Type)
+	}
+}
+
+// validate memeriksa konfigurasi JWT
+func (j JWT) validate(v *validator) {
+	switch j.Algorithm {
+	case "", "HS256", "HS384", "HS512":
+		// Secret hanya dibutuhkan untuk algoritma HMAC
+		if j.Secret == "" {
+			v.add("jwt.secret", "wajib diisi")
+		} else if len(j.Secret) < MinJWTSecretLength {
+			v.add("jwt.secret", "minimal %d byte, saat ini %d byte", MinJWTSecretLength, len(j.Secret))
+		}
+		if j.PrivateKeyFile != "" {
+			v.add("jwt.private_key_file", "tidak dipakai oleh algoritma HMAC %s", j.algorithm())
+		}
+		if j.KeyDir != "" {
+			v.add("jwt.key_dir", "tidak dipakai oleh algoritma HMAC %s", j.algorithm())
+		}
+		if len(j.RetiredKeyFiles) > 0 {
+			v.add("jwt.retired_key_files", "tidak dipakai oleh algoritma HMAC %s", j.algorithm())
+		}
+		if j.RotationInterval > 0 {
+			v.add("jwt.rotation_interval", "rotasi otomatis hanya didukung untuk algoritma asimetris")
+		}
+	case "RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA":
+		if j.PrivateKeyFile != "" && j.KeyDir != "" {
+			v.add("jwt.key_dir", "tidak dapat dipakai bersama jwt.private_key_file")
+		}
+		// Kunci hasil rotasi tidak dapat ditulis kembali ke private_key_file
+		if j.PrivateKeyFile != "" && j.RotationInterval > 0 {
+			v.add("jwt.rotation_interval", "tidak didukung dengan jwt.private_key_file; gunakan jwt.key_dir")
+		}
+	default:
+		v.add("jwt.algorithm", "algoritma %q tidak didukung", j.Algorithm)
+	}
+
+	if j.ExpiresIn <= 0 {
+		v.add("jwt.expires_in", "harus lebih dari 0 detik")
+	}
+
+	if j.RefreshExpiresIn < 0 {
+		v.add("jwt.refresh_expires_in", "tidak boleh negatif")
+	}
+
+	if j.RotationInterval < 0 {
+		v.add("jwt.rotation_interval", "tidak boleh negatif")
+	}
+
+	if j.Leeway < 0 {
+		v.add("jwt.leeway", "tidak boleh negatif")
+	}
+}
+
+// algorithm mengembalikan algoritma JWT dengan HS256 sebagai default
+func (j JWT) algorithm() string {
+	if j.Algorithm == "" {
+		return "HS256"
+	}
+	return j.Algorithm
+}
+
+// validate memeriksa konfigurasi sesi
+func (s Session) validate(v *validator) {
+	if s.ExpiresIn < 0 {
+		v.add("session.expires_in", "tidak boleh negatif")
+	}
+
+	if s.IdleTimeout < 0 {
+		v.add("session.idle_timeout", "tidak boleh negatif")
+	}
+
+	switch strings.ToLower(s.CookieSameSite) {
+	case "", "lax", "strict":
+	case "none":
+		// Browser menolak SameSite=None tanpa atribut Secure
+		if s.CookieInsecure {
+			v.add("session.cookie_same_site", "none membutuhkan cookie Secure")
+		}
+		// Cookie dikirim pada permintaan lintas situs, sehingga origin
+		// frontend harus didaftarkan secara eksplisit
+		if len(s.TrustedOrigins) == 0 {
+			v.add("session.trusted_origins", "wajib diisi jika cookie_same_site none")
+		}
+	default:
+		v.add("session.cookie_same_site", "harus salah satu dari lax, strict atau none, bukan %q", s.CookieSameSite)
+	}
+
+	for _, origin := range s.TrustedOrigins {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			v.add("session.trusted_origins", "origin %q harus berupa skema dan host, misalnya https://example.com", origin)
+		}
+	}
+}
+
+// validate memeriksa konfigurasi OTP
+func (o OTP) validate(v *validator) {
+	switch o.DefaultType {
+	case "", "sms", "email", "whatsapp":
+	default:
+		v.add("otp.default_type", "harus salah satu dari sms, email atau whatsapp, bukan %q", o.DefaultType)
+	}
+
+	if o.Length != 0 && (o.Length < MinOTPLength || o.Length > MaxOTPLength) {
+		v.add("otp.length", "harus berada di antara %d dan %d digit", MinOTPLength, MaxOTPLength)
+	}
+
+	if o.ExpiresIn < 0 {
+		v.add("otp.expires_in", "tidak boleh negatif")
+	}
+
+	if o.MaxAttempts < 0 {
+		v.add("otp.max_attempts", "tidak boleh negatif")
+	}
+
+	if o.Secret != "" && len(o.Secret) < MinJWTSecretLength {
+		v.add("otp.secret", "minimal %d byte, saat ini %d byte", MinJWTSecretLength, len(o.Secret))
+	}
+
+	if o.Quota.ResendCooldown < 0 {
+		v.add("otp.quota.resend_cooldown", "tidak boleh negatif")
+	}
+	if o.Quota.HourlyLimit < 0 {
+		v.add("otp.quota.hourly_limit", "tidak boleh negatif")
+	}
+	if o.Quota.DailyLimit < 0 {
+		v.add("otp.quota.daily_limit", "tidak boleh negatif")
+	}
+	if o.Quota.IPHourlyLimit < 0 {
+		v.add("otp.quota.ip_hourly_limit", "tidak boleh negatif")
+	}
+	if o.Quota.IPDailyLimit < 0 {
+		v.add("otp.quota.ip_daily_limit", "tidak boleh negatif")
+	}
+}
+
+// validate memeriksa konfigurasi lupa password
+func (p PasswordReset) validate(v *validator) {
+	switch p.Method {
+	case "", "link", "otp":
+	default:
+		v.add("password_reset.method", "harus salah satu dari link atau otp, bukan %q", p.Method)
+	}
+
+	if p.ExpiresIn < 0 {
+		v.add("password_reset.expires_in", "tidak boleh negatif")
+	}
+
+	if p.URL != "" {
+		if u, err := url.Parse(p.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("password_reset.url", "harus berupa URL absolut")
+		}
+	}
+}
+
+// validate memeriksa konfigurasi verifikasi email
+func (e EmailVerification) validate(v *validator) {
+	if e.ExpiresIn < 0 {
+		v.add("email_verification.expires_in", "tidak boleh negatif")
+	}
+
+	if e.ResendCooldown < 0 {
+		v.add("email_verification.resend_cooldown", "tidak boleh negatif")
+	}
+
+	if e.URL != "" {
+		if u, err := url.Parse(e.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("email_verification.url", "harus berupa URL absolut")
+		}
+	}
+}
+
+// validate memeriksa konfigurasi autentikasi dua langkah
+func (m MFA) validate(v *validator) {
+	if m.EncryptionKey != "" && len(m.EncryptionKey) < MinJWTSecretLength {
+		v.add("mfa.encryption_key", "minimal %d byte, saat ini %d byte", MinJWTSecretLength, len(m.EncryptionKey))
+	}
+
+	if m.ChallengeExpiresIn < 0 {
+		v.add("mfa.challenge_expires_in", "tidak boleh negatif")
+	}
+}
+
+// validate memeriksa konfigurasi WebAuthn yang diaktifkan
+func (w WebAuthn) validate(v *validator) {
+	if w.Timeout < 0 {
+		v.add("webauthn.timeout", "tidak boleh negatif")
+	}
+
+	if !w.Enabled {
+		return
+	}
+
+	if w.RPID == "" {
+		v.add("webauthn.rp_id", "wajib diisi jika webauthn diaktifkan")
+	} else if strings.ContainsAny(w.RPID, ":/") {
+		v.add("webauthn.rp_id", "harus berupa domain tanpa skema, port atau path")
+	}
+
+	if len(w.RPOrigins) == 0 {
+		v.add("webauthn.rp_origins", "minimal satu origin jika webauthn diaktifkan")
+	}
+	for _, origin := range w.RPOrigins {
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			v.add("webauthn.rp_origins", "origin %q harus berupa skema dan host, misalnya https://example.com", origin)
+		}
+	}
+}
+
+// validate memeriksa konfigurasi provider OAuth
+func (p Providers) validate(v *validator) {
+	p.Google.validate(v, "providers.google")
+	p.Twitter.validate(v, "providers.twitter")
+	p.GitHub.validate(v, "providers.github")
+	p.Facebook.validate(v, "providers.facebook")
+
+	// Nama provider dipakai pada rute /auth/{name} dan nama cookie sehingga
+	// harus unik di semua bentuk konfigurasi
+	used := map[string]bool{
+		ProviderGoogle:   p.Google.Enabled,
+		ProviderTwitter:  p.Twitter.Enabled,
+		ProviderGitHub:   p.GitHub.Enabled,
+		ProviderFacebook: p.Facebook.Enabled,
+	}
+	validateNamedProviders(v, "providers.oidc", p.OIDC, used, true)
+	validateNamedProviders(v, "providers.oauth", p.OAuth, used, false)
+}
+
+// validateNamedProviders memeriksa provider di map OIDC atau OAuth. Nama
+// diurutkan agar urutan error tetap sama.
+func validateNamedProviders(v *validator, prefix string, entries map[string]OAuth, used map[string]bool, oidc bool) {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		o := entries[name]
+		path := prefix + "." + name
+		typ := ProviderOIDC
+		if !oidc {
+			typ = o.ProviderType(name)
+		}
+
+		switch {
+		case !providerNamePattern.MatchString(name):
+			v.add(path, "nama provider hanya boleh berisi huruf kecil, angka dan -")
+		case used[name]:
+			v.add(path, "nama provider %q sudah dipakai", name)
+		case !providerTypes[typ]:
+			v.add(path+".type", "harus salah satu dari google, twitter, github, facebook, oidc atau custom")
+		}
+		used[name] = true
+
+		o.validate(v, path)
+		// Entri yang diisi kredensial tetapi lupa diaktifkan akan diabaikan
+		// tanpa pesan, sehingga lebih baik ditolak sejak awal
+		if !o.Enabled && (o.ClientID != "" || o.ClientSecret != "" || o.Issuer != "") {
+			v.add(path+".enabled", "harus true jika client_id, client_secret atau issuer diisi")
+		}
+		if !o.Enabled || typ != ProviderOIDC {
+			continue
+		}
+		if o.Issuer == "" {
+			v.add(path+".issuer", "wajib diisi untuk provider OIDC")
+		} else if u, err := url.Parse(o.Issuer); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add(path+".issuer", "harus berupa URL absolut")
+		}
+	}
+}
+
+// providerNamePattern adalah format nama provider yang diperbolehkan. Garis
+// bawah tidak diizinkan karena dipakai sebagai pemisah nama cookie OAuth,
+// sehingga provider "x_nonce" tidak dapat bertabrakan dengan cookie nonce "x".
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidProviderName melaporkan apakah name boleh dipakai sebagai nama provider
+func ValidProviderName(name string) bool {
+	return providerNamePattern.MatchString(name)
+}
+
+// providerTypes adalah nilai OAuth.Type yang dikenal
+var providerTypes = map[string]bool{
+	ProviderGoogle:   true,
+	ProviderTwitter:  true,
+	ProviderGitHub:   true,
+	ProviderFacebook: true,
+	ProviderOIDC:     true,
+	ProviderCustom:   true,
+}
+
+// validate memeriksa konfigurasi satu provider OAuth yang diaktifkan
+func (o OAuth) validate(v *validator, path string) {
+	if !o.Enabled {
+		return
+	}
+
+	if o.ClientID == "" {
+		v.add(path+".client_id", "wajib diisi jika provider diaktifkan")
+	}
+	if o.ClientSecret == "" {
+		v.add(path+".client_secret", "wajib diisi jika provider diaktifkan")
+	}
+	urls := []struct{ field, value string }{
+		{"callback_url", o.CallbackURL},
+		{"auth_url", o.AuthURL},
+		{"token_url", o.TokenURL},
+		{"api_url", o.APIURL},
+	}
+	for _, u := range urls {
+		if u.value == "" {
+			continue
+		}
+		if parsed, err := url.Parse(u.value); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			v.add(path+"."+u.field, "harus berupa URL absolut")
+		}
+	}
+}
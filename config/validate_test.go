@@ -0,0 +1,260 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// validConfig mengembalikan konfigurasi minimal yang lolos validasi
+func validConfig() Config {
+	return Config{
+		Database: Database{Type: "sqlite", Database: "auth.db"},
+		JWT:      JWT{Secret: "0123456789abcdef0123456789abcdef", ExpiresIn: 3600},
+	}
+}
+
+// TestValidate menguji validasi konfigurasi
+func TestValidate(t *testing.T) {
+	t.Run("Valid Config", func(t *testing.T) {
+		assert.NoError(t, validConfig().Validate())
+	})
+
+	t.Run("Aggregates All Problems", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.JWT = JWT{Secret: "short"}
+		cfg.OTP.DefaultType = "pigeon"
+		cfg.Providers.Google = OAuth{Enabled: true, ClientSecret: "secret"}
+
+		err := cfg.Validate()
+		var verr *ValidationError
+		assert.True(t, errors.As(err, &verr))
+
+		fields := map[string]bool{}
+		for _, fe := range verr.Errors {
+			fields[fe.Field] = true
+		}
+		assert.Equal(t, map[string]bool{
+			"jwt.secret":                 true,
+			"jwt.expires_in":             true,
+			"otp.default_type":           true,
+			"providers.google.client_id": true,
+		}, fields)
+		assert.Contains(t, err.Error(), "providers.google.client_id")
+	})
+
+	t.Run("Disabled Provider Is Ignored", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Providers.GitHub = OAuth{Enabled: false}
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("Invalid Callback URL", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Providers.Facebook = OAuth{Enabled: true, ClientID: "id", ClientSecret: "secret", CallbackURL: "/callback"}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "providers.facebook.callback_url")
+	})
+
+	t.Run("OIDC Providers", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Providers.Google = OAuth{Enabled: true, ClientID: "id", ClientSecret: "secret"}
+		cfg.Providers.OIDC = map[string]OAuth{
+			"keycloak":   {Enabled: true, ClientID: "id", ClientSecret: "secret", Issuer: "https://sso.example.com/realms/main"},
+			"okta":       {Enabled: true, ClientID: "id", ClientSecret: "secret"},
+			"Azure AD":   {Enabled: true, ClientID: "id", ClientSecret: "secret", Issuer: "https://login.microsoftonline.com/tenant/v2.0"},
+			"okta_nonce": {Enabled: true, ClientID: "id", ClientSecret: "secret", Issuer: "https://okta.example.com"},
+			"google":     {Enabled: false},
+		}
+
+		err := cfg.Validate()
+		var verr *ValidationError
+		assert.True(t, errors.As(err, &verr))
+
+		var fields []string
+		for _, fe := range verr.Errors {
+			fields = append(fields, fe.Field)
+		}
+		assert.Equal(t, []string{
+			"providers.oidc.Azure AD",
+			"providers.oidc.google",
+			"providers.oidc.okta.issuer",
+			"providers.oidc.okta_nonce",
+		}, fields)
+	})
+
+	t.Run("Named OAuth Providers", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Providers.OIDC = map[string]OAuth{
+			"keycloak": {Enabled: true, ClientID: "id", ClientSecret: "secret", Issuer: "https://sso.example.com/realms/main"},
+		}
+		cfg.Providers.OAuth = map[string]OAuth{
+			"github":            {Enabled: true, ClientID: "id", ClientSecret: "secret"},
+			"github-enterprise": {Enabled: true, Type: "github", ClientID: "id", ClientSecret: "secret", APIURL: "https://ghe.example.com/api/v3"},
+			"gitlab":            {Enabled: true, Type: "custom", ClientID: "id", ClientSecret: "secret"},
+			"auth0":             {Enabled: true, ClientID: "id", ClientSecret: "secret", Issuer: "https://tenant.auth0.com/"},
+		}
+		assert.NoError(t, cfg.Validate())
+
+		all := cfg.Providers.OAuthProviders()
+		assert.Len(t, all, 5)
+		assert.Equal(t, "oidc", all["keycloak"].Type)
+		assert.Equal(t, "oidc", all["auth0"].Type)
+		assert.Equal(t, "github", all["github"].Type)
+		assert.Equal(t, "github", all["github-enterprise"].Type)
+
+		cfg.Providers.OAuth["bitbucket"] = OAuth{Enabled: true, ClientID: "id", ClientSecret: "secret"}
+		cfg.Providers.OAuth["keycloak"] = OAuth{Enabled: true, Type: "oidc", ClientID: "id", ClientSecret: "secret", Issuer: "https://sso.example.com"}
+
+		err := cfg.Validate()
+		var verr *ValidationError
+		assert.True(t, errors.As(err, &verr))
+
+		var fields []string
+		for _, fe := range verr.Errors {
+			fields = append(fields, fe.Field)
+		}
+		assert.Equal(t, []string{"providers.oauth.bitbucket.type", "providers.oauth.keycloak"}, fields)
+	})
+
+	t.Run("Disabled Named Providers", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Providers.OIDC = map[string]OAuth{
+			"keycloak": {Enabled: true, ClientID: "id", ClientSecret: "secret", Issuer: "https://sso.example.com/realms/main"},
+			"okta":     {},
+		}
+		cfg.Providers.OAuth = map[string]OAuth{
+			"gitlab": {Type: "custom"},
+		}
+		assert.NoError(t, cfg.Validate())
+
+		// Entri yang tidak diaktifkan tidak ikut didaftarkan
+		all := cfg.Providers.OAuthProviders()
+		assert.Len(t, all, 1)
+		assert.Contains(t, all, "keycloak")
+
+		// Kredensial pada entri yang tidak diaktifkan ditolak
+		cfg.Providers.OIDC["okta"] = OAuth{ClientID: "id", ClientSecret: "secret", Issuer: "https://okta.example.com"}
+		cfg.Providers.OAuth["gitlab"] = OAuth{Type: "custom", ClientID: "id"}
+
+		err := cfg.Validate()
+		var verr *ValidationError
+		assert.True(t, errors.As(err, &verr))
+
+		var fields []string
+		for _, fe := range verr.Errors {
+			fields = append(fields, fe.Field)
+		}
+		assert.Equal(t, []string{"providers.oidc.okta.enabled", "providers.oauth.gitlab.enabled"}, fields)
+	})
+
+	t.Run("Asymmetric Algorithm Without Secret", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.JWT = JWT{Algorithm: "ES256", ExpiresIn: 3600}
+		cfg.OTP.Secret = "0123456789abcdef0123456789abcdef"
+		cfg.MFA.EncryptionKey = "fedcba9876543210fedcba9876543210"
+		assert.NoError(t, cfg.Validate())
+
+		cfg.JWT.Algorithm = "none"
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "jwt.algorithm")
+	})
+
+	t.Run("Secrets Required Without JWT Secret", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.JWT = JWT{Algorithm: "RS256", ExpiresIn: 3600}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "otp.secret")
+		assert.Contains(t, err.Error(), "mfa.encryption_key")
+	})
+
+	t.Run("JWT Key Dir And Rotation", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.JWT = JWT{Algorithm: "ES256", ExpiresIn: 3600, KeyDir: "/var/lib/auth/keys", RotationInterval: 86400}
+		cfg.OTP.Secret = "0123456789abcdef0123456789abcdef"
+		cfg.MFA.EncryptionKey = "fedcba9876543210fedcba9876543210"
+		assert.NoError(t, cfg.Validate())
+
+		cfg.JWT.PrivateKeyFile = "/run/secrets/jwt.pem"
+		err := cfg.Validate()
+		var verr *ValidationError
+		assert.True(t, errors.As(err, &verr))
+
+		var fields []string
+		for _, fe := range verr.Errors {
+			fields = append(fields, fe.Field)
+		}
+		assert.Equal(t, []string{"jwt.key_dir", "jwt.rotation_interval"}, fields)
+	})
+
+	t.Run("Session Cookie SameSite", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Session = Session{Enabled: true, CookieSameSite: "Strict"}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Session = Session{CookieSameSite: "none", TrustedOrigins: []string{"https://app.example.com"}}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Session = Session{CookieSameSite: "none", CookieInsecure: true}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "session.cookie_same_site")
+		assert.Contains(t, err.Error(), "session.trusted_origins")
+	})
+
+	t.Run("Password Reset", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.PasswordReset = PasswordReset{Method: "otp", URL: "https://app.example.com/reset"}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.PasswordReset = PasswordReset{Method: "magic", URL: "/reset"}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "password_reset.method")
+		assert.Contains(t, err.Error(), "password_reset.url")
+	})
+
+	t.Run("OTP Quota", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.OTP.Quota = OTPQuota{ResendCooldown: -1, IPDailyLimit: -5}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "otp.quota.resend_cooldown")
+		assert.Contains(t, err.Error(), "otp.quota.ip_daily_limit")
+	})
+
+	t.Run("MFA Encryption Key", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.MFA = MFA{EncryptionKey: "short"}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "mfa.encryption_key")
+	})
+
+	t.Run("WebAuthn Relying Party", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.WebAuthn = WebAuthn{Enabled: true, RPID: "https://example.com", RPOrigins: []string{"example.com"}}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "webauthn.rp_id")
+		assert.Contains(t, err.Error(), "webauthn.rp_origins")
+	})
+
+	t.Run("Unknown Database Type", func(t *testing.T) {
+		cfg := validConfig()
+		cfg.Database.Type = "oracle"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "database.type")
+	})
+}
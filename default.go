@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/middleware"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/providers"
+	"github.com/kreasimaju/auth/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// Fungsi-fungsi tingkat package di file ini hanyalah pembungkus tipis untuk
+// instance Auth default. Aplikasi yang membutuhkan lebih dari satu
+// konfigurasi sebaiknya memakai New dan method pada *Auth secara langsung.
+
+var (
+	stdMu sync.RWMutex
+	std   = &Auth{}
+)
+
+// Default mengembalikan instance Auth default yang dipakai oleh fungsi
+// tingkat package. Sebelum Init dipanggil, instance ini memakai utils.DB.
+func Default() *Auth {
+	stdMu.RLock()
+	defer stdMu.RUnlock()
+	return std
+}
+
+// SetDefault mengganti instance Auth default
+func SetDefault(a *Auth) {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std = a
+}
+
+// Init menginisialisasi package auth dengan konfigurasi yang diberikan
+func Init(cfg config.Config) error {
+	a, err := New(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Pertahankan global lama agar kode yang masih memakainya tetap berfungsi
+	utils.DB = a.db
+	utils.SetJWTSecret(cfg.JWT.Secret)
+	utils.SetKeyRing(a.keys)
+	utils.SetValidationRules(utils.ValidationRulesFromConfig(cfg.JWT))
+
+	SetDefault(a)
+
+	return nil
+}
+
+// GetConfig mengembalikan konfigurasi saat ini
+func GetConfig() config.Config {
+	return Default().Config()
+}
+
+// GoogleLoginURL mengembalikan URL untuk login Google
+func GoogleLoginURL(state string) string {
+	return Default().GoogleLoginURL(state)
+}
+
+// HandleGoogleCallback menangani callback dari Google OAuth
+func HandleGoogleCallback(code string) (*models.User, error) {
+	return Default().HandleGoogleCallback(code)
+}
+
+// TwitterLoginURL mengembalikan URL untuk login Twitter dengan code verifier PKCE
+func TwitterLoginURL(state, verifier string) string {
+	return Default().TwitterLoginURL(state, verifier)
+}
+
+// HandleTwitterCallback menangani callback dari Twitter OAuth 2.0
+func HandleTwitterCallback(code, verifier string) (*models.User, error) {
+	return Default().HandleTwitterCallback(code, verifier)
+}
+
+// GitHubLoginURL mengembalikan URL untuk login GitHub
+func GitHubLoginURL(state string) string {
+	return Default().GitHubLoginURL(state)
+}
+
+// HandleGitHubCallback menangani callback dari GitHub OAuth
+func HandleGitHubCallback(code string) (*models.User, error) {
+	return Default().HandleGitHubCallback(code)
+}
+
+// FacebookLoginURL mengembalikan URL untuk login Facebook
+func FacebookLoginURL(state string) string {
+	return Default().FacebookLoginURL(state)
+}
+
+// HandleFacebookCallback menangani callback dari Facebook Login
+func HandleFacebookCallback(code string) (*models.User, error) {
+	return Default().HandleFacebookCallback(code)
+}
+
+// RegisterProvider mendaftarkan provider OAuth milik aplikasi
+func RegisterProvider(p providers.Provider) error {
+	return Default().RegisterProvider(p)
+}
+
+// OAuthLoginURL mengembalikan URL login untuk provider bernama name
+func OAuthLoginURL(name, state, nonce, verifier string) (string, error) {
+	return Default().OAuthLoginURL(name, state, nonce, verifier)
+}
+
+// HandleOAuthCallback menangani callback dari provider bernama name
+func HandleOAuthCallback(name, code, nonce, verifier string) (*models.User, error) {
+	return Default().HandleOAuthCallback(name, code, nonce, verifier)
+}
+
+// Middleware mengembalikan handler middleware otentikasi untuk Echo
+func Middleware(opts ...middleware.Option) echo.MiddlewareFunc {
+	return Default().Middleware(opts...)
+}
+
+// RoleMiddleware mengembalikan handler middleware peran untuk Echo
+func RoleMiddleware(roles ...string) echo.MiddlewareFunc {
+	return Default().RoleMiddleware(roles...)
+}
+
+// GinMiddleware mengembalikan handler middleware otentikasi untuk Gin
+func GinMiddleware(opts ...middleware.Option) gin.HandlerFunc {
+	return Default().GinMiddleware(opts...)
+}
+
+// GinRoleMiddleware mengembalikan handler middleware peran untuk Gin
+func GinRoleMiddleware(roles ...string) gin.HandlerFunc {
+	return Default().GinRoleMiddleware(roles...)
+}
+
+// FiberMiddleware mengembalikan handler middleware otentikasi untuk Fiber
+func FiberMiddleware(opts ...middleware.Option) fiber.Handler {
+	return Default().FiberMiddleware(opts...)
+}
+
+// FiberRoleMiddleware mengembalikan handler middleware peran untuk Fiber
+func FiberRoleMiddleware(roles ...string) fiber.Handler {
+	return Default().FiberRoleMiddleware(roles...)
+}
+
+// SessionMiddleware mengembalikan middleware Echo yang menerima sesi cookie maupun bearer token
+func SessionMiddleware(opts ...middleware.Option) echo.MiddlewareFunc {
+	return Default().SessionMiddleware(opts...)
+}
+
+// GinSessionMiddleware mengembalikan middleware Gin yang menerima sesi cookie maupun bearer token
+func GinSessionMiddleware(opts ...middleware.Option) gin.HandlerFunc {
+	return Default().GinSessionMiddleware(opts...)
+}
+
+// FiberSessionMiddleware mengembalikan middleware Fiber yang menerima sesi cookie maupun bearer token
+func FiberSessionMiddleware(opts ...middleware.Option) fiber.Handler {
+	return Default().FiberSessionMiddleware(opts...)
+}
+
+// RegisterRoutes mendaftarkan rute otentikasi untuk Echo
+func RegisterRoutes(e *echo.Echo) {
+	Default().RegisterRoutes(e)
+}
+
+// RegisterLocal mendaftarkan pengguna dengan email dan password
+func RegisterLocal(email, password, firstName, lastName, phone, defaultRegion string) (*models.User, error) {
+	return Default().RegisterLocal(email, password, firstName, lastName, phone, defaultRegion)
+}
+
+// LoginLocal melakukan autentikasi pengguna dengan email/phone dan password
+func LoginLocal(identifier, password string) (*models.User, error) {
+	return Default().LoginLocal(identifier, password)
+}
+
+// Login melakukan autentikasi pengguna dengan email dan password
+func Login(email, password string) (*models.User, error) {
+	return Default().Login(email, password)
+}
+
+// GenerateOTP membuat kode OTP baru
+func GenerateOTP(userID uint, otpType, target, purpose string) (*models.OTPCode, error) {
+	return Default().GenerateOTP(userID, otpType, target, purpose)
+}
+
+// VerifyOTP memverifikasi kode OTP
+func VerifyOTP(userID uint, code, otpType, purpose string) (bool, error) {
+	return Default().VerifyOTP(userID, code, otpType, purpose)
+}
+
+// SendOTP mengirim kode OTP melalui channel yang dipilih
+func SendOTP(otpCode *models.OTPCode) error {
+	return Default().SendOTP(otpCode)
+}
+
+// FindUserByEmail mencari pengguna berdasarkan email
+func FindUserByEmail(email string) (*models.User, error) {
+	return Default().FindUserByEmail(email)
+}
+
+// FindUserByPhone mencari pengguna berdasarkan nomor telepon
+func FindUserByPhone(phone string) (*models.User, error) {
+	return Default().FindUserByPhone(phone)
+}
+
+// RequestOTPLogin meminta OTP untuk login
+func RequestOTPLogin(contact, otpType, defaultRegion string) (*models.OTPCode, error) {
+	return Default().RequestOTPLogin(contact, otpType, defaultRegion)
+}
+
+// VerifyOTPLogin memverifikasi OTP untuk login
+func VerifyOTPLogin(contact, otpType, code, defaultRegion string) (*models.User, error) {
+	return Default().VerifyOTPLogin(contact, otpType, code, defaultRegion)
+}
+
+// RequestOTPRegistration menyimpan registrasi tertunda dan mengirim OTP ke kontak
+func RequestOTPRegistration(contact, otpType, defaultRegion, password, firstName, lastName string) (*models.OTPCode, error) {
+	return Default().RequestOTPRegistration(contact, otpType, defaultRegion, password, firstName, lastName)
+}
+
+// VerifyOTPRegistration memverifikasi OTP registrasi dan membuat pengguna
+func VerifyOTPRegistration(contact, otpType, code, defaultRegion string) (*models.User, error) {
+	return Default().VerifyOTPRegistration(contact, otpType, code, defaultRegion)
+}
+
+// RequestPasswordReset mengirim instruksi reset password ke pengguna
+func RequestPasswordReset(identifier, defaultRegion string) error {
+	return Default().RequestPasswordReset(identifier, defaultRegion)
+}
+
+// ResetPassword mengganti password dengan token reset
+func ResetPassword(token, newPassword string) error {
+	return Default().ResetPassword(token, newPassword)
+}
+
+// ResetPasswordWithOTP mengganti password dengan kode OTP reset
+func ResetPasswordWithOTP(identifier, code, newPassword, defaultRegion string) error {
+	return Default().ResetPasswordWithOTP(identifier, code, newPassword, defaultRegion)
+}
+
+// SendEmailVerification mengirim token verifikasi ke email pengguna
+func SendEmailVerification(userID uint) error {
+	return Default().SendEmailVerification(userID)
+}
+
+// SetEmail melengkapi email pengguna yang belum memiliki email
+func SetEmail(userID uint, email string) error {
+	return Default().SetEmail(userID, email)
+}
+
+// VerifyEmail memakai token verifikasi dan menandai email sebagai terverifikasi
+func VerifyEmail(token string) (*models.User, error) {
+	return Default().VerifyEmail(token)
+}
+
+// EnrollTOTP membuat secret TOTP baru untuk pengguna
+func EnrollTOTP(userID uint) (*TOTPEnrollment, error) {
+	return Default().EnrollTOTP(userID)
+}
+
+// ConfirmTOTP mengaktifkan TOTP dengan kode pertama dari authenticator
+func ConfirmTOTP(userID uint, code string) error {
+	return Default().ConfirmTOTP(userID, code)
+}
+
+// DisableTOTP menonaktifkan TOTP pengguna
+func DisableTOTP(userID uint, code string) error {
+	return Default().DisableTOTP(userID, code)
+}
+
+// VerifyMFA menyelesaikan login dua langkah
+func VerifyMFA(challengeToken, code string) (*models.User, error) {
+	return Default().VerifyMFA(challengeToken, code)
+}
+
+// GenerateBackupCodes membuat set kode cadangan baru untuk pengguna
+func GenerateBackupCodes(userID uint) ([]string, error) {
+	return Default().GenerateBackupCodes(userID)
+}
+
+// RegenerateBackupCodes membuat set kode cadangan baru setelah pengguna
+// memasukkan kode OTP, kode TOTP, kode cadangan atau password
+func RegenerateBackupCodes(userID uint, code, otpType, password string) ([]string, error) {
+	return Default().RegenerateBackupCodes(userID, code, otpType, password)
+}
+
+// UseBackupCode memakai satu kode cadangan milik pengguna
+func UseBackupCode(userID uint, code string) error {
+	return Default().UseBackupCode(userID, code)
+}
+
+// GetSecurityOverview mengembalikan ringkasan keamanan akun pengguna
+func GetSecurityOverview(userID uint) (*SecurityOverview, error) {
+	return Default().GetSecurityOverview(userID)
+}
+
+// BeginPasskeyRegistration memulai pendaftaran passkey untuk pengguna
+func BeginPasskeyRegistration(userID uint) (*WebAuthnCeremony, error) {
+	return Default().BeginPasskeyRegistration(userID)
+}
+
+// FinishPasskeyRegistration memverifikasi dan menyimpan passkey baru
+func FinishPasskeyRegistration(userID uint, sessionToken, name string, response []byte) (*models.WebAuthnCredential, error) {
+	return Default().FinishPasskeyRegistration(userID, sessionToken, name, response)
+}
+
+// BeginPasskeyLogin memulai login tanpa password dengan passkey
+func BeginPasskeyLogin() (*WebAuthnCeremony, error) {
+	return Default().BeginPasskeyLogin()
+}
+
+// FinishPasskeyLogin menyelesaikan login tanpa password dengan passkey
+func FinishPasskeyLogin(sessionToken string, response []byte) (*models.User, error) {
+	return Default().FinishPasskeyLogin(sessionToken, response)
+}
+
+// BeginWebAuthnMFA memulai verifikasi passkey sebagai langkah kedua login
+func BeginWebAuthnMFA(challengeToken string) (*WebAuthnCeremony, error) {
+	return Default().BeginWebAuthnMFA(challengeToken)
+}
+
+// VerifyWebAuthnMFA menyelesaikan login dua langkah dengan passkey
+func VerifyWebAuthnMFA(challengeToken, sessionToken string, response []byte) (*models.User, error) {
+	return Default().VerifyWebAuthnMFA(challengeToken, sessionToken, response)
+}
+
+// ListPasskeys mengembalikan passkey milik pengguna
+func ListPasskeys(userID uint) ([]models.WebAuthnCredential, error) {
+	return Default().ListPasskeys(userID)
+}
+
+// DeletePasskey menghapus passkey milik pengguna
+func DeletePasskey(userID, credentialID uint) error {
+	return Default().DeletePasskey(userID, credentialID)
+}
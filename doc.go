@@ -27,6 +27,18 @@ Inisialisasi:
 		panic(err)
 	}
 
+Beberapa Instance:
+
+Fungsi tingkat package memakai instance default yang diatur oleh Init. Untuk
+menjalankan beberapa konfigurasi sekaligus (misalnya realm pelanggan dan staf),
+buat instance sendiri dengan New:
+
+	customers, err := auth.New(customerConfig)
+	staff, err := auth.New(staffConfig)
+
+	user, err := customers.RegisterLocal("user@example.com", "password123", "John", "Doe", "", "ID")
+	staff.RegisterRoutes(e)
+
 Autentikasi Lokal:
 
 	// Registrasi pengguna baru
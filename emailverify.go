@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"gorm.io/gorm"
+)
+
+// Error yang dikembalikan oleh fungsi verifikasi email
+var (
+	ErrInvalidVerificationToken = errors.New("token verifikasi tidak valid atau sudah kedaluwarsa")
+	ErrAlreadyVerified          = errors.New("email sudah diverifikasi")
+	ErrEmailAlreadySet          = errors.New("pengguna sudah memiliki email")
+	ErrEmailTaken               = errors.New("email sudah terdaftar")
+	ErrInvalidEmail             = errors.New("format email tidak valid")
+)
+
+// emailVerifyType adalah nilai Type dan OwnerType models.Token untuk
+// verifikasi email, sama dengan polymorphicValue relasi User.EmailVerify
+const emailVerifyType = "email_verify"
+
+// SendEmailVerification mengirim token verifikasi ke email pengguna.
+// *RetryAfterError dikembalikan jika token sebelumnya dikirim kurang dari
+// email_verification.resend_cooldown detik yang lalu.
+func (a *Auth) SendEmailVerification(userID uint) error {
+	var user models.User
+	if err := a.DB().First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+	if user.Email == "" {
+		return fmt.Errorf("pengguna tidak memiliki email")
+	}
+
+	var last models.Token
+	err := a.DB().Where("owner_id = ? AND type = ?", userID, emailVerifyType).
+		Order("created_at DESC").First(&last).Error
+	if err == nil {
+		if wait := a.emailVerificationCooldown() - time.Since(last.CreatedAt); wait > 0 {
+			return &RetryAfterError{RetryAfter: wait}
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	expiresIn := a.config.EmailVerification.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = config.DefaultEmailVerificationExpiresIn
+	}
+	token, expiresAt, err := a.issueUserToken(user.ID, emailVerifyType, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Gunakan tautan berikut untuk memverifikasi email Anda: %s. Tautan berlaku hingga %s.",
+		tokenLink(a.config.EmailVerification.URL, token), expiresAt.Format(time.RFC1123))
+
+	return a.deliver("email", user.Email, message)
+}
+
+// SetEmail melengkapi email pengguna yang belum memiliki email, misalnya
+// pengguna dari Twitter atau akun Facebook tanpa email, lalu mengirim token
+// verifikasi ke alamat tersebut. Email baru dianggap belum terverifikasi
+// sampai VerifyEmail dipanggil; status IsVerified akun tidak berubah karena
+// bisa berasal dari nomor telepon yang sudah diverifikasi lewat OTP.
+func (a *Auth) SetEmail(userID uint, email string) error {
+	email = strings.TrimSpace(email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+
+	err := a.DB().Transaction(func(tx *gorm.DB) error {
+		var existing models.User
+		err := tx.Where("email = ?", email).First(&existing).Error
+		if err == nil {
+			return ErrEmailTaken
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Hanya pengguna tanpa email yang boleh diubah; mengganti email yang
+		// sudah ada memerlukan alur tersendiri
+		result := tx.Model(&models.User{}).Where("id = ? AND (email IS NULL OR email = '')", userID).
+			Updates(map[string]interface{}{"email": email, "email_verified": false})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := tx.First(&existing, userID).Error; err != nil {
+				return err
+			}
+			return ErrEmailAlreadySet
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.SendEmailVerification(userID)
+}
+
+// VerifyEmail memakai token verifikasi dan menandai email pengguna sebagai
+// terverifikasi. Token hanya dapat dipakai sekali.
+func (a *Auth) VerifyEmail(token string) (*models.User, error) {
+	var user models.User
+	err := a.DB().Transaction(func(tx *gorm.DB) error {
+		stored, err := consumeUserToken(tx, token, emailVerifyType)
+		if errors.Is(err, errUserTokenInvalid) {
+			return ErrInvalidVerificationToken
+		} else if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", stored.OwnerID).
+			Updates(map[string]interface{}{"is_verified": true, "email_verified": true}).Error; err != nil {
+			return err
+		}
+
+		return tx.First(&user, stored.OwnerID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// emailVerificationCooldown mengembalikan jarak minimum antar pengiriman token verifikasi
+func (a *Auth) emailVerificationCooldown() time.Duration {
+	cooldown := a.config.EmailVerification.ResendCooldown
+	if cooldown <= 0 {
+		cooldown = config.DefaultEmailVerificationCooldown
+	}
+	return time.Duration(cooldown) * time.Second
+}
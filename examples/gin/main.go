@@ -26,8 +26,8 @@ func main() {
 			Local: true, // Aktifkan auth lokal
 		},
 		JWT: config.JWT{
-			Secret:    "your-jwt-secret-key",
-			ExpiresIn: 86400, // 24 jam
+			Secret:    "your-jwt-secret-key-at-least-32-bytes", // minimal 32 byte
+			ExpiresIn: 86400,                                   // 24 jam
 		},
 	})
 
@@ -0,0 +1,1398 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/kreasimaju/auth/middleware"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/providers"
+	"github.com/kreasimaju/auth/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// ===== Implementasi Handler =====
+
+// oauthAuthHandler mengarahkan pengguna ke halaman otorisasi provider. State,
+// nonce dan code verifier PKCE disimpan di cookie sampai callback.
+func (a *Auth) oauthAuthHandler(name string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state, err := a.newOAuthState(c, name)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate state: " + err.Error(),
+			})
+		}
+		nonce, err := a.newOAuthNonce(c, name)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate nonce: " + err.Error(),
+			})
+		}
+		verifier := a.newOAuthVerifier(c, name)
+
+		loginURL, err := a.OAuthLoginURL(name, state, nonce, verifier)
+		if err != nil {
+			return c.JSON(http.StatusBadGateway, map[string]string{
+				"error": "Failed to contact " + name + ": " + err.Error(),
+			})
+		}
+		return c.Redirect(http.StatusTemporaryRedirect, loginURL)
+	}
+}
+
+// oauthCallbackHandler menangani callback provider dan menerbitkan login
+func (a *Auth) oauthCallbackHandler(name string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		nonce := a.takeOAuthNonce(c, name)
+		verifier := a.takeOAuthVerifier(c, name)
+		if !a.checkOAuthState(c, name) || nonce == "" || verifier == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid OAuth state",
+			})
+		}
+
+		code := c.QueryParam("code")
+		if code == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Code parameter is required",
+			})
+		}
+
+		user, err := a.HandleOAuthCallback(name, code, nonce, verifier)
+		var mfa *MFARequiredError
+		if errors.As(err, &mfa) {
+			return mfaRequiredResponse(c, mfa)
+		}
+		if errors.Is(err, providers.ErrEmailConflict) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Email already registered, please log in and verify your email first",
+			})
+		}
+		if err != nil {
+			// Detail error provider hanya dicatat di log, tidak dikirim ke klien
+			log.Printf("OAuth callback for %s failed: %v", name, err)
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Failed to authenticate with " + name,
+			})
+		}
+
+		// Terbitkan sesi cookie atau access token dan refresh token
+		resp, err := a.issueLogin(c, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate token: " + err.Error(),
+			})
+		}
+
+		// Sebagian provider (Twitter, akun Facebook tanpa email) tidak
+		// memberikan email; klien dapat melengkapinya melalui POST /auth/email
+		resp["user"] = map[string]interface{}{
+			"id":            user.ID,
+			"email":         user.Email,
+			"first_name":    user.FirstName,
+			"last_name":     user.LastName,
+			"email_missing": user.Email == "",
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+// mfaRequiredResponse meminta klien menyelesaikan login melalui
+// /auth/mfa/verify atau /auth/mfa/webauthn/verify
+func mfaRequiredResponse(c echo.Context, mfa *MFARequiredError) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"mfa_required": true,
+		"mfa_token":    mfa.Token,
+		"methods":      mfa.Methods,
+		"expires_at":   mfa.ExpiresAt,
+	})
+}
+
+// issueLogin menerbitkan kredensial setelah login berhasil: sesi cookie jika
+// session.enabled aktif, atau access token dan refresh token
+func (a *Auth) issueLogin(c echo.Context, user *models.User) (map[string]interface{}, error) {
+	if a.config.Session.Enabled {
+		session, err := a.startSession(c, user)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"session_expires_at": session.ExpiresAt,
+		}, nil
+	}
+
+	tokens, err := a.IssueTokens(user)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	}, nil
+}
+
+// Handler untuk registrasi lokal
+func (a *Auth) registerHandler(c echo.Context) error {
+	// Parse request
+	var req struct {
+		Email         string `json:"email"`
+		Password      string `json:"password"`
+		FirstName     string `json:"first_name"`
+		LastName      string `json:"last_name"`
+		Phone         string `json:"phone"`
+		DefaultRegion string `json:"default_region"` // Kode negara 2 huruf, misal: "ID", "US", dll
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validasi input
+	if req.Email == "" || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Email and password are required",
+		})
+	}
+
+	// Cek apakah email sudah terdaftar
+	_, err := a.FindUserByEmail(req.Email)
+	if err == nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Email already registered",
+		})
+	}
+
+	// Jika nomor telepon disediakan, validasi formatnya
+	if req.Phone != "" {
+		// Validasi format telepon
+		if !utils.IsValidPhoneNumber(req.Phone, req.DefaultRegion) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid phone number format",
+			})
+		}
+
+		// Format telepon untuk penyimpanan
+		formattedPhone, err := utils.FormatPhoneNumber(req.Phone, req.DefaultRegion)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid phone number: " + err.Error(),
+			})
+		}
+
+		// Cek apakah nomor telepon sudah terdaftar
+		_, err = a.FindUserByPhone(formattedPhone)
+		if err == nil {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Phone number already registered",
+			})
+		}
+	}
+
+	// Registrasi pengguna
+	user, err := a.RegisterLocal(req.Email, req.Password, req.FirstName, req.LastName, req.Phone, req.DefaultRegion)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to register user: " + err.Error(),
+		})
+	}
+
+	// Terbitkan sesi cookie atau access token dan refresh token
+	resp, err := a.issueLogin(c, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate token: " + err.Error(),
+		})
+	}
+
+	resp["user"] = map[string]interface{}{
+		"id":         user.ID,
+		"email":      user.Email,
+		"phone":      user.Phone,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Handler untuk login lokal
+func (a *Auth) loginHandler(c echo.Context) error {
+	// Parse request
+	var req struct {
+		Identifier string `json:"identifier"` // Email atau nomor telepon
+		Password   string `json:"password"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validasi input
+	if req.Identifier == "" || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Identifier (email or phone) and password are required",
+		})
+	}
+
+	// Login pengguna
+	user, err := a.LoginLocal(req.Identifier, req.Password)
+	if err != nil {
+		// Password benar, tetapi login baru selesai setelah /auth/mfa/verify
+		var mfa *MFARequiredError
+		if errors.As(err, &mfa) {
+			return mfaRequiredResponse(c, mfa)
+		}
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid identifier or password",
+		})
+	}
+
+	// Terbitkan sesi cookie atau access token dan refresh token
+	resp, err := a.issueLogin(c, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate token: " + err.Error(),
+		})
+	}
+
+	resp["user"] = map[string]interface{}{
+		"id":         user.ID,
+		"email":      user.Email,
+		"phone":      user.Phone,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Handler untuk rotasi refresh token
+func (a *Auth) refreshHandler(c echo.Context) error {
+	// Parse request
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Refresh token is required",
+		})
+	}
+
+	// Rotasi refresh token
+	tokens, err := a.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid or expired refresh token",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to refresh token: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Handler untuk logout. Rute ini dilindungi middleware autentikasi.
+func (a *Auth) logoutHandler(c echo.Context) error {
+	// Parse request; refresh token bersifat opsional
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	// Pengguna yang login melalui sesi cookie cukup mengakhiri sesinya
+	if claims.SessionID != 0 {
+		if err := a.endSessionByID(claims.SessionID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to logout: " + err.Error(),
+			})
+		}
+		a.clearSessionCookie(c)
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "Logged out",
+		})
+	}
+
+	if err := a.logout(claims, req.RefreshToken); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to logout: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Logged out",
+	})
+}
+
+// Handler untuk logout dari semua perangkat. Rute ini dilindungi middleware autentikasi.
+func (a *Auth) logoutAllHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := a.LogoutEverywhere(claims.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to logout: " + err.Error(),
+		})
+	}
+	if claims.SessionID != 0 {
+		a.clearSessionCookie(c)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Logged out from all devices",
+	})
+}
+
+// Handler daftar sesi aktif milik pengguna yang sedang login
+func (a *Auth) listSessionsHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	sessions, err := a.ListSessions(claims.UserID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list sessions: " + err.Error(),
+		})
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// Handler untuk mencabut satu sesi milik pengguna
+func (a *Auth) revokeSessionHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid session ID",
+		})
+	}
+
+	if err := a.RevokeSession(claims.UserID, uint(sessionID)); err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Session not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to revoke session: " + err.Error(),
+		})
+	}
+
+	// Mencabut sesi sendiri sama dengan logout
+	if uint(sessionID) == claims.SessionID {
+		a.clearSessionCookie(c)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Session revoked",
+	})
+}
+
+// Handler untuk mencabut semua sesi lain selain sesi saat ini
+func (a *Auth) revokeOtherSessionsHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := a.RevokeOtherSessions(claims.UserID, claims.SessionID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to revoke sessions: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Other sessions revoked",
+	})
+}
+
+// Handler JWKS: mempublikasikan public key untuk verifikasi token
+func (a *Auth) jwksHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAge))
+	return c.JSON(http.StatusOK, a.JWKS())
+}
+
+// Handler verifikasi email dengan token dari email
+func (a *Auth) verifyEmailHandler(c echo.Context) error {
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Token == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Token is required",
+		})
+	}
+
+	user, err := a.VerifyEmail(req.Token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidVerificationToken) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid or expired token",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to verify email: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Email has been verified",
+		"user": map[string]interface{}{
+			"id":             user.ID,
+			"email":          user.Email,
+			"is_verified":    user.IsVerified,
+			"email_verified": user.EmailVerified,
+		},
+	})
+}
+
+// Handler untuk mengirim ulang email verifikasi ke pengguna yang sedang login
+func (a *Auth) resendVerificationHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := a.SendEmailVerification(claims.UserID); err != nil {
+		var retry *RetryAfterError
+		switch {
+		case errors.As(err, &retry):
+			return tooManyRequests(c, retry, "Please wait before requesting another verification email")
+		case errors.Is(err, ErrAlreadyVerified):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Email already verified",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to send verification email: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Verification email has been sent",
+	})
+}
+
+// Handler untuk melengkapi email pengguna yang belum memiliki email
+func (a *Auth) setEmailHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := a.SetEmail(claims.UserID, req.Email); err != nil {
+		var retry *RetryAfterError
+		switch {
+		case errors.Is(err, ErrInvalidEmail):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid email address",
+			})
+		case errors.Is(err, ErrEmailAlreadySet):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "User already has an email address",
+			})
+		case errors.Is(err, ErrEmailTaken):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Email already registered",
+			})
+		case errors.As(err, &retry):
+			return tooManyRequests(c, retry, "Please wait before requesting another verification email")
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to set email: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Email saved, verification email has been sent",
+	})
+}
+
+// Handler lupa password: responsnya selalu sama agar keberadaan akun tidak terungkap
+func (a *Auth) forgotPasswordHandler(c echo.Context) error {
+	var req struct {
+		Identifier    string `json:"identifier"` // email atau nomor telepon
+		Email         string `json:"email"`
+		DefaultRegion string `json:"default_region"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Identifier == "" {
+		req.Identifier = req.Email
+	}
+	if req.Identifier == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Email or phone is required",
+		})
+	}
+
+	// Kuota dihitung sebelum mencari akun agar keberadaan akun tidak terungkap
+	if err := a.ReserveOTPSend(otpQuotaTarget(req.Identifier, req.DefaultRegion), c.RealIP()); err != nil {
+		var retry *RetryAfterError
+		if errors.As(err, &retry) {
+			return tooManyRequests(c, retry, "Too many requests, please try again later")
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to request password reset: " + err.Error(),
+		})
+	}
+
+	if err := a.RequestPasswordReset(req.Identifier, req.DefaultRegion); err != nil {
+		// Kegagalan tidak diteruskan ke klien karena hanya terjadi untuk akun yang ada
+		log.Printf("Failed to send password reset instructions: %v", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "If the account exists, password reset instructions have been sent",
+	})
+}
+
+// Handler reset password dengan token dari email atau kode OTP
+func (a *Auth) resetPasswordHandler(c echo.Context) error {
+	var req struct {
+		Token         string `json:"token"`
+		Identifier    string `json:"identifier"` // wajib jika memakai kode OTP
+		Code          string `json:"code"`
+		Password      string `json:"password"`
+		DefaultRegion string `json:"default_region"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	var err error
+	switch {
+	case req.Token != "":
+		err = a.ResetPassword(req.Token, req.Password)
+	case req.Identifier != "" && req.Code != "":
+		err = a.ResetPasswordWithOTP(req.Identifier, req.Code, req.Password, req.DefaultRegion)
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Token or identifier and code are required",
+		})
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrWeakPassword):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("Password must be at least %d characters", MinPasswordLength),
+			})
+		case errors.Is(err, ErrInvalidResetToken):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid or expired token",
+			})
+		case errors.Is(err, ErrTooManyOTPAttempts):
+			return c.JSON(http.StatusTooManyRequests, map[string]string{
+				"error": "Too many attempts, please request a new code",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to reset password: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Password has been reset",
+	})
+}
+
+// verifyMFAHandler menyelesaikan login dua langkah
+func (a *Auth) verifyMFAHandler(c echo.Context) error {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.MFAToken == "" || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "MFA token and code are required",
+		})
+	}
+
+	user, err := a.VerifyMFA(req.MFAToken, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidMFAChallenge):
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid or expired MFA challenge",
+			})
+		case errors.Is(err, ErrInvalidTOTPCode), errors.Is(err, ErrInvalidBackupCode):
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid code",
+			})
+		case errors.Is(err, ErrTooManyOTPAttempts):
+			return c.JSON(http.StatusTooManyRequests, map[string]string{
+				"error": "Too many attempts, please log in again",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to verify MFA: " + err.Error(),
+		})
+	}
+
+	resp, err := a.issueLogin(c, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate token: " + err.Error(),
+		})
+	}
+
+	resp["user"] = map[string]interface{}{
+		"id":         user.ID,
+		"email":      user.Email,
+		"phone":      user.Phone,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// enrollTOTPHandler memulai pendaftaran aplikasi authenticator
+func (a *Auth) enrollTOTPHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	enrollment, err := a.EnrollTOTP(claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrTOTPAlreadyEnabled) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "TOTP already enabled",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to enroll TOTP: " + err.Error(),
+		})
+	}
+
+	// qr_code berisi PNG dalam base64
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+// confirmTOTPHandler mengaktifkan TOTP dengan kode pertama dari authenticator
+func (a *Auth) confirmTOTPHandler(c echo.Context) error {
+	return a.totpCodeHandler(c, a.ConfirmTOTP, "TOTP has been enabled")
+}
+
+// disableTOTPHandler menonaktifkan TOTP
+func (a *Auth) disableTOTPHandler(c echo.Context) error {
+	return a.totpCodeHandler(c, a.DisableTOTP, "TOTP has been disabled")
+}
+
+// totpCodeHandler menjalankan fn dengan kode TOTP dari body permintaan
+func (a *Auth) totpCodeHandler(c echo.Context, fn func(userID uint, code string) error, message string) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Code is required",
+		})
+	}
+
+	if err := fn(claims.UserID, req.Code); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTOTPCode):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid code",
+			})
+		case errors.Is(err, ErrTOTPNotEnrolled):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "TOTP enrollment has not been started",
+			})
+		case errors.Is(err, ErrTOTPNotEnabled):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "TOTP is not enabled",
+			})
+		case errors.Is(err, ErrTOTPAlreadyEnabled):
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "TOTP already enabled",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update TOTP: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": message,
+	})
+}
+
+// regenerateBackupCodesHandler membuat set kode cadangan baru dan
+// membatalkan set sebelumnya
+func (a *Auth) regenerateBackupCodesHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req struct {
+		Code     string `json:"code"`
+		Type     string `json:"type"` // "sms", "email" atau "whatsapp" jika code adalah OTP
+		Password string `json:"password"`
+	}
+	if err := c.Bind(&req); err != nil || (req.Code == "" && req.Password == "") {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Code or password is required",
+		})
+	}
+	if req.Type != "" && req.Type != "sms" && req.Type != "email" && req.Type != "whatsapp" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid OTP type",
+		})
+	}
+
+	codes, err := a.RegenerateBackupCodes(claims.UserID, req.Code, req.Type, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTOTPCode), errors.Is(err, ErrInvalidBackupCode), errors.Is(err, ErrInvalidOTPCode):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid code",
+			})
+		case errors.Is(err, ErrTooManyOTPAttempts):
+			return c.JSON(http.StatusTooManyRequests, map[string]string{
+				"error": "Too many attempts, please request a new code",
+			})
+		case errors.Is(err, ErrInvalidPassword):
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid password",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate backup codes: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"codes": codes,
+	})
+}
+
+// securityOverviewHandler mengembalikan ringkasan keamanan akun
+func (a *Auth) securityOverviewHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	overview, err := a.GetSecurityOverview(claims.UserID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to load security overview: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, overview)
+}
+
+// beginPasskeyRegistrationHandler memulai pendaftaran passkey
+func (a *Auth) beginPasskeyRegistrationHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	ceremony, err := a.BeginPasskeyRegistration(claims.UserID)
+	if err != nil {
+		return webAuthnError(c, err, "Failed to start passkey registration")
+	}
+
+	return c.JSON(http.StatusOK, ceremony)
+}
+
+// finishPasskeyRegistrationHandler menyimpan passkey dari respons browser
+func (a *Auth) finishPasskeyRegistrationHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	var req struct {
+		SessionToken string          `json:"session_token"`
+		Name         string          `json:"name"`
+		Credential   json.RawMessage `json:"credential"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	if req.SessionToken == "" || len(req.Credential) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Session token and credential are required",
+		})
+	}
+
+	credential, err := a.FinishPasskeyRegistration(claims.UserID, req.SessionToken, req.Name, req.Credential)
+	if err != nil {
+		// Respons pendaftaran yang ditolak adalah kesalahan klien, bukan login gagal
+		if errors.Is(err, ErrInvalidPasskey) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid passkey response",
+			})
+		}
+		return webAuthnError(c, err, "Failed to register passkey")
+	}
+
+	return c.JSON(http.StatusOK, credential)
+}
+
+// listPasskeysHandler menampilkan passkey milik pengguna
+func (a *Auth) listPasskeysHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	passkeys, err := a.ListPasskeys(claims.UserID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to list passkeys: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"passkeys": passkeys,
+	})
+}
+
+// deletePasskeyHandler menghapus satu passkey milik pengguna
+func (a *Auth) deletePasskeyHandler(c echo.Context) error {
+	claims, ok := middleware.EchoClaims(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not authenticated",
+		})
+	}
+
+	passkeyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid passkey ID",
+		})
+	}
+
+	if err := a.DeletePasskey(claims.UserID, uint(passkeyID)); err != nil {
+		if errors.Is(err, ErrPasskeyNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Passkey not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete passkey: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Passkey deleted",
+	})
+}
+
+// beginPasskeyLoginHandler memulai login tanpa password
+func (a *Auth) beginPasskeyLoginHandler(c echo.Context) error {
+	ceremony, err := a.BeginPasskeyLogin()
+	if err != nil {
+		return webAuthnError(c, err, "Failed to start passkey login")
+	}
+
+	return c.JSON(http.StatusOK, ceremony)
+}
+
+// finishPasskeyLoginHandler menyelesaikan login tanpa password
+func (a *Auth) finishPasskeyLoginHandler(c echo.Context) error {
+	var req struct {
+		SessionToken string          `json:"session_token"`
+		Credential   json.RawMessage `json:"credential"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	if req.SessionToken == "" || len(req.Credential) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Session token and credential are required",
+		})
+	}
+
+	user, err := a.FinishPasskeyLogin(req.SessionToken, req.Credential)
+	if err != nil {
+		return webAuthnError(c, err, "Failed to verify passkey")
+	}
+
+	return a.passkeyLoginResponse(c, user)
+}
+
+// beginWebAuthnMFAHandler memulai verifikasi passkey sebagai langkah kedua login
+func (a *Auth) beginWebAuthnMFAHandler(c echo.Context) error {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	if err := c.Bind(&req); err != nil || req.MFAToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "MFA token is required",
+		})
+	}
+
+	ceremony, err := a.BeginWebAuthnMFA(req.MFAToken)
+	if err != nil {
+		if errors.Is(err, ErrPasskeyNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "No passkey registered",
+			})
+		}
+		return webAuthnError(c, err, "Failed to start passkey verification")
+	}
+
+	return c.JSON(http.StatusOK, ceremony)
+}
+
+// verifyWebAuthnMFAHandler menyelesaikan login dua langkah dengan passkey
+func (a *Auth) verifyWebAuthnMFAHandler(c echo.Context) error {
+	var req struct {
+		MFAToken     string          `json:"mfa_token"`
+		SessionToken string          `json:"session_token"`
+		Credential   json.RawMessage `json:"credential"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	if req.MFAToken == "" || req.SessionToken == "" || len(req.Credential) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "MFA token, session token and credential are required",
+		})
+	}
+
+	user, err := a.VerifyWebAuthnMFA(req.MFAToken, req.SessionToken, req.Credential)
+	if err != nil {
+		return webAuthnError(c, err, "Failed to verify passkey")
+	}
+
+	return a.passkeyLoginResponse(c, user)
+}
+
+// passkeyLoginResponse menerbitkan sesi atau token setelah passkey diverifikasi
+func (a *Auth) passkeyLoginResponse(c echo.Context, user *models.User) error {
+	resp, err := a.issueLogin(c, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to generate token: " + err.Error(),
+		})
+	}
+
+	resp["user"] = map[string]interface{}{
+		"id":         user.ID,
+		"email":      user.Email,
+		"phone":      user.Phone,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// webAuthnError memetakan error passkey ke respons HTTP
+func webAuthnError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, ErrWebAuthnDisabled):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "WebAuthn is not enabled",
+		})
+	case errors.Is(err, ErrInvalidWebAuthnSession):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid or expired WebAuthn session",
+		})
+	case errors.Is(err, ErrInvalidMFAChallenge):
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid or expired MFA challenge",
+		})
+	case errors.Is(err, ErrInvalidPasskey):
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid passkey",
+		})
+	case errors.Is(err, ErrPasskeyCloned):
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Passkey rejected because it may have been cloned; remove it and register it again",
+		})
+	case errors.Is(err, ErrTooManyOTPAttempts):
+		return c.JSON(http.StatusTooManyRequests, map[string]string{
+			"error": "Too many attempts, please log in again",
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": message + ": " + err.Error(),
+	})
+}
+
+// Handler untuk request OTP
+func (a *Auth) requestOTPHandler(c echo.Context) error {
+	// Parse request
+	var req struct {
+		Contact       string `json:"contact"`        // email atau nomor telepon
+		Type          string `json:"type"`           // "sms", "email", "whatsapp"
+		Purpose       string `json:"purpose"`        // "login", "register", "reset_password"
+		DefaultRegion string `json:"default_region"` // Kode negara 2 huruf, default "ID"
+
+		// Data pengguna untuk purpose "register"; password boleh kosong
+		Password  string `json:"password"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validasi input
+	if req.Contact == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Contact is required",
+		})
+	}
+
+	if req.Type == "" {
+		// Gunakan tipe default dari konfigurasi
+		req.Type = a.config.OTP.DefaultType
+	}
+
+	if req.Type != "sms" && req.Type != "email" && req.Type != "whatsapp" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid OTP type",
+		})
+	}
+
+	if req.Purpose == "" {
+		req.Purpose = "login"
+	}
+
+	// Gunakan default region Indonesia jika tidak disediakan
+	if req.DefaultRegion == "" {
+		req.DefaultRegion = "ID"
+	}
+
+	if req.Purpose != "login" && req.Purpose != "register" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid purpose",
+		})
+	}
+
+	// Batasi pengiriman per target dan per IP sebelum OTP dibuat
+	if err := a.ReserveOTPSend(otpQuotaTarget(req.Contact, req.DefaultRegion), c.RealIP()); err != nil {
+		var retry *RetryAfterError
+		if errors.As(err, &retry) {
+			return tooManyRequests(c, retry, "Too many OTP requests, please try again later")
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to request OTP: " + err.Error(),
+		})
+	}
+	retryAfter := a.otpQuota().ResendCooldown
+
+	// Jika untuk login, cek apakah pengguna sudah terdaftar
+	if req.Purpose == "login" {
+		// Request OTP untuk login
+		_, err := a.RequestOTPLogin(req.Contact, req.Type, req.DefaultRegion)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "User not found",
+				})
+			}
+			if errors.Is(err, ErrOTPDelivery) {
+				// Detail error provider hanya dicatat di log
+				log.Printf("Failed to deliver OTP: %v", err)
+				return c.JSON(http.StatusBadGateway, map[string]string{
+					"error": "Failed to deliver OTP",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to request OTP: " + err.Error(),
+			})
+		}
+
+		// Berhasil mengirim OTP
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message":     "OTP has been sent",
+			"retry_after": retryAfter,
+		})
+	} else {
+		// Untuk registrasi, simpan data pengguna sampai OTP diverifikasi
+		_, err := a.RequestOTPRegistration(req.Contact, req.Type, req.DefaultRegion, req.Password, req.FirstName, req.LastName)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrContactRegistered):
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Contact already registered",
+				})
+			case errors.Is(err, ErrInvalidContact):
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid contact: " + err.Error(),
+				})
+			case errors.Is(err, ErrWeakPassword):
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": fmt.Sprintf("Password must be at least %d characters", MinPasswordLength),
+				})
+			case errors.Is(err, ErrOTPDelivery):
+				log.Printf("Failed to deliver OTP: %v", err)
+				return c.JSON(http.StatusBadGateway, map[string]string{
+					"error": "Failed to deliver OTP",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to request OTP: " + err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message":     "OTP has been sent for registration",
+			"retry_after": retryAfter,
+		})
+	}
+}
+
+// tooManyRequests mengirim respons 429 dengan header Retry-After dan field
+// retry_after dalam detik
+func tooManyRequests(c echo.Context, retry *RetryAfterError, message string) error {
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(retry.retryAfterSeconds(), 10))
+	return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+		"error":       message,
+		"retry_after": retry.retryAfterSeconds(),
+	})
+}
+
+// Handler untuk verifikasi OTP
+func (a *Auth) verifyOTPHandler(c echo.Context) error {
+	// Parse request
+	var req struct {
+		Contact       string `json:"contact"`        // email atau nomor telepon
+		Type          string `json:"type"`           // "sms", "email", "whatsapp"
+		Code          string `json:"code"`           // kode OTP
+		Purpose       string `json:"purpose"`        // "login", "register", "reset_password"
+		DefaultRegion string `json:"default_region"` // Kode negara 2 huruf, default "ID"
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validasi input
+	if req.Contact == "" || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Contact and code are required",
+		})
+	}
+
+	if req.Type == "" {
+		req.Type = a.config.OTP.DefaultType
+	}
+
+	if req.Purpose == "" {
+		req.Purpose = "login"
+	}
+
+	// Gunakan default region Indonesia jika tidak disediakan
+	if req.DefaultRegion == "" {
+		req.DefaultRegion = "ID"
+	}
+
+	// Verifikasi OTP untuk login
+	if req.Purpose == "login" {
+		user, err := a.VerifyOTPLogin(req.Contact, req.Type, req.Code, req.DefaultRegion)
+		var mfa *MFARequiredError
+		if errors.As(err, &mfa) {
+			return mfaRequiredResponse(c, mfa)
+		}
+		if errors.Is(err, ErrTooManyOTPAttempts) {
+			return c.JSON(http.StatusTooManyRequests, map[string]string{
+				"error": "Too many attempts, please request a new code",
+			})
+		}
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid OTP: " + err.Error(),
+			})
+		}
+
+		// Terbitkan sesi cookie atau access token dan refresh token
+		resp, err := a.issueLogin(c, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate token: " + err.Error(),
+			})
+		}
+
+		resp["user"] = map[string]interface{}{
+			"id":         user.ID,
+			"email":      user.Email,
+			"phone":      user.Phone,
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+
+	// Verifikasi OTP untuk registrasi membuat pengguna baru
+	if req.Purpose == "register" {
+		user, err := a.VerifyOTPRegistration(req.Contact, req.Type, req.Code, req.DefaultRegion)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrTooManyOTPAttempts):
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"error": "Too many attempts, please request a new code",
+				})
+			case errors.Is(err, ErrContactRegistered):
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Contact already registered",
+				})
+			case errors.Is(err, ErrInvalidContact):
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid contact: " + err.Error(),
+				})
+			case errors.Is(err, ErrInvalidRegistration):
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Invalid OTP: " + err.Error(),
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to register user: " + err.Error(),
+			})
+		}
+
+		resp, err := a.issueLogin(c, user)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate token: " + err.Error(),
+			})
+		}
+
+		resp["user"] = map[string]interface{}{
+			"id":          user.ID,
+			"email":       user.Email,
+			"phone":       user.Phone,
+			"first_name":  user.FirstName,
+			"last_name":   user.LastName,
+			"is_verified": user.IsVerified,
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+
+	// Reset password dengan OTP memakai endpoint /auth/reset-password
+	return c.JSON(http.StatusBadRequest, map[string]string{
+		"error": "Invalid purpose",
+	})
+}
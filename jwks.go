@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/utils"
+)
+
+// Nilai default untuk JWKSVerifier
+const (
+	DefaultJWKSCacheTTL        = 5 * time.Minute
+	DefaultJWKSRefreshInterval = 30 * time.Second
+)
+
+// JWKSVerifier memverifikasi token menggunakan public key dari JWKS remote,
+// misalnya https://auth.example.com/.well-known/jwks.json. Kunci di-cache
+// (default 5 menit) dan diambil ulang lebih awal jika token memakai kid yang
+// belum dikenal. Method Validate dapat dipakai langsung dengan middleware:
+//
+//	verifier := auth.NewJWKSVerifier("https://auth.example.com/.well-known/jwks.json")
+//	e.Use(middleware.EchoAuthMiddleware(middleware.WithValidator(verifier.Validate)))
+type JWKSVerifier struct {
+	url             string
+	client          *http.Client
+	cacheTTL        time.Duration
+	refreshInterval time.Duration
+	rules           utils.ValidationRules
+
+	mu        sync.Mutex
+	keys      map[string]*utils.SigningKey
+	fetchedAt time.Time
+	fetchErr  error     // error pengambilan terakhir jika gagal
+	failedAt  time.Time // waktu pengambilan terakhir yang gagal
+	inflight  *jwksCall
+}
+
+// JWKSOption mengatur JWKSVerifier
+type JWKSOption func(*JWKSVerifier)
+
+// WithJWKSHTTPClient mengganti http.Client yang dipakai untuk mengambil JWKS
+func WithJWKSHTTPClient(client *http.Client) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.client = client
+	}
+}
+
+// WithJWKSCacheTTL mengatur berapa lama JWKS disimpan sebelum diambil ulang
+func WithJWKSCacheTTL(ttl time.Duration) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.cacheTTL = ttl
+	}
+}
+
+// WithJWKSRefreshInterval mengatur jarak minimum antar pengambilan ulang
+// JWKS yang dipicu oleh kid yang tidak dikenal
+func WithJWKSRefreshInterval(interval time.Duration) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.refreshInterval = interval
+	}
+}
+
+// WithJWKSIssuer mewajibkan klaim iss token sama dengan issuer
+func WithJWKSIssuer(issuer string) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.rules.Issuer = issuer
+	}
+}
+
+// WithJWKSAudience mewajibkan klaim aud token memuat salah satu audience
+func WithJWKSAudience(audience ...string) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.rules.Audience = audience
+	}
+}
+
+// WithJWKSLeeway mengatur toleransi selisih jam untuk exp, nbf dan iat
+func WithJWKSLeeway(leeway time.Duration) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.rules.Leeway = leeway
+	}
+}
+
+// NewJWKSVerifier membuat JWKSVerifier untuk URL JWKS yang diberikan.
+// JWKS baru diambil saat token pertama divalidasi.
+func NewJWKSVerifier(url string, opts ...JWKSOption) *JWKSVerifier {
+	v := &JWKSVerifier{
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		cacheTTL:        DefaultJWKSCacheTTL,
+		refreshInterval: DefaultJWKSRefreshInterval,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Validate memvalidasi token JWT dengan kunci dari JWKS.
+// Signature-nya sesuai dengan middleware.TokenValidator.
+func (v *JWKSVerifier) Validate(tokenString string) (*jwt.Token, error) {
+	return utils.ParseJWT(tokenString, v.Keyfunc, v.rules)
+}
+
+// Keyfunc adalah jwt.Keyfunc yang memilih public key berdasarkan header kid
+func (v *JWKSVerifier) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token tidak memiliki kid")
+	}
+
+	key, err := v.lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.Public, nil
+}
+
+// Refresh mengambil ulang JWKS dan mengganti isi cache
+func (v *JWKSVerifier) Refresh(ctx context.Context) error {
+	return v.refresh(ctx, true)
+}
+
+// lookup mencari kunci di cache dan mengambil ulang JWKS bila perlu
+func (v *JWKSVerifier) lookup(kid string) (*utils.SigningKey, error) {
+	v.mu.Lock()
+	age := time.Since(v.fetchedAt)
+	key, known := v.keys[kid]
+	// Ambil ulang jika cache kedaluwarsa, atau jika kid belum dikenal
+	// (kemungkinan kunci baru setelah rotasi) dengan batas frekuensi
+	stale := v.keys == nil || age > v.cacheTTL || (!known && age > v.refreshInterval)
+	v.mu.Unlock()
+
+	if stale {
+		if err := v.refresh(context.Background(), false); err != nil {
+			// Tetap pakai cache lama jika server JWKS sedang tidak tersedia
+			if !known {
+				return nil, err
+			}
+			return key, nil
+		}
+
+		v.mu.Lock()
+		key, known = v.keys[kid]
+		v.mu.Unlock()
+	}
+
+	if !known {
+		return nil, fmt.Errorf("kunci dengan kid %q tidak dikenal", kid)
+	}
+	return key, nil
+}
+
+// jwksCall adalah pengambilan JWKS yang sedang berjalan
+type jwksCall struct {
+	done chan struct{}
+	err  error
+}
+
+// refresh mengambil JWKS tanpa memegang v.mu selama permintaan HTTP.
+// Pemanggil yang datang bersamaan menunggu hasil pengambilan yang sama.
+// Setelah pengambilan gagal, percobaan berikutnya ditunda selama
+// refreshInterval kecuali force bernilai true.
+func (v *JWKSVerifier) refresh(ctx context.Context, force bool) error {
+	v.mu.Lock()
+	if call := v.inflight; call != nil {
+		v.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	if !force && v.fetchErr != nil && time.Since(v.failedAt) < v.refreshInterval {
+		err := v.fetchErr
+		v.mu.Unlock()
+		return err
+	}
+	call := &jwksCall{done: make(chan struct{})}
+	v.inflight = call
+	v.mu.Unlock()
+
+	keys, err := v.fetch(ctx)
+
+	v.mu.Lock()
+	if err != nil {
+		v.fetchErr = err
+		v.failedAt = time.Now()
+	} else {
+		v.keys = keys
+		v.fetchedAt = time.Now()
+		v.fetchErr = nil
+	}
+	v.inflight = nil
+	v.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+	return err
+}
+
+// fetch mengambil JWKS dari server
+func (v *JWKSVerifier) fetch(ctx context.Context) (map[string]*utils.SigningKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gagal mengambil JWKS: status %d", resp.StatusCode)
+	}
+
+	var set utils.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("gagal mem-parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*utils.SigningKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.VerificationKey()
+		if err != nil {
+			// Kunci dengan tipe yang tidak didukung dilewati saja
+			continue
+		}
+		keys[key.ID] = key
+	}
+
+	return keys, nil
+}
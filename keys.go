@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/utils"
+)
+
+// jwksMaxAge adalah lama (detik) JWKS boleh di-cache oleh klien
+const jwksMaxAge = 300
+
+// rotationLeeway ditambahkan pada masa retensi kunci lama untuk
+// mengantisipasi selisih jam antar server
+const rotationLeeway = time.Minute
+
+// keyDirSyncInterval adalah jarak pembacaan ulang jwt.key_dir untuk memuat
+// kunci hasil rotasi instance lain
+const keyDirSyncInterval = time.Minute
+
+// keyActivationDelay adalah jeda sebelum kunci baru di jwt.key_dir dipakai
+// untuk menandatangani, agar semua instance sempat memuatnya untuk verifikasi
+const keyActivationDelay = 2 * keyDirSyncInterval
+
+// keyFileExt adalah ekstensi file kunci di jwt.key_dir. Nama file berisi
+// waktu aktif kunci dalam nanodetik Unix, misalnya 01729150000000000000.pem.
+const keyFileExt = ".pem"
+
+// JWKS mengembalikan public key yang dipakai untuk memverifikasi token
+// instance ini. Kunci HMAC tidak pernah dipublikasikan, sehingga hasilnya
+// kosong untuk algoritma HS*.
+func (a *Auth) JWKS() utils.JWKS {
+	if a.keys == nil {
+		return utils.JWKS{Keys: []utils.JWK{}}
+	}
+	return a.keys.JWKS()
+}
+
+// RotateSigningKey membuat kunci penandatanganan baru dengan algoritma yang
+// sama. Kunci lama tetap dipublikasikan dan diterima untuk verifikasi sampai
+// semua token yang ditandatanganinya kedaluwarsa.
+//
+// Tanpa jwt.key_dir, kunci baru hanya ada di memori instance ini sehingga
+// rotasi hanya cocok untuk aplikasi satu instance. Dengan jwt.key_dir, kunci
+// baru disimpan di direktori bersama dan mulai dipakai setelah
+// keyActivationDelay. Rotasi ditolak jika jwt.private_key_file diatur.
+func (a *Auth) RotateSigningKey() error {
+	if a.keys == nil {
+		return errors.New("instance belum memiliki kunci penandatanganan")
+	}
+
+	current := a.keys.SigningKey()
+	if current.IsSymmetric() {
+		return errors.New("rotasi kunci hanya didukung untuk algoritma asimetris")
+	}
+	if a.config.JWT.PrivateKeyFile != "" {
+		return errors.New("rotasi kunci tidak didukung dengan jwt.private_key_file; gunakan jwt.key_dir")
+	}
+
+	next, err := utils.GenerateSigningKey(current.Method.Alg())
+	if err != nil {
+		return err
+	}
+
+	if a.config.JWT.KeyDir != "" {
+		if err := writeKeyFile(a.config.JWT.KeyDir, next, time.Now().Add(keyActivationDelay)); err != nil {
+			return err
+		}
+		_, err := a.syncKeyDir()
+		return err
+	}
+
+	a.keys.Rotate(next, keyRetention(a.config.JWT))
+
+	return nil
+}
+
+// StartKeyRotation merotasi kunci penandatanganan setiap interval sampai ctx
+// dibatalkan. Dipanggil otomatis oleh New jika jwt.rotation_interval diatur.
+// Dengan jwt.key_dir, rotasi dilewati jika instance lain sudah membuat kunci
+// baru dalam interval yang sama.
+func (a *Auth) StartKeyRotation(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.rotateIfDue(interval); err != nil {
+					log.Printf("Gagal merotasi kunci JWT: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// rotateIfDue merotasi kunci jika kunci terbaru sudah berumur interval
+func (a *Auth) rotateIfDue(interval time.Duration) error {
+	if a.config.JWT.KeyDir == "" {
+		return a.RotateSigningKey()
+	}
+
+	newest, err := a.syncKeyDir()
+	if err != nil {
+		return err
+	}
+	if time.Since(newest) < interval {
+		return nil
+	}
+	return a.RotateSigningKey()
+}
+
+// startKeyDirSync membaca ulang jwt.key_dir secara berkala sampai ctx
+// dibatalkan
+func (a *Auth) startKeyDirSync(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(keyDirSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := a.syncKeyDir(); err != nil {
+					log.Printf("Gagal membaca jwt.key_dir: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// syncKeyDir menyamakan KeyRing dengan isi jwt.key_dir dan mengembalikan
+// waktu aktif kunci terbaru
+func (a *Auth) syncKeyDir() (time.Time, error) {
+	a.keyDirMu.Lock()
+	defer a.keyDirMu.Unlock()
+
+	set, err := readKeyDir(a.config.JWT)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if set.active.ID != a.keys.SigningKey().ID {
+		a.keys.Rotate(set.active, keyRetention(a.config.JWT))
+	}
+	set.addTo(a.keys)
+
+	return set.newest, nil
+}
+
+// keyRetention adalah lama kunci pensiun tetap diterima untuk verifikasi
+func keyRetention(cfg config.JWT) time.Duration {
+	return time.Duration(cfg.ExpiresIn)*time.Second + rotationLeeway
+}
+
+// keyDirSet adalah isi jwt.key_dir
+type keyDirSet struct {
+	active   *utils.SigningKey               // kunci aktif terbaru untuk menandatangani
+	verifyOn map[*utils.SigningKey]time.Time // kunci lain -> batas verifikasi; nol berarti belum aktif
+	newest   time.Time                       // waktu aktif kunci terbaru, termasuk yang belum aktif
+}
+
+// addTo menambahkan kunci verifikasi ke ring
+func (s *keyDirSet) addTo(ring *utils.KeyRing) {
+	for key, until := range s.verifyOn {
+		ring.AddVerificationKey(key, until)
+	}
+}
+
+// keyFile adalah satu file kunci di jwt.key_dir
+type keyFile struct {
+	path     string
+	activeAt time.Time
+}
+
+// readKeyDir membaca semua kunci di jwt.key_dir. Kunci dengan waktu aktif
+// terbaru yang sudah lewat dipakai untuk menandatangani; kunci yang belum
+// aktif hanya dipakai untuk verifikasi. File kunci pensiun yang masa
+// retensinya habis dihapus. Direktori kosong diisi dengan kunci baru.
+func readKeyDir(cfg config.JWT) (*keyDirSet, error) {
+	files, err := listKeyFiles(cfg.KeyDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		key, err := utils.GenerateSigningKey(cfg.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeKeyFile(cfg.KeyDir, key, time.Now()); err != nil {
+			return nil, err
+		}
+		if files, err = listKeyFiles(cfg.KeyDir); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	active := -1
+	for i, f := range files {
+		if !f.activeAt.After(now) {
+			active = i
+		}
+	}
+	if active < 0 {
+		// Semua kunci belum aktif; pakai yang paling awal
+		active = 0
+	}
+
+	set := &keyDirSet{
+		verifyOn: make(map[*utils.SigningKey]time.Time),
+		newest:   files[len(files)-1].activeAt,
+	}
+	if set.active, err = utils.LoadSigningKeyFile(cfg.Algorithm, files[active].path, ""); err != nil {
+		return nil, err
+	}
+
+	retention := keyRetention(cfg)
+	for i, f := range files {
+		if i == active {
+			continue
+		}
+
+		var until time.Time
+		if i < active {
+			// Kunci lama pensiun saat kunci berikutnya aktif
+			until = files[i+1].activeAt.Add(retention)
+			if now.After(until) {
+				os.Remove(f.path)
+				continue
+			}
+		}
+
+		key, err := utils.LoadVerificationKeyFile(cfg.Algorithm, f.path)
+		if err != nil {
+			return nil, err
+		}
+		set.verifyOn[key] = until
+	}
+
+	return set, nil
+}
+
+// listKeyFiles mengembalikan file kunci di dir, diurutkan dari waktu aktif
+// paling awal. File dengan nama lain diabaikan.
+func listKeyFiles(dir string) ([]keyFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca jwt.key_dir: %w", err)
+	}
+
+	var files []keyFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, keyFileExt) {
+			continue
+		}
+		nanos, err := strconv.ParseInt(strings.TrimSuffix(name, keyFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		files = append(files, keyFile{path: filepath.Join(dir, name), activeAt: time.Unix(0, nanos)})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].activeAt.Before(files[j].activeAt)
+	})
+	return files, nil
+}
+
+// writeKeyFile menyimpan private key ke dir dengan waktu aktif activeAt.
+// File ditulis ke file sementara lalu di-rename agar instance lain tidak
+// membaca file yang belum lengkap.
+func writeKeyFile(dir string, key *utils.SigningKey, activeAt time.Time) error {
+	data, err := utils.EncodePrivateKeyPEM(key.Private)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".key-*")
+	if err != nil {
+		return fmt.Errorf("gagal menulis kunci ke jwt.key_dir: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("gagal menulis kunci ke jwt.key_dir: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("gagal menulis kunci ke jwt.key_dir: %w", err)
+	}
+
+	name := fmt.Sprintf("%020d%s", activeAt.UnixNano(), keyFileExt)
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidToken dikembalikan saat token JWT tidak valid atau sudah kedaluwarsa
+var ErrInvalidToken = errors.New("token tidak valid atau sudah kedaluwarsa")
+
+// RevokeToken mencabut satu access token sehingga ditolak oleh middleware
+// sebelum waktu kedaluwarsanya
+func (a *Auth) RevokeToken(tokenString string) error {
+	token, err := a.ValidateToken(tokenString)
+	if err != nil || !token.Valid {
+		return ErrInvalidToken
+	}
+
+	claims, err := utils.ClaimsFromToken(token)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	return a.revokeClaims(claims)
+}
+
+// revokeClaims mencabut token berdasarkan klaimnya
+func (a *Auth) revokeClaims(claims *Claims) error {
+	if claims.ID == "" {
+		return ErrInvalidToken
+	}
+
+	expiresAt := time.Now().Add(time.Duration(a.config.JWT.ExpiresIn) * time.Second)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	return a.RevocationStore().Revoke(claims.ID, claims.UserID, expiresAt)
+}
+
+// Logout mencabut access token dan, jika diberikan, seluruh family refresh
+// token yang dimulai oleh login yang sama
+func (a *Auth) Logout(accessToken, refreshToken string) error {
+	token, err := a.ValidateToken(accessToken)
+	if err != nil || !token.Valid {
+		return ErrInvalidToken
+	}
+
+	claims, err := utils.ClaimsFromToken(token)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	return a.logout(claims, refreshToken)
+}
+
+// logout mencabut token berdasarkan klaim yang sudah divalidasi
+func (a *Auth) logout(claims *Claims, refreshToken string) error {
+	if err := a.revokeClaims(claims); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	// Hanya cabut refresh token milik pengguna yang sama
+	var stored models.RefreshToken
+	err := a.DB().Where("token_hash = ? AND user_id = ?", utils.HashToken(refreshToken), claims.UserID).
+		First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return models.RevokeRefreshTokenFamily(a.DB(), stored.FamilyID)
+}
+
+// LogoutEverywhere mencabut semua access token, refresh token dan sesi
+// pengguna di semua perangkat
+func (a *Auth) LogoutEverywhere(userID uint) error {
+	if err := a.RevocationStore().RevokeUser(userID, time.Now()); err != nil {
+		return err
+	}
+	if err := models.RevokeUserRefreshTokens(a.DB(), userID); err != nil {
+		return err
+	}
+	return models.RevokeUserSessions(a.DB(), userID)
+}
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+// Error yang dikembalikan oleh fungsi autentikasi dua langkah
+var (
+	ErrTOTPNotEnrolled     = errors.New("pendaftaran TOTP belum dimulai")
+	ErrTOTPAlreadyEnabled  = errors.New("TOTP sudah diaktifkan")
+	ErrTOTPNotEnabled      = errors.New("TOTP belum diaktifkan")
+	ErrInvalidTOTPCode     = errors.New("kode TOTP tidak valid")
+	ErrInvalidMFAChallenge = errors.New("tantangan MFA tidak valid atau sudah kedaluwarsa")
+	ErrMFAKeyRequired      = errors.New("mfa.encryption_key wajib diatur jika jwt.secret kosong")
+)
+
+// Parameter TOTP (RFC 6238) yang didukung aplikasi authenticator umum
+const (
+	totpPeriod     = 30
+	totpSkew       = 1
+	totpDigits     = otp.DigitsSix
+	totpAlgorithm  = otp.AlgorithmSHA1
+	totpSecretSize = 20
+	totpQRSize     = 256
+)
+
+// mfaChallengeBytes adalah jumlah byte acak dalam token tantangan MFA
+const mfaChallengeBytes = 32
+
+// MFARequiredError dikembalikan LoginLocal saat password benar tetapi
+// pengguna mengaktifkan TOTP atau mendaftarkan passkey. Token diteruskan ke
+// VerifyMFA bersama kode dari aplikasi authenticator, atau ke
+// VerifyWebAuthnMFA bersama respons passkey, untuk menyelesaikan login.
+type MFARequiredError struct {
+	Token     string
+	ExpiresAt time.Time
+	Methods   []string
+}
+
+// Error mengimplementasikan interface error
+func (e *MFARequiredError) Error() string {
+	return "verifikasi dua langkah diperlukan"
+}
+
+// TOTPEnrollment berisi data untuk mendaftarkan aplikasi authenticator.
+// QRCode adalah gambar PNG dari URL otpauth://.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+	QRCode []byte `json:"qr_code"`
+}
+
+// EnrollTOTP membuat secret TOTP baru untuk pengguna. Secret disimpan
+// terenkripsi dan baru berlaku setelah dikonfirmasi dengan ConfirmTOTP.
+// Pendaftaran yang belum dikonfirmasi diganti oleh pemanggilan berikutnya.
+func (a *Auth) EnrollTOTP(userID uint) (*TOTPEnrollment, error) {
+	var user models.User
+	if err := a.DB().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	key, err := a.mfaKey()
+	if err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Phone
+	}
+	issuer := a.config.MFA.Issuer
+	if issuer == "" {
+		issuer = config.DefaultMFAIssuer
+	}
+
+	totpKey, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: account,
+		Period:      totpPeriod,
+		SecretSize:  totpSecretSize,
+		Digits:      totpDigits,
+		Algorithm:   totpAlgorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := utils.EncryptSecret(key, totpKey.Secret())
+	if err != nil {
+		return nil, err
+	}
+	err = a.DB().Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    encrypted,
+		"totp_last_step": 0,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := totpKey.Image(totpQRSize, totpQRSize)
+	if err != nil {
+		return nil, err
+	}
+	var qr bytes.Buffer
+	if err := png.Encode(&qr, img); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: totpKey.Secret(),
+		URL:    totpKey.URL(),
+		QRCode: qr.Bytes(),
+	}, nil
+}
+
+// ConfirmTOTP mengaktifkan TOTP setelah pengguna memasukkan kode pertama
+// dari aplikasi authenticator
+func (a *Auth) ConfirmTOTP(userID uint, code string) error {
+	var user models.User
+	if err := a.DB().First(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return ErrTOTPNotEnrolled
+	}
+
+	if err := a.checkTOTP(&user, code); err != nil {
+		return err
+	}
+
+	return a.DB().Model(&user).Update("totp_enabled", true).Error
+}
+
+// DisableTOTP menonaktifkan TOTP dan menghapus secret-nya. Kode yang valid
+// diperlukan agar sesi yang dicuri saja tidak cukup untuk mematikan MFA.
+func (a *Auth) DisableTOTP(userID uint, code string) error {
+	var user models.User
+	if err := a.DB().First(&user, userID).Error; err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	if err := a.checkTOTP(&user, code); err != nil {
+		return err
+	}
+
+	return a.DB().Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+	}).Error
+}
+
+// VerifyMFA menyelesaikan login dua langkah dengan token dari
+// MFARequiredError dan kode TOTP atau kode cadangan. Setelah otp.max_attempts
+// kode salah, tantangan dibatalkan dan ErrTooManyOTPAttempts dikembalikan.
+func (a *Auth) VerifyMFA(challengeToken, code string) (*models.User, error) {
+	challenge, user, err := a.openMFAChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var check func(user *models.User, code string) error
+	switch {
+	case isBackupCode(code):
+		check = func(user *models.User, code string) error { return a.UseBackupCode(user.ID, code) }
+	case user.TOTPEnabled:
+		check = a.checkTOTP
+	default:
+		// Pengguna yang hanya memakai passkey tidak memiliki kode TOTP
+		check = func(*models.User, string) error { return ErrInvalidTOTPCode }
+	}
+	if err := check(user, code); err != nil {
+		if !errors.Is(err, ErrInvalidTOTPCode) && !errors.Is(err, ErrInvalidBackupCode) {
+			return nil, err
+		}
+		if err := a.failMFAChallenge(challenge); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := a.completeMFAChallenge(challenge, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// requireMFA mengembalikan *MFARequiredError jika pengguna mengaktifkan TOTP
+// atau mendaftarkan passkey, atau nil jika login dapat langsung diselesaikan
+func (a *Auth) requireMFA(user *models.User) error {
+	var methods []string
+	if user.TOTPEnabled {
+		methods = append(methods, "totp")
+	}
+	if a.webAuthn != nil {
+		var passkeys int64
+		if err := a.DB().Model(&models.WebAuthnCredential{}).
+			Where("user_id = ?", user.ID).Count(&passkeys).Error; err != nil {
+			return err
+		}
+		if passkeys > 0 {
+			methods = append(methods, "webauthn")
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+
+	if remaining, err := a.BackupCodesRemaining(user.ID); err == nil && remaining > 0 {
+		methods = append(methods, "backup_code")
+	}
+
+	challenge, err := a.newMFAChallenge(user.ID, methods)
+	if err != nil {
+		return err
+	}
+	return challenge
+}
+
+// newMFAChallenge menerbitkan tantangan MFA untuk pengguna yang passwordnya
+// sudah diverifikasi
+func (a *Auth) newMFAChallenge(userID uint, methods []string) (*MFARequiredError, error) {
+	token, err := utils.GenerateRandomToken(mfaChallengeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresIn := a.config.MFA.ChallengeExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = config.DefaultMFAChallengeExpiresIn
+	}
+	now := time.Now()
+
+	challenge := models.MFAChallenge{
+		UserID:    userID,
+		Token:     utils.HashToken(token),
+		ExpiresAt: now.Add(time.Duration(expiresIn) * time.Second),
+	}
+	if err := a.DB().Create(&challenge).Error; err != nil {
+		return nil, err
+	}
+
+	// Tantangan yang kedaluwarsa tidak dibutuhkan lagi
+	a.DB().Where("expires_at <= ?", now).Delete(&models.MFAChallenge{})
+
+	return &MFARequiredError{
+		Token:     token,
+		ExpiresAt: challenge.ExpiresAt,
+		Methods:   methods,
+	}, nil
+}
+
+// openMFAChallenge memuat tantangan MFA yang masih berlaku beserta penggunanya
+func (a *Auth) openMFAChallenge(challengeToken string) (*models.MFAChallenge, *models.User, error) {
+	var challenge models.MFAChallenge
+	err := a.DB().Where("token = ?", utils.HashToken(challengeToken)).First(&challenge).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidMFAChallenge
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	if challenge.Attempts >= a.otpMaxAttempts() {
+		return nil, nil, ErrTooManyOTPAttempts
+	}
+
+	var user models.User
+	if err := a.DB().First(&user, challenge.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidMFAChallenge
+		}
+		return nil, nil, err
+	}
+
+	return &challenge, &user, nil
+}
+
+// failMFAChallenge mencatat satu percobaan gagal dan mengembalikan
+// ErrTooManyOTPAttempts jika batas otp.max_attempts tercapai
+func (a *Auth) failMFAChallenge(challenge *models.MFAChallenge) error {
+	if err := a.DB().Model(challenge).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return err
+	}
+	if challenge.Attempts+1 >= a.otpMaxAttempts() {
+		return ErrTooManyOTPAttempts
+	}
+	return nil
+}
+
+// completeMFAChallenge menandai tantangan sudah dipakai dan mencatat waktu login
+func (a *Auth) completeMFAChallenge(challenge *models.MFAChallenge, user *models.User) error {
+	// Update bersyarat agar satu tantangan hanya menghasilkan satu login
+	now := time.Now()
+	res := a.DB().Model(&models.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", now)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidMFAChallenge
+	}
+
+	user.LastLogin = &now
+	a.DB().Model(user).Update("last_login", now)
+	return nil
+}
+
+// checkTOTP mencocokkan kode dengan secret pengguna dalam jendela ±totpSkew
+// langkah. Langkah yang cocok dicatat sehingga kode yang sama, atau kode dari
+// langkah sebelumnya, tidak dapat dipakai lagi.
+func (a *Auth) checkTOTP(user *models.User, code string) error {
+	key, err := a.mfaKey()
+	if err != nil {
+		return err
+	}
+	secret, err := utils.DecryptSecret(key, user.TOTPSecret)
+	if err != nil {
+		return fmt.Errorf("gagal membuka secret TOTP: %w", err)
+	}
+
+	code = strings.TrimSpace(code)
+	opts := totp.ValidateOpts{Period: totpPeriod, Digits: totpDigits, Algorithm: totpAlgorithm}
+	current := time.Now().Unix() / totpPeriod
+
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= user.TOTPLastStep {
+			continue
+		}
+
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), opts)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+
+		res := a.DB().Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidTOTPCode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	return ErrInvalidTOTPCode
+}
+
+// mfaKey mengembalikan key AES-256 untuk secret TOTP: hash SHA-256 dari
+// mfa.encryption_key, atau turunan jwt.secret. Key acak tidak dipakai karena
+// secret yang tersimpan harus tetap terbaca setelah restart.
+func (a *Auth) mfaKey() ([]byte, error) {
+	switch {
+	case a.config.MFA.EncryptionKey != "":
+		sum := sha256.Sum256([]byte(a.config.MFA.EncryptionKey))
+		return sum[:], nil
+	case a.config.JWT.Secret != "":
+		mac := hmac.New(sha256.New, []byte(a.config.JWT.Secret))
+		mac.Write([]byte("kreasimaju-auth mfa"))
+		return mac.Sum(nil), nil
+	}
+	return nil, ErrMFAKeyRequired
+}
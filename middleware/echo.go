@@ -4,15 +4,45 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/kreasimaju/auth/utils"
 	"github.com/labstack/echo/v4"
 )
 
 // EchoAuthMiddleware adalah middleware autentikasi untuk Echo
-func EchoAuthMiddleware() echo.MiddlewareFunc {
+func EchoAuthMiddleware(opts ...Option) echo.MiddlewareFunc {
+	o := newOptions(opts)
+
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			// Sesi cookie diperiksa lebih dulu jika middleware mendukung sesi
+			if o.session != nil {
+				if cookie, err := c.Cookie(o.sessionCookie); err == nil && cookie.Value != "" {
+					req := c.Request()
+					if !o.allowsSessionRequest(req.Method, req.Host, req.Header.Get("Origin"), req.Header.Get("Sec-Fetch-Site")) {
+						return c.JSON(http.StatusForbidden, map[string]string{
+							"error": "Cross-origin request rejected",
+						})
+					}
+
+					claims, err := o.session(cookie.Value)
+					if err != nil {
+						return c.JSON(http.StatusUnauthorized, map[string]string{
+							"error": "Invalid or expired session",
+						})
+					}
+
+					if o.rejectsUnverified(claims) {
+						return c.JSON(http.StatusForbidden, map[string]string{
+							"error": "Email address is not verified",
+						})
+					}
+
+					c.Set(ClaimsKey, claims)
+					c.Set(UserKey, claims.Map())
+					return next(c)
+				}
+			}
+
 			// Mendapatkan token dari header
 			auth := c.Request().Header.Get("Authorization")
 			if auth == "" {
@@ -32,7 +62,7 @@ func EchoAuthMiddleware() echo.MiddlewareFunc {
 			tokenString := parts[1]
 
 			// Validasi token JWT
-			token, err := utils.ValidateJWT(tokenString)
+			token, err := o.validator(tokenString)
 			if err != nil || !token.Valid {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error": "Invalid or expired token",
@@ -40,15 +70,35 @@ func EchoAuthMiddleware() echo.MiddlewareFunc {
 			}
 
 			// Ambil klaim dari token
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
+			claims, err := utils.ClaimsFromToken(token)
+			if err != nil {
 				return c.JSON(http.StatusInternalServerError, map[string]string{
 					"error": "Could not parse token claims",
 				})
 			}
 
+			// Tolak token yang sudah dicabut
+			revoked, err := o.isRevoked(claims)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Could not check token revocation",
+				})
+			}
+			if revoked {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Token has been revoked",
+				})
+			}
+
+			if o.rejectsUnverified(claims) {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "Email address is not verified",
+				})
+			}
+
 			// Tetapkan klaim user ke konteks
-			c.Set("user", claims)
+			c.Set(ClaimsKey, claims)
+			c.Set(UserKey, claims.Map())
 
 			return next(c)
 		}
@@ -60,7 +110,7 @@ func EchoRoleMiddleware(roles ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Mendapatkan user dari konteks (yang diatur oleh middleware auth)
-			user, ok := c.Get("user").(jwt.MapClaims)
+			claims, ok := EchoClaims(c)
 			if !ok {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error": "User not authenticated",
@@ -68,8 +118,8 @@ func EchoRoleMiddleware(roles ...string) echo.MiddlewareFunc {
 			}
 
 			// Memeriksa peran pengguna
-			userRole, ok := user["role"].(string)
-			if !ok {
+			userRole := claims.Role
+			if userRole == "" {
 				return c.JSON(http.StatusForbidden, map[string]string{
 					"error": "User has no role assigned",
 				})
@@ -88,3 +138,17 @@ func EchoRoleMiddleware(roles ...string) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// EchoClaims mengambil klaim pengguna yang diatur oleh EchoAuthMiddleware
+func EchoClaims(c echo.Context) (*utils.Claims, bool) {
+	if claims, ok := claimsFromValue(c.Get(ClaimsKey)); ok {
+		return claims, true
+	}
+	return claimsFromValue(c.Get(UserKey))
+}
+
+// EchoSessionMiddleware adalah middleware autentikasi Echo yang menerima sesi
+// cookie maupun bearer token
+func EchoSessionMiddleware(cookieName string, validator SessionValidator, opts ...Option) echo.MiddlewareFunc {
+	return EchoAuthMiddleware(append(opts, WithSession(cookieName, validator))...)
+}
@@ -4,13 +4,42 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/kreasimaju/auth/utils"
 )
 
 // FiberAuthMiddleware adalah middleware autentikasi untuk Fiber
-func FiberAuthMiddleware() fiber.Handler {
+func FiberAuthMiddleware(opts ...Option) fiber.Handler {
+	o := newOptions(opts)
+
 	return func(c *fiber.Ctx) error {
+		// Sesi cookie diperiksa lebih dulu jika middleware mendukung sesi
+		if o.session != nil {
+			if value := c.Cookies(o.sessionCookie); value != "" {
+				if !o.allowsSessionRequest(c.Method(), string(c.Request().Host()), c.Get("Origin"), c.Get("Sec-Fetch-Site")) {
+					return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+						"error": "Cross-origin request rejected",
+					})
+				}
+
+				claims, err := o.session(value)
+				if err != nil {
+					return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+						"error": "Invalid or expired session",
+					})
+				}
+
+				if o.rejectsUnverified(claims) {
+					return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+						"error": "Email address is not verified",
+					})
+				}
+
+				c.Locals(ClaimsKey, claims)
+				c.Locals(UserKey, claims.Map())
+				return c.Next()
+			}
+		}
+
 		// Mendapatkan token dari header
 		auth := c.Get("Authorization")
 		if auth == "" {
@@ -30,7 +59,7 @@ func FiberAuthMiddleware() fiber.Handler {
 		tokenString := parts[1]
 
 		// Validasi token JWT
-		token, err := utils.ValidateJWT(tokenString)
+		token, err := o.validator(tokenString)
 		if err != nil || !token.Valid {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid or expired token",
@@ -38,15 +67,35 @@ func FiberAuthMiddleware() fiber.Handler {
 		}
 
 		// Ambil klaim dari token
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		claims, err := utils.ClaimsFromToken(token)
+		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Could not parse token claims",
 			})
 		}
 
+		// Tolak token yang sudah dicabut
+		revoked, err := o.isRevoked(claims)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not check token revocation",
+			})
+		}
+		if revoked {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token has been revoked",
+			})
+		}
+
+		if o.rejectsUnverified(claims) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Email address is not verified",
+			})
+		}
+
 		// Tetapkan klaim user ke konteks
-		c.Locals("user", claims)
+		c.Locals(ClaimsKey, claims)
+		c.Locals(UserKey, claims.Map())
 
 		return c.Next()
 	}
@@ -56,23 +105,16 @@ func FiberAuthMiddleware() fiber.Handler {
 func FiberRoleMiddleware(roles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Mendapatkan user dari konteks (yang diatur oleh middleware auth)
-		user := c.Locals("user")
-		if user == nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "User not authenticated",
-			})
-		}
-
-		claims, ok := user.(jwt.MapClaims)
+		claims, ok := FiberClaims(c)
 		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid user claims",
+				"error": "User not authenticated",
 			})
 		}
 
 		// Memeriksa peran pengguna
-		userRole, ok := claims["role"].(string)
-		if !ok {
+		userRole := claims.Role
+		if userRole == "" {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "User has no role assigned",
 			})
@@ -90,3 +132,17 @@ func FiberRoleMiddleware(roles ...string) fiber.Handler {
 		})
 	}
 }
+
+// FiberClaims mengambil klaim pengguna yang diatur oleh FiberAuthMiddleware
+func FiberClaims(c *fiber.Ctx) (*utils.Claims, bool) {
+	if claims, ok := claimsFromValue(c.Locals(ClaimsKey)); ok {
+		return claims, true
+	}
+	return claimsFromValue(c.Locals(UserKey))
+}
+
+// FiberSessionMiddleware adalah middleware autentikasi Fiber yang menerima
+// sesi cookie maupun bearer token
+func FiberSessionMiddleware(cookieName string, validator SessionValidator, opts ...Option) fiber.Handler {
+	return FiberAuthMiddleware(append(opts, WithSession(cookieName, validator))...)
+}
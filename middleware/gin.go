@@ -5,13 +5,49 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/kreasimaju/auth/utils"
 )
 
 // GinAuthMiddleware adalah middleware autentikasi untuk Gin
-func GinAuthMiddleware() gin.HandlerFunc {
+func GinAuthMiddleware(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts)
+
 	return func(c *gin.Context) {
+		// Sesi cookie diperiksa lebih dulu jika middleware mendukung sesi
+		if o.session != nil {
+			if value, err := c.Cookie(o.sessionCookie); err == nil && value != "" {
+				if !o.allowsSessionRequest(c.Request.Method, c.Request.Host, c.GetHeader("Origin"), c.GetHeader("Sec-Fetch-Site")) {
+					c.JSON(http.StatusForbidden, gin.H{
+						"error": "Cross-origin request rejected",
+					})
+					c.Abort()
+					return
+				}
+
+				claims, err := o.session(value)
+				if err != nil {
+					c.JSON(http.StatusUnauthorized, gin.H{
+						"error": "Invalid or expired session",
+					})
+					c.Abort()
+					return
+				}
+
+				if o.rejectsUnverified(claims) {
+					c.JSON(http.StatusForbidden, gin.H{
+						"error": "Email address is not verified",
+					})
+					c.Abort()
+					return
+				}
+
+				c.Set(ClaimsKey, claims)
+				c.Set(UserKey, claims.Map())
+				c.Next()
+				return
+			}
+		}
+
 		// Mendapatkan token dari header
 		auth := c.GetHeader("Authorization")
 		if auth == "" {
@@ -35,7 +71,7 @@ func GinAuthMiddleware() gin.HandlerFunc {
 		tokenString := parts[1]
 
 		// Validasi token JWT
-		token, err := utils.ValidateJWT(tokenString)
+		token, err := o.validator(tokenString)
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid or expired token",
@@ -45,8 +81,8 @@ func GinAuthMiddleware() gin.HandlerFunc {
 		}
 
 		// Ambil klaim dari token
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		claims, err := utils.ClaimsFromToken(token)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Could not parse token claims",
 			})
@@ -54,8 +90,34 @@ func GinAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Tolak token yang sudah dicabut
+		revoked, err := o.isRevoked(claims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Could not check token revocation",
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
+		if o.rejectsUnverified(claims) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Email address is not verified",
+			})
+			c.Abort()
+			return
+		}
+
 		// Tetapkan klaim user ke konteks
-		c.Set("user", claims)
+		c.Set(ClaimsKey, claims)
+		c.Set(UserKey, claims.Map())
 
 		c.Next()
 	}
@@ -65,27 +127,18 @@ func GinAuthMiddleware() gin.HandlerFunc {
 func GinRoleMiddleware(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Mendapatkan user dari konteks (yang diatur oleh middleware auth)
-		user, exists := c.Get("user")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "User not authenticated",
-			})
-			c.Abort()
-			return
-		}
-
-		claims, ok := user.(jwt.MapClaims)
+		claims, ok := GinClaims(c)
 		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid user claims",
+				"error": "User not authenticated",
 			})
 			c.Abort()
 			return
 		}
 
 		// Memeriksa peran pengguna
-		userRole, ok := claims["role"].(string)
-		if !ok {
+		userRole := claims.Role
+		if userRole == "" {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "User has no role assigned",
 			})
@@ -107,3 +160,20 @@ func GinRoleMiddleware(roles ...string) gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// GinClaims mengambil klaim pengguna yang diatur oleh GinAuthMiddleware
+func GinClaims(c *gin.Context) (*utils.Claims, bool) {
+	if value, exists := c.Get(ClaimsKey); exists {
+		if claims, ok := claimsFromValue(value); ok {
+			return claims, true
+		}
+	}
+	value, _ := c.Get(UserKey)
+	return claimsFromValue(value)
+}
+
+// GinSessionMiddleware adalah middleware autentikasi Gin yang menerima sesi
+// cookie maupun bearer token
+func GinSessionMiddleware(cookieName string, validator SessionValidator, opts ...Option) gin.HandlerFunc {
+	return GinAuthMiddleware(append(opts, WithSession(cookieName, validator))...)
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/utils"
+)
+
+// Kunci konteks yang diisi oleh middleware autentikasi
+const (
+	// ClaimsKey menyimpan *utils.Claims
+	ClaimsKey = "claims"
+	// UserKey menyimpan jwt.MapClaims untuk kompatibilitas dengan kode lama
+	UserKey = "user"
+)
+
+// TokenValidator memvalidasi string token dan mengembalikan token hasil parse
+type TokenValidator func(tokenString string) (*jwt.Token, error)
+
+// SessionValidator memvalidasi nilai cookie sesi dan mengembalikan klaim pengguna
+type SessionValidator func(sessionToken string) (*utils.Claims, error)
+
+// Option mengatur perilaku middleware autentikasi
+type Option func(*options)
+
+// options berisi pengaturan yang dipakai bersama oleh middleware Echo, Gin dan Fiber
+type options struct {
+	validator   TokenValidator
+	revocations utils.RevocationStore
+
+	// Sesi cookie; nil berarti middleware hanya menerima bearer token
+	session        SessionValidator
+	sessionCookie  string
+	trustedOrigins map[string]bool
+
+	requireVerified bool
+}
+
+// WithValidator mengganti fungsi validasi token yang digunakan middleware.
+// Secara default middleware memakai utils.ValidateJWT.
+func WithValidator(validator TokenValidator) Option {
+	return func(o *options) {
+		if validator != nil {
+			o.validator = validator
+		}
+	}
+}
+
+// WithRevocationStore membuat middleware menolak token yang sudah dicabut,
+// misalnya setelah logout
+func WithRevocationStore(store utils.RevocationStore) Option {
+	return func(o *options) {
+		o.revocations = store
+	}
+}
+
+// WithSession membuat middleware menerima sesi cookie. Jika cookie ada,
+// sesi divalidasi dengan validator; jika tidak, middleware kembali memeriksa
+// header Authorization.
+func WithSession(cookieName string, validator SessionValidator) Option {
+	return func(o *options) {
+		o.session = validator
+		o.sessionCookie = cookieName
+	}
+}
+
+// WithTrustedOrigins mengizinkan origin lain, misalnya frontend di domain
+// berbeda, mengirim permintaan POST, PUT, PATCH atau DELETE dengan cookie
+// sesi. Permintaan lintas origin lainnya yang memakai cookie sesi ditolak
+// untuk mencegah CSRF.
+func WithTrustedOrigins(origins ...string) Option {
+	return func(o *options) {
+		if o.trustedOrigins == nil {
+			o.trustedOrigins = make(map[string]bool)
+		}
+		for _, origin := range origins {
+			o.trustedOrigins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+		}
+	}
+}
+
+// RequireVerified membuat middleware menolak pengguna yang email-nya belum
+// diverifikasi (klaim is_verified bernilai false) dengan status 403
+func RequireVerified() Option {
+	return func(o *options) {
+		o.requireVerified = true
+	}
+}
+
+// rejectsUnverified memeriksa apakah klaim harus ditolak oleh RequireVerified
+func (o *options) rejectsUnverified(claims *utils.Claims) bool {
+	return o.requireVerified && !claims.IsVerified
+}
+
+// allowsSessionRequest memeriksa apakah permintaan yang diautentikasi dengan
+// cookie sesi boleh diproses. Method aman selalu diizinkan. Method lain
+// ditolak jika browser menandainya sebagai lintas origin (Sec-Fetch-Site atau
+// Origin) dan origin-nya tidak ada di WithTrustedOrigins. Permintaan tanpa
+// kedua header tersebut bukan berasal dari browser modern dan diizinkan.
+func (o *options) allowsSessionRequest(method, host, origin, fetchSite string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	if o.trustedOrigins[strings.ToLower(origin)] {
+		return true
+	}
+
+	switch fetchSite {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
+// isRevoked memeriksa apakah klaim token sudah dicabut. Token dianggap
+// tidak dicabut jika middleware tidak memiliki RevocationStore.
+func (o *options) isRevoked(claims *utils.Claims) (bool, error) {
+	if o.revocations == nil {
+		return false, nil
+	}
+	return utils.IsTokenRevoked(o.revocations, claims)
+}
+
+// newOptions membangun pengaturan middleware dari daftar option
+func newOptions(opts []Option) *options {
+	o := &options{
+		validator: utils.ValidateJWT,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// claimsFromValue mengambil Claims dari nilai konteks yang berupa
+// *utils.Claims atau jwt.MapClaims
+func claimsFromValue(value interface{}) (*utils.Claims, bool) {
+	switch claims := value.(type) {
+	case *utils.Claims:
+		return claims, true
+	case jwt.MapClaims:
+		parsed, err := utils.ClaimsFromMap(claims)
+		return parsed, err == nil
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// BackupCode adalah kode cadangan sekali pakai untuk login saat pengguna
+// kehilangan akses ke telepon atau aplikasi authenticator. Hanya hash kode
+// yang disimpan.
+type BackupCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(64);index" json:"-"` // HMAC-SHA256 kode cadangan
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// MFAChallenge adalah tantangan login dua langkah yang diterbitkan setelah
+// password benar. Token disimpan sebagai hash SHA-256.
+type MFAChallenge struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Token     string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	Attempts  int        `gorm:"default:0" json:"attempts"` // jumlah kode salah
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -9,15 +9,19 @@ import (
 // OTPCode model untuk kode OTP
 type OTPCode struct {
 	gorm.Model
-	UserID    uint       `gorm:"index" json:"user_id"`
-	Code      string     `gorm:"type:varchar(10)" json:"code"`
-	Type      string     `gorm:"type:varchar(20)" json:"type"`    // "sms", "email", "whatsapp"
-	Target    string     `gorm:"type:varchar(255)" json:"target"` // email atau nomor telepon
-	Purpose   string     `gorm:"type:varchar(50)" json:"purpose"` // "login", "register", "reset_password", "verify"
+	UserID    uint       `gorm:"index;default:null" json:"user_id"` // NULL untuk OTP registrasi
+	CodeHash  string     `gorm:"type:varchar(64)" json:"-"`         // HMAC-SHA256 kode OTP
+	Type      string     `gorm:"type:varchar(20)" json:"type"`      // "sms", "email", "whatsapp"
+	Target    string     `gorm:"type:varchar(255)" json:"target"`   // email atau nomor telepon
+	Purpose   string     `gorm:"type:varchar(50)" json:"purpose"`   // "login", "register", "reset_password", "verify"
 	ExpiresAt time.Time  `json:"expires_at"`
 	UsedAt    *time.Time `json:"used_at"`
 	Attempts  int        `gorm:"default:0" json:"attempts"` // jumlah percobaan
 	Valid     bool       `gorm:"default:true" json:"valid"`
+
+	// Code adalah kode OTP asli. Hanya terisi pada OTP yang baru dibuat agar
+	// dapat dikirim, dan tidak pernah disimpan ke database.
+	Code string `gorm:"-" json:"-"`
 }
 
 // IsValid memeriksa apakah kode OTP masih valid
@@ -41,14 +45,40 @@ func (o *OTPCode) MarkAsUsed(db *gorm.DB) error {
 	now := time.Now()
 	o.UsedAt = &now
 	o.Valid = false
-	return db.Save(o).Error
+	return db.Model(o).Updates(map[string]interface{}{"used_at": now, "valid": false}).Error
 }
 
-// IncrementAttempts menambah jumlah percobaan dan menginvalidasi jika melebihi batas
+// IncrementAttempts menambah jumlah percobaan dan menginvalidasi jika melebihi batas.
+// Penambahan dilakukan di database agar percobaan paralel tetap terhitung.
 func (o *OTPCode) IncrementAttempts(db *gorm.DB, maxAttempts int) error {
-	o.Attempts++
-	if o.Attempts >= maxAttempts {
-		o.Valid = false
+	err := db.Model(&OTPCode{}).Where("id = ?", o.ID).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		return err
+	}
+
+	err = db.Model(&OTPCode{}).Where("id = ? AND attempts >= ?", o.ID, maxAttempts).
+		UpdateColumn("valid", false).Error
+	if err != nil {
+		return err
 	}
-	return db.Save(o).Error
+
+	return db.Select("attempts", "valid").First(o, o.ID).Error
+}
+
+// OTPRequest mencatat satu pengiriman OTP untuk menghitung cooldown dan kuota
+// per target maupun per IP klien
+type OTPRequest struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Target    string    `gorm:"type:varchar(255);index" json:"target"` // email atau nomor telepon
+	IP        string    `gorm:"type:varchar(45);index" json:"ip"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// OTPQuotaLock adalah baris kunci per target atau IP. Pemeriksaan kuota
+// mengunci baris ini (SELECT ... FOR UPDATE) agar permintaan bersamaan dari
+// beberapa instance aplikasi diproses satu per satu.
+type OTPQuotaLock struct {
+	Scope     string    `gorm:"primarykey;type:varchar(300)" json:"scope"` // "target:<email/telepon>" atau "ip:<alamat>"
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken model untuk refresh token yang disimpan dalam bentuk hash.
+// Setiap token termasuk dalam satu family yang dimulai saat login; rotasi
+// menghasilkan token baru dalam family yang sama.
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	FamilyID  string     `gorm:"type:varchar(64);index" json:"family_id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`    // diisi saat token dirotasi
+	RevokedAt *time.Time `json:"revoked_at"` // diisi saat family dicabut
+}
+
+// IsActive memeriksa apakah refresh token masih dapat digunakan
+func (rt *RefreshToken) IsActive() bool {
+	return rt.UsedAt == nil && rt.RevokedAt == nil && time.Now().Before(rt.ExpiresAt)
+}
+
+// RevokeRefreshTokenFamily mencabut semua refresh token dalam satu family
+func RevokeRefreshTokenFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserRefreshTokens mencabut semua refresh token milik pengguna
+func RevokeUserRefreshTokens(db *gorm.DB, userID uint) error {
+	return db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PendingRegistration menyimpan data registrasi OTP sampai kontak
+// diverifikasi. Setiap kontak hanya memiliki satu registrasi tertunda.
+type PendingRegistration struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Contact   string    `gorm:"type:varchar(255);uniqueIndex" json:"contact"` // email atau nomor telepon E.164
+	Type      string    `gorm:"type:varchar(20)" json:"type"`                 // "sms", "email", "whatsapp"
+	Password  string    `gorm:"type:varchar(255)" json:"-"`                   // hash bcrypt, kosong jika tanpa password
+	FirstName string    `gorm:"type:varchar(100)" json:"first_name"`
+	LastName  string    `gorm:"type:varchar(100)" json:"last_name"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
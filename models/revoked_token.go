@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevokedToken model untuk JWT yang dicabut sebelum waktu kedaluwarsanya
+type RevokedToken struct {
+	gorm.Model
+	JTI       string    `gorm:"type:varchar(64);uniqueIndex" json:"jti"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"` // entri dapat dihapus setelah waktu ini
+}
+
+// UserTokenRevocation model untuk mencabut semua JWT pengguna yang
+// diterbitkan sebelum waktu tertentu ("logout dari semua perangkat")
+type UserTokenRevocation struct {
+	gorm.Model
+	UserID        uint      `gorm:"uniqueIndex" json:"user_id"`
+	RevokedBefore time.Time `json:"revoked_before"`
+}
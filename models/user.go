@@ -9,19 +9,25 @@ import (
 // User model
 type User struct {
 	gorm.Model
-	Email         string         `gorm:"type:varchar(100);uniqueIndex" json:"email"`
-	Password      string         `gorm:"type:varchar(255)" json:"-"`
-	Phone         string         `gorm:"type:varchar(20);index" json:"phone"`
-	FirstName     string         `gorm:"type:varchar(100)" json:"first_name"`
-	LastName      string         `gorm:"type:varchar(100)" json:"last_name"`
-	Role          string         `gorm:"type:varchar(20);default:'user'" json:"role"`
-	IsVerified    bool           `gorm:"default:false" json:"is_verified"`
-	LastLogin     *time.Time     `json:"last_login"`
-	Providers     []UserProvider `json:"providers"`
-	Sessions      []Session      `json:"-"`
-	PasswordReset []Token        `gorm:"polymorphic:Owner;polymorphicValue:password_reset" json:"-"`
-	EmailVerify   []Token        `gorm:"polymorphic:Owner;polymorphicValue:email_verify" json:"-"`
-	OTPCodes      []OTPCode      `json:"-"`
+	Email          string               `gorm:"type:varchar(100);uniqueIndex;default:null" json:"email"` // NULL untuk pengguna yang mendaftar dengan nomor telepon
+	Password       string               `gorm:"type:varchar(255)" json:"-"`
+	Phone          string               `gorm:"type:varchar(20);index" json:"phone"`
+	FirstName      string               `gorm:"type:varchar(100)" json:"first_name"`
+	LastName       string               `gorm:"type:varchar(100)" json:"last_name"`
+	Role           string               `gorm:"type:varchar(20);default:'user'" json:"role"`
+	IsVerified     bool                 `gorm:"default:false" json:"is_verified"`    // memiliki email atau nomor telepon terverifikasi
+	EmailVerified  bool                 `gorm:"default:false" json:"email_verified"` // email yang tersimpan sudah diverifikasi
+	TOTPSecret     string               `gorm:"type:varchar(255)" json:"-"`          // secret TOTP terenkripsi AES-GCM
+	TOTPEnabled    bool                 `gorm:"default:false" json:"totp_enabled"`
+	TOTPLastStep   int64                `gorm:"default:0" json:"-"`                                 // langkah waktu kode TOTP terakhir, mencegah kode dipakai ulang
+	WebAuthnHandle string               `gorm:"type:varchar(64);uniqueIndex;default:null" json:"-"` // user handle acak untuk passkey
+	LastLogin      *time.Time           `json:"last_login"`
+	Providers      []UserProvider       `json:"providers"`
+	Sessions       []Session            `json:"-"`
+	PasswordReset  []Token              `gorm:"polymorphic:Owner;polymorphicValue:password_reset" json:"-"`
+	EmailVerify    []Token              `gorm:"polymorphic:Owner;polymorphicValue:email_verify" json:"-"`
+	OTPCodes       []OTPCode            `json:"-"`
+	Passkeys       []WebAuthnCredential `json:"-"`
 }
 
 // UserProvider model untuk provider autentikasi
@@ -36,14 +42,34 @@ type UserProvider struct {
 	Data         string     `gorm:"type:text" json:"-"` // JSON data dari provider
 }
 
-// Session model untuk sesi pengguna
+// Session model untuk sesi pengguna. Token disimpan sebagai hash SHA-256
+// dari nilai cookie sehingga kebocoran database tidak membocorkan sesi.
 type Session struct {
 	gorm.Model
-	UserID    uint      `gorm:"index" json:"user_id"`
-	Token     string    `gorm:"type:varchar(255);uniqueIndex" json:"token"`
-	UserAgent string    `gorm:"type:varchar(255)" json:"user_agent"`
-	IP        string    `gorm:"type:varchar(45)" json:"ip"`
-	ExpiresAt time.Time `json:"expires_at"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Token      string     `gorm:"type:varchar(255);uniqueIndex" json:"-"`
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IP         string     `gorm:"type:varchar(45)" json:"ip"`
+	ExpiresAt  time.Time  `json:"expires_at"`   // batas absolut
+	LastSeenAt time.Time  `json:"last_seen_at"` // dipakai untuk batas tidak aktif
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// IsActive memeriksa apakah sesi masih berlaku. idleTimeout bernilai nol
+// berarti sesi hanya dibatasi oleh ExpiresAt.
+func (s *Session) IsActive(idleTimeout time.Duration) bool {
+	now := time.Now()
+	if s.RevokedAt != nil || now.After(s.ExpiresAt) {
+		return false
+	}
+	return idleTimeout <= 0 || now.Before(s.LastSeenAt.Add(idleTimeout))
+}
+
+// RevokeUserSessions mencabut semua sesi milik pengguna
+func RevokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
 }
 
 // Token model untuk reset password, verifikasi email, dll
@@ -51,7 +77,7 @@ type Token struct {
 	gorm.Model
 	OwnerID   uint       `gorm:"index" json:"owner_id"`
 	OwnerType string     `gorm:"type:varchar(50)" json:"owner_type"`
-	Token     string     `gorm:"type:varchar(255);uniqueIndex" json:"token"`
+	Token     string     `gorm:"type:varchar(255);uniqueIndex" json:"-"` // hash SHA-256 dari token
 	Type      string     `gorm:"type:varchar(50)" json:"type"`
 	ExpiresAt time.Time  `json:"expires_at"`
 	UsedAt    *time.Time `json:"used_at"`
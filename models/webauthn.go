@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// WebAuthnCredential adalah passkey atau kunci keamanan milik pengguna.
+// Hanya public key yang disimpan; private key tidak pernah meninggalkan
+// authenticator.
+type WebAuthnCredential struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	UserID          uint       `gorm:"index" json:"user_id"`
+	Name            string     `gorm:"type:varchar(100)" json:"name"`
+	CredentialID    string     `gorm:"type:varchar(255);uniqueIndex" json:"credential_id"` // base64url dari credential ID
+	PublicKey       []byte     `json:"-"`                                                  // public key dalam format COSE
+	AttestationType string     `gorm:"type:varchar(50)" json:"attestation_type"`
+	Transports      string     `gorm:"type:varchar(100)" json:"transports"` // dipisahkan koma, misalnya "usb,nfc"
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `gorm:"default:0" json:"sign_count"`
+	CloneWarning    bool       `gorm:"default:false" json:"clone_warning"` // sign count mundur, authenticator mungkin digandakan
+	BackupEligible  bool       `gorm:"default:false" json:"backup_eligible"`
+	BackupState     bool       `gorm:"default:false" json:"backup_state"` // passkey tersinkron ke perangkat lain
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// WebAuthnSession menyimpan data ceremony WebAuthn antara langkah begin dan
+// finish. Token disimpan sebagai hash SHA-256.
+type WebAuthnSession struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Token     string    `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	UserID    uint      `gorm:"index" json:"user_id"`            // 0 untuk login tanpa password
+	Purpose   string    `gorm:"type:varchar(20)" json:"purpose"` // "register", "login" atau "mfa"
+	Data      string    `gorm:"type:text" json:"-"`              // webauthn.SessionData dalam JSON
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/providers"
+	"golang.org/x/oauth2"
+)
+
+// initProviders mendaftarkan provider OAuth yang diaktifkan ke registry;
+// provider yang tidak diaktifkan sudah disaring oleh OAuthProviders.
+// Provider bertipe custom dilewati; implementasinya didaftarkan aplikasi
+// dengan RegisterProvider.
+func (a *Auth) initProviders(cfg config.Providers) error {
+	a.registry = providers.NewRegistry()
+
+	all := cfg.OAuthProviders()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oauthCfg := all[name]
+		if oauthCfg.Type == config.ProviderCustom {
+			continue
+		}
+
+		p, err := providers.New(name, oauthCfg, jwksKeySet)
+		if err != nil {
+			return err
+		}
+		if err := a.registry.Register(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jwksKeySet memverifikasi ID token provider OIDC dengan JWKSVerifier
+// terhadap jwks_uri issuer
+func jwksKeySet(jwksURL string) jwt.Keyfunc {
+	return NewJWKSVerifier(jwksURL).Keyfunc
+}
+
+// RegisterProvider mendaftarkan provider OAuth milik aplikasi, misalnya untuk
+// entri providers.oauth bertipe "custom". Panggil sebelum RegisterRoutes agar
+// rute /auth/{name} dan /auth/{name}/callback ikut dipasang.
+func (a *Auth) RegisterProvider(p providers.Provider) error {
+	if a.registry == nil {
+		a.registry = providers.NewRegistry()
+	}
+	return a.registry.Register(p)
+}
+
+// Provider mengembalikan provider OAuth yang terdaftar dengan nama name
+func (a *Auth) Provider(name string) (providers.Provider, bool) {
+	return a.registry.Get(name)
+}
+
+// OAuthLoginURL mengembalikan URL login untuk provider bernama name. nonce dan
+// verifier (code verifier PKCE) bersifat opsional dan harus diberikan kembali
+// ke HandleOAuthCallback.
+func (a *Auth) OAuthLoginURL(name, state, nonce, verifier string) (string, error) {
+	p, ok := a.registry.Get(name)
+	if !ok {
+		return "", fmt.Errorf("provider %s tidak diaktifkan", name)
+	}
+
+	var opts []oauth2.AuthCodeOption
+	if nonce != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+	if verifier != "" {
+		opts = append(opts, oauth2.S256ChallengeOption(verifier))
+	}
+	return p.AuthURL(state, opts...)
+}
+
+// HandleOAuthCallback menukar code dari callback provider bernama name lalu
+// mencari atau membuat pengguna. Seperti LoginLocal, *MFARequiredError
+// dikembalikan jika pengguna mengaktifkan TOTP atau mendaftarkan passkey.
+func (a *Auth) HandleOAuthCallback(name, code, nonce, verifier string) (*models.User, error) {
+	p, ok := a.registry.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("provider %s tidak diaktifkan", name)
+	}
+
+	var opts []oauth2.AuthCodeOption
+	if verifier != "" {
+		opts = append(opts, oauth2.VerifierOption(verifier))
+	}
+	user, err := providers.Authenticate(providers.WithNonce(context.Background(), nonce), a.DB(), p, code, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Login melalui provider tidak melewati faktor kedua milik pengguna
+	if err := a.requireMFA(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GoogleLoginURL mengembalikan URL untuk login Google
+func (a *Auth) GoogleLoginURL(state string) string {
+	url, _ := a.OAuthLoginURL(config.ProviderGoogle, state, "", "")
+	return url
+}
+
+// HandleGoogleCallback menangani callback dari Google OAuth
+func (a *Auth) HandleGoogleCallback(code string) (*models.User, error) {
+	return a.HandleOAuthCallback(config.ProviderGoogle, code, "", "")
+}
+
+// TwitterLoginURL mengembalikan URL untuk login Twitter. verifier adalah code
+// verifier PKCE yang harus diberikan kembali ke HandleTwitterCallback.
+func (a *Auth) TwitterLoginURL(state, verifier string) string {
+	url, _ := a.OAuthLoginURL(config.ProviderTwitter, state, "", verifier)
+	return url
+}
+
+// HandleTwitterCallback menangani callback dari Twitter OAuth 2.0
+func (a *Auth) HandleTwitterCallback(code, verifier string) (*models.User, error) {
+	return a.HandleOAuthCallback(config.ProviderTwitter, code, "", verifier)
+}
+
+// GitHubLoginURL mengembalikan URL untuk login GitHub
+func (a *Auth) GitHubLoginURL(state string) string {
+	url, _ := a.OAuthLoginURL(config.ProviderGitHub, state, "", "")
+	return url
+}
+
+// HandleGitHubCallback menangani callback dari GitHub OAuth
+func (a *Auth) HandleGitHubCallback(code string) (*models.User, error) {
+	return a.HandleOAuthCallback(config.ProviderGitHub, code, "", "")
+}
+
+// FacebookLoginURL mengembalikan URL untuk login Facebook
+func (a *Auth) FacebookLoginURL(state string) string {
+	url, _ := a.OAuthLoginURL(config.ProviderFacebook, state, "", "")
+	return url
+}
+
+// HandleFacebookCallback menangani callback dari Facebook Login
+func (a *Auth) HandleFacebookCallback(code string) (*models.User, error) {
+	return a.HandleOAuthCallback(config.ProviderFacebook, code, "", "")
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/kreasimaju/auth/utils"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/oauth2"
+)
+
+// oauthStateCookie adalah awalan nama cookie state OAuth; nama provider
+// ditambahkan di belakangnya. Nama provider tidak boleh berisi "_", sehingga
+// akhiran di bawah tidak dapat bertabrakan dengan cookie provider lain.
+const oauthStateCookie = "kreasimaju_oauth_"
+
+// oauthStateExpiresIn adalah waktu maksimal antara redirect ke provider dan
+// callback
+const oauthStateExpiresIn = 10 * time.Minute
+
+// Akhiran nama cookie untuk nilai lain yang disimpan bersama state
+const (
+	oauthVerifierSuffix = "_verifier" // code verifier PKCE
+	oauthNonceSuffix    = "_nonce"    // nonce ID token OpenID Connect
+)
+
+// newOAuthState membuat state acak untuk parameter state OAuth dan
+// menyimpannya di cookie berumur pendek, sehingga callback yang tidak dimulai
+// oleh browser yang sama (CSRF) dapat ditolak
+func (a *Auth) newOAuthState(c echo.Context, provider string) (string, error) {
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	a.setOAuthCookie(c, provider, state)
+	return state, nil
+}
+
+// checkOAuthState mencocokkan parameter state callback dengan cookie dari
+// newOAuthState lalu menghapus cookie tersebut
+func (a *Auth) checkOAuthState(c echo.Context, provider string) bool {
+	stored := a.takeOAuthCookie(c, provider)
+	if stored == "" {
+		return false
+	}
+
+	state := c.QueryParam("state")
+	return subtle.ConstantTimeCompare([]byte(state), []byte(stored)) == 1
+}
+
+// newOAuthVerifier membuat code verifier PKCE dan menyimpannya di cookie
+// dengan umur yang sama seperti state
+func (a *Auth) newOAuthVerifier(c echo.Context, provider string) string {
+	verifier := oauth2.GenerateVerifier()
+	a.setOAuthCookie(c, provider+oauthVerifierSuffix, verifier)
+	return verifier
+}
+
+// takeOAuthVerifier membaca code verifier dari newOAuthVerifier lalu
+// menghapus cookie-nya. String kosong dikembalikan jika cookie tidak ada.
+func (a *Auth) takeOAuthVerifier(c echo.Context, provider string) string {
+	return a.takeOAuthCookie(c, provider+oauthVerifierSuffix)
+}
+
+// newOAuthNonce membuat nonce acak untuk ID token OpenID Connect dan
+// menyimpannya di cookie agar ID token tidak dapat diputar ulang
+func (a *Auth) newOAuthNonce(c echo.Context, provider string) (string, error) {
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	a.setOAuthCookie(c, provider+oauthNonceSuffix, nonce)
+	return nonce, nil
+}
+
+// takeOAuthNonce membaca nonce dari newOAuthNonce lalu menghapus cookie-nya
+func (a *Auth) takeOAuthNonce(c echo.Context, provider string) string {
+	return a.takeOAuthCookie(c, provider+oauthNonceSuffix)
+}
+
+// setOAuthCookie menyimpan nilai di cookie yang berlaku selama oauthStateExpiresIn
+func (a *Auth) setOAuthCookie(c echo.Context, name, value string) {
+	c.SetCookie(a.oauthStateCookie(name, value, time.Now().Add(oauthStateExpiresIn)))
+}
+
+// takeOAuthCookie membaca cookie dari setOAuthCookie lalu menghapusnya
+func (a *Auth) takeOAuthCookie(c echo.Context, name string) string {
+	cookie, err := c.Cookie(oauthStateCookie + name)
+	if err != nil {
+		return ""
+	}
+
+	expired := a.oauthStateCookie(name, "", time.Unix(0, 0))
+	expired.MaxAge = -1
+	c.SetCookie(expired)
+
+	return cookie.Value
+}
+
+// oauthStateCookie membuat cookie state dengan atribut yang sama seperti
+// cookie sesi. SameSite Strict diturunkan menjadi Lax karena callback datang
+// sebagai redirect dari domain provider.
+func (a *Auth) oauthStateCookie(provider, value string, expires time.Time) *http.Cookie {
+	cookie := a.sessionCookie(value, expires)
+	cookie.Name = oauthStateCookie + provider
+	if cookie.SameSite == http.SameSiteStrictMode {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	return cookie
+}
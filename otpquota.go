@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RetryAfterError dikembalikan saat permintaan ditolak karena terlalu sering
+type RetryAfterError struct {
+	RetryAfter time.Duration
+}
+
+// Error mengimplementasikan interface error
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("terlalu sering, coba lagi dalam %d detik", e.retryAfterSeconds())
+}
+
+// retryAfterSeconds membulatkan RetryAfter ke atas dalam detik
+func (e *RetryAfterError) retryAfterSeconds() int64 {
+	return int64((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// otpPruneInterval adalah jarak minimum antar penghapusan catatan
+// pengiriman OTP yang sudah keluar dari jendela kuota terpanjang
+const otpPruneInterval = time.Hour
+
+// ReserveOTPSend memeriksa cooldown dan kuota pengiriman OTP untuk target
+// (email atau nomor telepon) dan IP klien, lalu mencatat pengiriman tersebut.
+// *RetryAfterError dikembalikan jika salah satu batas otp.quota terlampaui.
+// Pemeriksaan dan pencatatan dilakukan secara atomik sehingga permintaan
+// bersamaan untuk target yang sama tidak dapat melewati batas.
+func (a *Auth) ReserveOTPSend(target, ip string) error {
+	quota := a.otpQuota()
+
+	// Mutex mengurutkan permintaan di instance ini; baris OTPQuotaLock
+	// mengurutkan permintaan dari instance lain yang memakai database yang sama
+	a.otpQuotaMu.Lock()
+	defer a.otpQuotaMu.Unlock()
+
+	now := time.Now()
+	a.pruneOTPRequests(now)
+
+	return a.DB().Transaction(func(tx *gorm.DB) error {
+		if err := lockOTPQuota(tx, now, "target:"+target, "ip:"+ip); err != nil {
+			return err
+		}
+
+		var last models.OTPRequest
+		res := tx.Where("target = ?", target).Order("created_at DESC").Limit(1).Find(&last)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			if wait := time.Duration(quota.ResendCooldown)*time.Second - now.Sub(last.CreatedAt); wait > 0 {
+				return &RetryAfterError{RetryAfter: wait}
+			}
+		}
+
+		limits := []struct {
+			column string
+			value  string
+			window time.Duration
+			limit  int
+		}{
+			{"target", target, time.Hour, quota.HourlyLimit},
+			{"target", target, 24 * time.Hour, quota.DailyLimit},
+			{"ip", ip, time.Hour, quota.IPHourlyLimit},
+			{"ip", ip, 24 * time.Hour, quota.IPDailyLimit},
+		}
+		for _, l := range limits {
+			if l.value == "" {
+				continue
+			}
+
+			since := now.Add(-l.window)
+			var count int64
+			if err := tx.Model(&models.OTPRequest{}).
+				Where(l.column+" = ? AND created_at > ?", l.value, since).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count < int64(l.limit) {
+				continue
+			}
+
+			// Kuota kembali tersedia saat pengiriman tertua keluar dari jendela
+			var oldest models.OTPRequest
+			if err := tx.Where(l.column+" = ? AND created_at > ?", l.value, since).
+				Order("created_at ASC").First(&oldest).Error; err != nil {
+				return err
+			}
+			return &RetryAfterError{RetryAfter: oldest.CreatedAt.Add(l.window).Sub(now)}
+		}
+
+		return tx.Create(&models.OTPRequest{Target: target, IP: truncate(ip, 45), CreatedAt: now}).Error
+	})
+}
+
+// lockOTPQuota membuat dan mengunci baris OTPQuotaLock untuk setiap scope.
+// Scope diurutkan agar dua transaksi tidak saling menunggu. SQLite tidak
+// mendukung FOR UPDATE; di sana mutex instance yang mengurutkan permintaan.
+func lockOTPQuota(tx *gorm.DB, now time.Time, scopes ...string) error {
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		if strings.HasSuffix(scope, ":") {
+			continue
+		}
+		scope = truncate(scope, 300)
+
+		lock := models.OTPQuotaLock{Scope: scope, CreatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&lock).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("scope = ?", scope).First(&lock).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneOTPRequests menghapus catatan pengiriman dan baris kunci yang lebih
+// lama dari jendela kuota terpanjang, paling sering sekali per
+// otpPruneInterval. Pemanggil harus memegang otpQuotaMu.
+func (a *Auth) pruneOTPRequests(now time.Time) {
+	if now.Sub(a.otpPrunedAt) < otpPruneInterval {
+		return
+	}
+	a.otpPrunedAt = now
+
+	cutoff := now.Add(-24 * time.Hour)
+	if err := a.DB().Where("created_at <= ?", cutoff).Delete(&models.OTPRequest{}).Error; err != nil {
+		log.Printf("Failed to prune OTP requests: %v", err)
+	}
+	if err := a.DB().Where("created_at <= ?", cutoff).Delete(&models.OTPQuotaLock{}).Error; err != nil {
+		log.Printf("Failed to prune OTP quota locks: %v", err)
+	}
+}
+
+// otpQuota mengembalikan konfigurasi otp.quota dengan nilai default
+func (a *Auth) otpQuota() config.OTPQuota {
+	quota := a.config.OTP.Quota
+	if quota.ResendCooldown <= 0 {
+		quota.ResendCooldown = config.DefaultOTPResendCooldown
+	}
+	if quota.HourlyLimit <= 0 {
+		quota.HourlyLimit = config.DefaultOTPHourlyLimit
+	}
+	if quota.DailyLimit <= 0 {
+		quota.DailyLimit = config.DefaultOTPDailyLimit
+	}
+	if quota.IPHourlyLimit <= 0 {
+		quota.IPHourlyLimit = config.DefaultOTPIPHourlyLimit
+	}
+	if quota.IPDailyLimit <= 0 {
+		quota.IPDailyLimit = config.DefaultOTPIPDailyLimit
+	}
+	return quota
+}
+
+// otpQuotaTarget menormalkan email atau nomor telepon agar penulisan yang
+// berbeda untuk kontak yang sama dihitung dalam kuota yang sama
+func otpQuotaTarget(contact, defaultRegion string) string {
+	contact = strings.TrimSpace(contact)
+	if strings.Contains(contact, "@") {
+		return strings.ToLower(contact)
+	}
+
+	if defaultRegion == "" {
+		defaultRegion = "ID"
+	}
+	if phone, err := utils.FormatPhoneNumber(contact, defaultRegion); err == nil {
+		return phone
+	}
+	return contact
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOTPDelivery dikembalikan saat OTPSender gagal mengirim pesan
+var ErrOTPDelivery = errors.New("gagal mengirim pesan")
+
+// ErrNoOTPSender dikembalikan saat tidak ada OTPSender untuk channel. Error
+// ini membungkus ErrOTPDelivery.
+var ErrNoOTPSender = fmt.Errorf("%w: OTPSender belum didaftarkan", ErrOTPDelivery)
+
+// OTPSender mengirim pesan, seperti kode OTP atau tautan reset password, ke
+// satu channel. Implementasi siap pakai untuk SMTP, Twilio, Vonage dan Zenziva
+// tersedia di package sender.
+type OTPSender interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+// OTPSenderFunc memungkinkan fungsi biasa dipakai sebagai OTPSender
+type OTPSenderFunc func(ctx context.Context, to, message string) error
+
+// Send memanggil f(ctx, to, message)
+func (f OTPSenderFunc) Send(ctx context.Context, to, message string) error {
+	return f(ctx, to, message)
+}
+
+// otpSendTimeout membatasi waktu pengiriman satu pesan
+const otpSendTimeout = 30 * time.Second
+
+// SetOTPSender mendaftarkan pengirim untuk channel "email", "sms" atau
+// "whatsapp". Panggil sebelum mendaftarkan rute.
+//
+//	a.SetOTPSender("sms", &sender.Twilio{AccountSID: sid, AuthToken: token, From: "+15005550006"})
+func (a *Auth) SetOTPSender(channel string, sender OTPSender) {
+	if a.senders == nil {
+		a.senders = make(map[string]OTPSender)
+	}
+	a.senders[channel] = sender
+}
+
+// deliver mengirim pesan ke target melalui OTPSender yang terdaftar untuk
+// channel. Tanpa OTPSender, ErrNoOTPSender dikembalikan; isi pesan tidak
+// pernah ditulis ke log karena memuat kode OTP atau token.
+func (a *Auth) deliver(channel, target, message string) error {
+	switch channel {
+	case "email", "sms", "whatsapp":
+	default:
+		return fmt.Errorf("tipe OTP tidak didukung")
+	}
+
+	sender, ok := a.senders[channel]
+	if !ok {
+		return fmt.Errorf("%w untuk %s", ErrNoOTPSender, channel)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), otpSendTimeout)
+	defer cancel()
+
+	if err := sender.Send(ctx, target, message); err != nil {
+		return fmt.Errorf("%w melalui %s: %w", ErrOTPDelivery, channel, err)
+	}
+	return nil
+}
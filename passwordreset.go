@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"gorm.io/gorm"
+)
+
+// Error yang dikembalikan oleh fungsi reset password
+var (
+	ErrInvalidResetToken = errors.New("token reset password tidak valid atau sudah kedaluwarsa")
+	ErrWeakPassword      = fmt.Errorf("password minimal %d karakter", MinPasswordLength)
+)
+
+// MinPasswordLength adalah panjang minimum password baru
+const MinPasswordLength = 8
+
+// passwordResetType adalah nilai Type dan OwnerType models.Token untuk reset
+// password, sama dengan polymorphicValue relasi User.PasswordReset
+const passwordResetType = "password_reset"
+
+// RequestPasswordReset mengirim instruksi reset password ke pengguna dengan
+// email atau nomor telepon identifier. Jika akun tidak ditemukan, tidak ada
+// yang dikirim dan nil dikembalikan agar keberadaan akun tidak terungkap.
+func (a *Auth) RequestPasswordReset(identifier, defaultRegion string) error {
+	user, channel, target, err := a.findResetTarget(identifier, defaultRegion)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if a.config.PasswordReset.Method == "otp" {
+		otpCode, err := a.GenerateOTP(user.ID, channel, target, passwordResetType)
+		if err != nil {
+			return err
+		}
+		return a.SendOTP(otpCode)
+	}
+
+	expiresIn := a.config.PasswordReset.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = config.DefaultPasswordResetExpiresIn
+	}
+	token, expiresAt, err := a.issueUserToken(user.ID, passwordResetType, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Gunakan tautan berikut untuk mengatur ulang password Anda: %s. Tautan berlaku hingga %s.",
+		tokenLink(a.config.PasswordReset.URL, token), expiresAt.Format(time.RFC1123))
+
+	return a.deliver(channel, target, message)
+}
+
+// ResetPassword mengganti password dengan token dari RequestPasswordReset.
+// Token hanya dapat dipakai sekali dan semua sesi serta token pengguna dicabut.
+func (a *Auth) ResetPassword(token, newPassword string) error {
+	if len(newPassword) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+
+	var userID uint
+	err := a.DB().Transaction(func(tx *gorm.DB) error {
+		stored, err := consumeUserToken(tx, token, passwordResetType)
+		if errors.Is(err, errUserTokenInvalid) {
+			return ErrInvalidResetToken
+		} else if err != nil {
+			return err
+		}
+
+		userID = stored.OwnerID
+		return setPassword(tx, userID, newPassword)
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.LogoutEverywhere(userID)
+}
+
+// ResetPasswordWithOTP mengganti password dengan kode OTP yang dikirim oleh
+// RequestPasswordReset saat password_reset.method bernilai "otp"
+func (a *Auth) ResetPasswordWithOTP(identifier, code, newPassword, defaultRegion string) error {
+	if len(newPassword) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+
+	user, channel, _, err := a.findResetTarget(identifier, defaultRegion)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidResetToken
+	} else if err != nil {
+		return err
+	}
+
+	if valid, err := a.VerifyOTP(user.ID, code, channel, passwordResetType); err != nil || !valid {
+		if errors.Is(err, ErrTooManyOTPAttempts) {
+			return err
+		}
+		return ErrInvalidResetToken
+	}
+
+	if err := setPassword(a.DB(), user.ID, newPassword); err != nil {
+		return err
+	}
+
+	return a.LogoutEverywhere(user.ID)
+}
+
+// findResetTarget mencari pengguna berdasarkan email atau nomor telepon dan
+// menentukan channel serta tujuan pengiriman instruksi reset
+func (a *Auth) findResetTarget(identifier, defaultRegion string) (*models.User, string, string, error) {
+	identifier = strings.TrimSpace(identifier)
+
+	if strings.Contains(identifier, "@") {
+		user, err := a.FindUserByEmail(identifier)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return user, "email", user.Email, nil
+	}
+
+	if defaultRegion == "" {
+		defaultRegion = "ID"
+	}
+	phone, err := utils.FormatPhoneNumber(identifier, defaultRegion)
+	if err != nil {
+		// Nomor yang tidak valid diperlakukan sama seperti akun yang tidak ada
+		return nil, "", "", gorm.ErrRecordNotFound
+	}
+
+	user, err := a.FindUserByPhone(phone)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	channel := "sms"
+	if a.config.OTP.DefaultType == "whatsapp" {
+		channel = "whatsapp"
+	}
+	return user, channel, user.Phone, nil
+}
+
+// setPassword menyimpan hash bcrypt password baru pengguna
+func setPassword(db *gorm.DB, userID uint, password string) error {
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&models.User{}).Where("id = ?", userID).Update("password", hashed).Error
+}
@@ -0,0 +1,125 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kreasimaju/auth/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+// DefaultGitHubAPIURL adalah base URL GitHub REST API
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHub adalah provider OAuth GitHub
+type GitHub struct {
+	oauthConfig *oauth2.Config
+	apiURL      string
+}
+
+// NewGitHub membuat provider GitHub baru dengan konfigurasi yang diberikan.
+// AuthURL, TokenURL dan APIURL dapat diarahkan ke GitHub Enterprise atau
+// server tiruan untuk pengujian.
+func NewGitHub(cfg config.OAuth) *GitHub {
+	endpoint := github.Endpoint
+	if cfg.AuthURL != "" {
+		endpoint.AuthURL = cfg.AuthURL
+	}
+	if cfg.TokenURL != "" {
+		endpoint.TokenURL = cfg.TokenURL
+	}
+
+	oauthConfig := &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.CallbackURL,
+		Scopes:       append([]string{"read:user", "user:email"}, cfg.Scopes...),
+		Endpoint:     endpoint,
+	}
+
+	apiURL := strings.TrimSuffix(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = DefaultGitHubAPIURL
+	}
+
+	return &GitHub{
+		oauthConfig: oauthConfig,
+		apiURL:      apiURL,
+	}
+}
+
+// Name mengembalikan nama provider
+func (g *GitHub) Name() string {
+	return config.ProviderGitHub
+}
+
+// AuthURL mengembalikan URL untuk login melalui GitHub
+func (g *GitHub) AuthURL(state string, opts ...oauth2.AuthCodeOption) (string, error) {
+	return g.oauthConfig.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange menukar authorization code dengan token
+func (g *GitHub) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return g.oauthConfig.Exchange(ctx, code, opts...)
+}
+
+// GitHubUser mewakili respons GET /user dari GitHub API
+type GitHubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// GitHubEmail mewakili satu alamat dari GET /user/emails
+type GitHubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// FetchProfile mengambil profil dan email terverifikasi dari GitHub API
+func (g *GitHub) FetchProfile(ctx context.Context, token *oauth2.Token) (*Profile, error) {
+	var githubUser GitHubUser
+	if err := fetchJSON(ctx, g.apiURL+"/user", token.AccessToken, &githubUser); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %s", err.Error())
+	}
+	var emails []GitHubEmail
+	if err := fetchJSON(ctx, g.apiURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return nil, fmt.Errorf("failed to get user emails: %s", err.Error())
+	}
+
+	profile := &Profile{
+		ID:  strconv.FormatInt(githubUser.ID, 10),
+		Raw: githubUser,
+	}
+	profile.FirstName, profile.LastName = splitName(githubUser.Name)
+	if profile.FirstName == "" {
+		profile.FirstName = githubUser.Login
+	}
+	profile.Email, profile.EmailVerified = primaryGitHubEmail(emails)
+
+	return profile, nil
+}
+
+// primaryGitHubEmail memilih email utama yang terverifikasi, atau email
+// terverifikasi lain jika email utama belum diverifikasi
+func primaryGitHubEmail(emails []GitHubEmail) (string, bool) {
+	var fallback string
+	for _, e := range emails {
+		if !e.Verified {
+			continue
+		}
+		if e.Primary {
+			return e.Email, true
+		}
+		if fallback == "" {
+			fallback = e.Email
+		}
+	}
+	return fallback, fallback != ""
+}
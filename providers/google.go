@@ -2,27 +2,34 @@ package providers
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"time"
+	"strings"
 
 	"github.com/kreasimaju/auth/config"
-	"github.com/kreasimaju/auth/models"
-	"github.com/kreasimaju/auth/utils"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
 
-var (
-	googleOAuthConfig *oauth2.Config
-)
+// DefaultGoogleAPIURL adalah base URL Google API untuk endpoint userinfo
+const DefaultGoogleAPIURL = "https://www.googleapis.com"
+
+// Google adalah provider OAuth Google
+type Google struct {
+	oauthConfig *oauth2.Config
+	apiURL      string
+}
 
-// InitGoogle menginisialisasi provider Google
-func InitGoogle(cfg config.OAuth) {
-	googleOAuthConfig = &oauth2.Config{
+// NewGoogle membuat provider Google baru dengan konfigurasi yang diberikan
+func NewGoogle(cfg config.OAuth) *Google {
+	endpoint := google.Endpoint
+	if cfg.AuthURL != "" {
+		endpoint.AuthURL = cfg.AuthURL
+	}
+	if cfg.TokenURL != "" {
+		endpoint.TokenURL = cfg.TokenURL
+	}
+
+	oauthConfig := &oauth2.Config{
 		ClientID:     cfg.ClientID,
 		ClientSecret: cfg.ClientSecret,
 		RedirectURL:  cfg.CallbackURL,
@@ -30,18 +37,44 @@ func InitGoogle(cfg config.OAuth) {
 			"https://www.googleapis.com/auth/userinfo.email",
 			"https://www.googleapis.com/auth/userinfo.profile",
 		},
-		Endpoint: google.Endpoint,
+		Endpoint: endpoint,
 	}
 
 	// Tambahkan scopes tambahan jika disediakan
 	if len(cfg.Scopes) > 0 {
-		googleOAuthConfig.Scopes = append(googleOAuthConfig.Scopes, cfg.Scopes...)
+		oauthConfig.Scopes = append(oauthConfig.Scopes, cfg.Scopes...)
+	}
+
+	apiURL := strings.TrimSuffix(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = DefaultGoogleAPIURL
+	}
+
+	return &Google{
+		oauthConfig: oauthConfig,
+		apiURL:      apiURL,
 	}
 }
 
-// GoogleLoginURL mengembalikan URL untuk login melalui Google
-func GoogleLoginURL(state string) string {
-	return googleOAuthConfig.AuthCodeURL(state)
+// Name mengembalikan nama provider
+func (g *Google) Name() string {
+	return config.ProviderGoogle
+}
+
+// AuthURL mengembalikan URL untuk login melalui Google
+func (g *Google) AuthURL(state string, opts ...oauth2.AuthCodeOption) (string, error) {
+	return g.oauthConfig.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange menukar authorization code dengan token
+func (g *Google) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return g.oauthConfig.Exchange(ctx, code, opts...)
+}
+
+// LoginURL mengembalikan URL untuk login melalui Google
+func (g *Google) LoginURL(state string) string {
+	url, _ := g.AuthURL(state)
+	return url
 }
 
 // GoogleUser mewakili respons dari Google API
@@ -56,167 +89,24 @@ type GoogleUser struct {
 	Locale        string `json:"locale"`
 }
 
-// HandleGoogleCallback menangani callback dari Google OAuth
-func HandleGoogleCallback(code string) (*models.User, error) {
-	// Exchange code untuk token
-	token, err := googleOAuthConfig.Exchange(context.Background(), code)
-	if err != nil {
-		return nil, fmt.Errorf("code exchange failed: %s", err.Error())
-	}
-
-	// Ambil data pengguna dari Google
-	googleUser, err := getGoogleUserInfo(token.AccessToken)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %s", err.Error())
-	}
-
-	// Cari pengguna di database atau buat baru
-	user, err := findOrCreateGoogleUser(googleUser, token)
-	if err != nil {
-		return nil, err
-	}
-
-	return user, nil
-}
-
-// getGoogleUserInfo mengambil informasi pengguna dari Google API
-func getGoogleUserInfo(accessToken string) (*GoogleUser, error) {
-	// Buat request ke Google API
-	resp, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + accessToken)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Periksa apakah respons berhasil
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get user info: status code %d", resp.StatusCode)
-	}
-
-	// Baca dan parse respons
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
+// FetchProfile mengambil data pengguna dari endpoint userinfo Google
+func (g *Google) FetchProfile(ctx context.Context, token *oauth2.Token) (*Profile, error) {
 	var googleUser GoogleUser
-	if err := json.Unmarshal(data, &googleUser); err != nil {
-		return nil, err
-	}
-
-	return &googleUser, nil
-}
-
-// findOrCreateGoogleUser mencari atau membuat pengguna berdasarkan data Google
-func findOrCreateGoogleUser(googleUser *GoogleUser, token *oauth2.Token) (*models.User, error) {
-	db := utils.DB
-	if db == nil {
-		return nil, errors.New("database connection not initialized")
-	}
-
-	// Cari provider user
-	var provider models.UserProvider
-	err := db.Where("provider_name = ? AND provider_id = ?", "google", googleUser.ID).First(&provider).Error
-
-	// Jika provider ditemukan, ambil user dari database
-	if err == nil {
-		var user models.User
-		if err := db.First(&user, provider.UserID).Error; err != nil {
-			return nil, err
-		}
-
-		// Update token
-		provider.AccessToken = token.AccessToken
-		provider.RefreshToken = token.RefreshToken
-		expiresAt := time.Now().Add(time.Duration(token.Expiry.Unix()-time.Now().Unix()) * time.Second)
-		provider.ExpiresAt = &expiresAt
-
-		db.Save(&provider)
-
-		// Update last login
-		now := time.Now()
-		user.LastLogin = &now
-		db.Save(&user)
-
-		return &user, nil
-	}
-
-	// Jika provider tidak ditemukan, periksa apakah email sudah terdaftar
-	var existingUser models.User
-	if err := db.Where("email = ?", googleUser.Email).First(&existingUser).Error; err == nil {
-		// Pengguna sudah ada, tambahkan provider baru
-		newProvider := models.UserProvider{
-			UserID:       existingUser.ID,
-			ProviderName: "google",
-			ProviderID:   googleUser.ID,
-			AccessToken:  token.AccessToken,
-			RefreshToken: token.RefreshToken,
-		}
-
-		if token.Expiry.Unix() > 0 {
-			expiresAt := time.Now().Add(time.Duration(token.Expiry.Unix()-time.Now().Unix()) * time.Second)
-			newProvider.ExpiresAt = &expiresAt
-		}
-
-		if err := db.Create(&newProvider).Error; err != nil {
-			return nil, err
-		}
-
-		// Update last login
-		now := time.Now()
-		existingUser.LastLogin = &now
-		db.Save(&existingUser)
-
-		return &existingUser, nil
-	}
-
-	// Buat pengguna baru
-	newUser := models.User{
-		Email:      googleUser.Email,
-		FirstName:  googleUser.GivenName,
-		LastName:   googleUser.FamilyName,
-		IsVerified: googleUser.VerifiedEmail,
-		Role:       "user", // Default role
-	}
-
-	// Mulai transaction
-	tx := db.Begin()
-
-	if err := tx.Create(&newUser).Error; err != nil {
-		tx.Rollback()
-		return nil, err
+	if err := fetchJSON(ctx, g.apiURL+"/oauth2/v2/userinfo", token.AccessToken, &googleUser); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %s", err.Error())
 	}
 
-	// Tambahkan provider baru
-	newProvider := models.UserProvider{
-		UserID:       newUser.ID,
-		ProviderName: "google",
-		ProviderID:   googleUser.ID,
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
+	profile := &Profile{
+		ID:            googleUser.ID,
+		Email:         googleUser.Email,
+		EmailVerified: googleUser.VerifiedEmail,
+		FirstName:     googleUser.GivenName,
+		LastName:      googleUser.FamilyName,
+		Raw:           googleUser,
 	}
-
-	if token.Expiry.Unix() > 0 {
-		expiresAt := time.Now().Add(time.Duration(token.Expiry.Unix()-time.Now().Unix()) * time.Second)
-		newProvider.ExpiresAt = &expiresAt
+	if profile.FirstName == "" && profile.LastName == "" {
+		profile.FirstName, profile.LastName = splitName(googleUser.Name)
 	}
 
-	// Simpan data tambahan sebagai JSON
-	providerData, _ := json.Marshal(googleUser)
-	newProvider.Data = string(providerData)
-
-	if err := tx.Create(&newProvider).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
-
-	// Commit transaction
-	tx.Commit()
-
-	// Update last login
-	now := time.Now()
-	newUser.LastLogin = &now
-	db.Save(&newUser)
-
-	return &newUser, nil
+	return profile, nil
 }
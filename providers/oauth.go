@@ -0,0 +1,174 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kreasimaju/auth/models"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// ErrEmailConflict dikembalikan saat email dari provider sudah dipakai
+// pengguna yang belum memverifikasi emailnya. Akun tersebut tidak dihubungkan
+// otomatis karena pemilik email belum terbukti.
+var ErrEmailConflict = errors.New("email is already registered to an unverified account")
+
+// Profile adalah data pengguna dari provider OAuth yang dipakai untuk mencari
+// atau membuat models.User
+type Profile struct {
+	ID            string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	Raw           interface{} // respons asli provider, disimpan di UserProvider.Data
+}
+
+// findOrCreateOAuthUser mencari pengguna yang sudah terhubung dengan akun
+// provider, menghubungkan pengguna dengan email terverifikasi yang sama, atau
+// membuat pengguna baru. Email yang belum diverifikasi provider tidak dipakai,
+// dan pengguna yang belum memverifikasi emailnya tidak dihubungkan
+// (ErrEmailConflict), agar akun orang lain tidak dapat diambil alih.
+func findOrCreateOAuthUser(db *gorm.DB, providerName string, profile *Profile, token *oauth2.Token) (*models.User, error) {
+	if db == nil {
+		return nil, errors.New("database connection not initialized")
+	}
+
+	data, _ := json.Marshal(profile.Raw)
+	link := models.UserProvider{
+		ProviderName: providerName,
+		ProviderID:   profile.ID,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		Data:         string(data),
+	}
+	if !token.Expiry.IsZero() {
+		expiresAt := token.Expiry
+		link.ExpiresAt = &expiresAt
+	}
+
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Akun provider yang sudah terhubung cukup diperbarui tokennya
+		var existing models.UserProvider
+		err := tx.Where("provider_name = ? AND provider_id = ?", providerName, profile.ID).First(&existing).Error
+		if err == nil {
+			if err := tx.First(&user, existing.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&existing).Updates(map[string]interface{}{
+				"access_token":  link.AccessToken,
+				"refresh_token": link.RefreshToken,
+				"expires_at":    link.ExpiresAt,
+				"data":          link.Data,
+			}).Error
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		email := ""
+		if profile.EmailVerified {
+			email = strings.TrimSpace(profile.Email)
+		}
+
+		err = gorm.ErrRecordNotFound
+		if email != "" {
+			err = tx.Where("email = ?", email).First(&user).Error
+			if err == nil && !user.EmailVerified {
+				return ErrEmailConflict
+			}
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user = models.User{
+				Email:         email,
+				FirstName:     profile.FirstName,
+				LastName:      profile.LastName,
+				IsVerified:    email != "",
+				EmailVerified: email != "",
+				Role:          "user",
+			}
+			err = tx.Create(&user).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		link.UserID = user.ID
+		return tx.Create(&link).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Update last login
+	now := time.Now()
+	user.LastLogin = &now
+	db.Model(&user).Update("last_login", now)
+
+	return &user, nil
+}
+
+// httpTimeout membatasi setiap permintaan ke provider agar endpoint yang
+// menggantung tidak menahan goroutine permintaan login
+const httpTimeout = 10 * time.Second
+
+// httpClient dipakai untuk semua permintaan ke provider, kecuali context
+// membawa http.Client lain melalui oauth2.HTTPClient
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// withHTTPClient memasang httpClient pada ctx untuk penukaran code oleh
+// golang.org/x/oauth2, kecuali pemanggil sudah memasang client sendiri
+func withHTTPClient(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+}
+
+// clientFromContext mengembalikan http.Client dari withHTTPClient
+func clientFromContext(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		return client
+	}
+	return httpClient
+}
+
+// fetchJSON mengirim GET dengan access token sebagai bearer dan membaca
+// respons JSON ke v. Access token kosong berarti permintaan tanpa kredensial.
+func fetchJSON(ctx context.Context, url, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := clientFromContext(ctx).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status code %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// splitName memecah nama lengkap menjadi nama depan dan nama belakang
+func splitName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if i := strings.IndexByte(name, ' '); i >= 0 {
+		return name[:i], strings.TrimSpace(name[i+1:])
+	}
+	return name, ""
+}
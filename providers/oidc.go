@@ -0,0 +1,312 @@
+package providers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/config"
+	"golang.org/x/oauth2"
+)
+
+// oidcDiscoveryPath adalah lokasi dokumen discovery relatif terhadap issuer
+const oidcDiscoveryPath = "/.well-known/openid-configuration"
+
+// oidcLeeway adalah toleransi selisih jam saat memeriksa exp dan iat ID token
+const oidcLeeway = time.Minute
+
+// oidcDiscoveryTimeout membatasi pembacaan dokumen discovery oleh AuthURL,
+// yang tidak menerima context dari pemanggil
+const oidcDiscoveryTimeout = 10 * time.Second
+
+// OIDCKeySet membuat jwt.Keyfunc untuk URL JWKS milik issuer, misalnya
+// dengan auth.NewJWKSVerifier(url).Keyfunc
+type OIDCKeySet func(jwksURL string) jwt.Keyfunc
+
+// OIDCDiscovery adalah bagian dokumen /.well-known/openid-configuration yang
+// dipakai oleh provider
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCClaims adalah klaim standar OpenID Connect dari ID token dan endpoint
+// userinfo
+type OIDCClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string      `json:"nonce,omitempty"`
+	AuthorizedParty   string      `json:"azp,omitempty"`
+	Email             string      `json:"email,omitempty"`
+	EmailVerified     interface{} `json:"email_verified,omitempty"` // bool, atau string "true" pada sebagian IdP
+	Name              string      `json:"name,omitempty"`
+	GivenName         string      `json:"given_name,omitempty"`
+	FamilyName        string      `json:"family_name,omitempty"`
+	PreferredUsername string      `json:"preferred_username,omitempty"`
+	Picture           string      `json:"picture,omitempty"`
+}
+
+// OIDC adalah provider OpenID Connect generik yang dikonfigurasi dengan URL
+// issuer. Endpoint dan JWKS dibaca dari dokumen discovery saat pertama kali
+// dibutuhkan, sehingga satu implementasi dapat dipakai untuk Keycloak, Okta,
+// Azure AD, Auth0 dan IdP lain.
+type OIDC struct {
+	name   string
+	cfg    config.OAuth
+	keySet OIDCKeySet
+
+	mu          sync.Mutex
+	discovery   *OIDCDiscovery
+	oauthConfig *oauth2.Config
+	keyfunc     jwt.Keyfunc
+	inflight    *oidcCall
+}
+
+// oidcCall adalah pembacaan dokumen discovery yang sedang berjalan
+type oidcCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewOIDC membuat provider OIDC bernama name. Nama ini disimpan sebagai
+// UserProvider.ProviderName sehingga beberapa IdP dapat dipakai bersamaan.
+// AuthURL dan TokenURL, jika diisi, menimpa endpoint dari discovery.
+func NewOIDC(name string, cfg config.OAuth, keySet OIDCKeySet) *OIDC {
+	return &OIDC{
+		name:   name,
+		cfg:    cfg,
+		keySet: keySet,
+	}
+}
+
+// Name mengembalikan nama provider
+func (o *OIDC) Name() string {
+	return o.name
+}
+
+// AuthURL mengembalikan URL otorisasi dari dokumen discovery. opts sebaiknya
+// berisi parameter nonce yang sama dengan nonce pada context FetchProfile.
+func (o *OIDC) AuthURL(state string, opts ...oauth2.AuthCodeOption) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcDiscoveryTimeout)
+	defer cancel()
+
+	_, oauthConfig, _, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauthConfig.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange menukar authorization code dengan token di token_endpoint issuer
+func (o *OIDC) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	_, oauthConfig, _, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return oauthConfig.Exchange(ctx, code, opts...)
+}
+
+// FetchProfile memverifikasi ID token dari token (signature, iss, aud, exp
+// dan nonce dari NonceFromContext) lalu memetakan klaimnya ke Profile.
+// Profile.EmailVerified hanya bernilai true jika cfg.TrustEmail aktif.
+func (o *OIDC) FetchProfile(ctx context.Context, token *oauth2.Token) (*Profile, error) {
+	discovery, _, keyfunc, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, errors.New("token response does not contain id_token")
+	}
+	claims, err := o.verifyIDToken(rawIDToken, discovery.Issuer, NonceFromContext(ctx), keyfunc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %s", err.Error())
+	}
+
+	// Sebagian IdP hanya mengirim klaim profil melalui userinfo
+	if discovery.UserinfoEndpoint != "" {
+		var info OIDCClaims
+		if err := fetchJSON(ctx, discovery.UserinfoEndpoint, token.AccessToken, &info); err != nil {
+			return nil, fmt.Errorf("failed to get user info: %s", err.Error())
+		}
+		if info.Subject != claims.Subject {
+			return nil, errors.New("userinfo subject does not match id_token")
+		}
+		mergeOIDCClaims(claims, &info)
+	}
+
+	// Klaim email_verified hanya dipercaya untuk IdP dengan trust_email, agar
+	// IdP yang dikelola pihak lain tidak dapat mengambil alih akun lewat email
+	profile := &Profile{
+		ID:            claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: o.cfg.TrustEmail && claims.emailVerified(),
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+		Raw:           claims,
+	}
+	if profile.FirstName == "" && profile.LastName == "" {
+		profile.FirstName, profile.LastName = splitName(claims.Name)
+	}
+	if profile.FirstName == "" {
+		profile.FirstName = claims.PreferredUsername
+	}
+
+	return profile, nil
+}
+
+// verifyIDToken memeriksa signature ID token terhadap JWKS issuer beserta
+// klaim iss, aud, exp dan nonce
+func (o *OIDC) verifyIDToken(rawIDToken, issuer, nonce string, keyfunc jwt.Keyfunc) (*OIDCClaims, error) {
+	claims := &OIDCClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, keyfunc,
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(o.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcLeeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("klaim sub wajib ada")
+	}
+	// Token untuk beberapa audience harus diterbitkan untuk client ini
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != o.cfg.ClientID {
+		return nil, errors.New("klaim azp tidak cocok")
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("klaim nonce tidak cocok")
+	}
+
+	return claims, nil
+}
+
+// discover membaca dokumen discovery issuer satu kali dan menyimpan hasilnya.
+// Permintaan HTTP dilakukan tanpa memegang o.mu, dan pemanggil yang datang
+// bersamaan menunggu pembacaan yang sama. Kegagalan tidak disimpan sehingga
+// permintaan berikutnya mencoba lagi.
+func (o *OIDC) discover(ctx context.Context) (*OIDCDiscovery, *oauth2.Config, jwt.Keyfunc, error) {
+	o.mu.Lock()
+	if o.discovery != nil {
+		defer o.mu.Unlock()
+		return o.discovery, o.oauthConfig, o.keyfunc, nil
+	}
+	if call := o.inflight; call != nil {
+		o.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, nil, nil, fmt.Errorf("OIDC discovery failed: %s", ctx.Err().Error())
+		}
+		if call.err != nil {
+			return nil, nil, nil, call.err
+		}
+		return o.discover(ctx)
+	}
+	call := &oidcCall{done: make(chan struct{})}
+	o.inflight = call
+	o.mu.Unlock()
+
+	discovery, oauthConfig, err := o.fetchDiscovery(ctx)
+	var keyfunc jwt.Keyfunc
+	if err == nil {
+		keyfunc = o.keySet(discovery.JWKSURI)
+	}
+
+	o.mu.Lock()
+	if err == nil {
+		o.discovery = discovery
+		o.oauthConfig = oauthConfig
+		o.keyfunc = keyfunc
+	}
+	o.inflight = nil
+	o.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return discovery, oauthConfig, keyfunc, nil
+}
+
+// fetchDiscovery membaca dan memeriksa dokumen discovery issuer lalu
+// membentuk konfigurasi OAuth dari endpoint-nya
+func (o *OIDC) fetchDiscovery(ctx context.Context) (*OIDCDiscovery, *oauth2.Config, error) {
+	issuer := strings.TrimSuffix(o.cfg.Issuer, "/")
+	var discovery OIDCDiscovery
+	if err := fetchJSON(ctx, issuer+oidcDiscoveryPath, "", &discovery); err != nil {
+		return nil, nil, fmt.Errorf("OIDC discovery failed: %s", err.Error())
+	}
+
+	// Issuer pada dokumen harus sama persis dengan issuer yang dikonfigurasi
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, nil, fmt.Errorf("OIDC discovery failed: issuer %q does not match %q", discovery.Issuer, o.cfg.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, nil, errors.New("OIDC discovery failed: missing authorization_endpoint, token_endpoint or jwks_uri")
+	}
+	if o.keySet == nil {
+		return nil, nil, errors.New("OIDC key set not configured")
+	}
+
+	endpoint := oauth2.Endpoint{
+		AuthURL:  discovery.AuthorizationEndpoint,
+		TokenURL: discovery.TokenEndpoint,
+	}
+	if o.cfg.AuthURL != "" {
+		endpoint.AuthURL = o.cfg.AuthURL
+	}
+	if o.cfg.TokenURL != "" {
+		endpoint.TokenURL = o.cfg.TokenURL
+	}
+
+	oauthConfig := &oauth2.Config{
+		ClientID:     o.cfg.ClientID,
+		ClientSecret: o.cfg.ClientSecret,
+		RedirectURL:  o.cfg.CallbackURL,
+		Scopes:       append([]string{"openid", "email", "profile"}, o.cfg.Scopes...),
+		Endpoint:     endpoint,
+	}
+	return &discovery, oauthConfig, nil
+}
+
+// emailVerified membaca klaim email_verified yang dapat berupa bool atau string
+func (c *OIDCClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// mergeOIDCClaims mengisi klaim profil yang kosong pada dst dari src
+func mergeOIDCClaims(dst, src *OIDCClaims) {
+	fill := func(d *string, s string) {
+		if *d == "" {
+			*d = s
+		}
+	}
+	if dst.Email == "" {
+		dst.Email = src.Email
+		dst.EmailVerified = src.EmailVerified
+	}
+	fill(&dst.Name, src.Name)
+	fill(&dst.GivenName, src.GivenName)
+	fill(&dst.FamilyName, src.FamilyName)
+	fill(&dst.PreferredUsername, src.PreferredUsername)
+	fill(&dst.Picture, src.Picture)
+}
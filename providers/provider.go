@@ -0,0 +1,167 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// Provider adalah provider login OAuth 2.0. Provider bawaan (Google, GitHub,
+// Facebook, Twitter dan OIDC) mengimplementasikan interface ini, dan aplikasi
+// dapat mendaftarkan provider sendiri dengan Auth.RegisterProvider.
+type Provider interface {
+	// Name adalah nama unik provider; dipakai pada rute /auth/{name} dan
+	// sebagai UserProvider.ProviderName
+	Name() string
+
+	// AuthURL mengembalikan URL otorisasi. opts dapat berisi code challenge
+	// PKCE dan parameter nonce.
+	AuthURL(state string, opts ...oauth2.AuthCodeOption) (string, error)
+
+	// Exchange menukar authorization code dengan token
+	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error)
+
+	// FetchProfile mengambil profil pengguna dengan token dari Exchange
+	FetchProfile(ctx context.Context, token *oauth2.Token) (*Profile, error)
+}
+
+// Authenticate menjalankan callback OAuth untuk provider: menukar code,
+// mengambil profil lalu mencari atau membuat pengguna. Permintaan ke provider
+// memakai http.Client dengan batas waktu, atau client dari oauth2.HTTPClient
+// pada ctx jika ada.
+func Authenticate(ctx context.Context, db *gorm.DB, p Provider, code string, opts ...oauth2.AuthCodeOption) (*models.User, error) {
+	ctx = withHTTPClient(ctx)
+	token, err := p.Exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %s", err.Error())
+	}
+
+	profile, err := p.FetchProfile(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if profile.ID == "" {
+		return nil, errors.New("failed to get user info: empty user id")
+	}
+
+	return findOrCreateOAuthUser(db, p.Name(), profile, token)
+}
+
+// nonceKey adalah kunci context untuk nonce OpenID Connect
+type nonceKey struct{}
+
+// WithNonce menyimpan nonce yang dikirim pada AuthURL agar dapat dicocokkan
+// dengan ID token oleh FetchProfile
+func WithNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, nonceKey{}, nonce)
+}
+
+// NonceFromContext mengembalikan nonce dari WithNonce, atau string kosong
+func NonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
+
+// New membuat provider bawaan bernama name sesuai config.OAuth.ProviderType.
+// keySet dipakai untuk memverifikasi ID token provider OIDC.
+func New(name string, cfg config.OAuth, keySet OIDCKeySet) (Provider, error) {
+	var p Provider
+	switch typ := cfg.ProviderType(name); typ {
+	case config.ProviderGoogle:
+		p = NewGoogle(cfg)
+	case config.ProviderGitHub:
+		p = NewGitHub(cfg)
+	case config.ProviderFacebook:
+		p = NewFacebook(cfg)
+	case config.ProviderTwitter:
+		p = NewTwitter(cfg)
+	case config.ProviderOIDC:
+		return NewOIDC(name, cfg, keySet), nil
+	default:
+		return nil, fmt.Errorf("tipe provider %q tidak dikenal", typ)
+	}
+
+	// Provider bawaan dapat didaftarkan dengan nama lain, misalnya GitHub
+	// Enterprise sebagai "github-enterprise" di samping "github"
+	if p.Name() != name {
+		p = renamed{Provider: p, name: name}
+	}
+	return p, nil
+}
+
+// renamed membungkus provider bawaan dengan nama lain
+type renamed struct {
+	Provider
+	name string
+}
+
+// Name mengembalikan nama pengganti
+func (r renamed) Name() string {
+	return r.name
+}
+
+// Registry menyimpan provider berdasarkan nama dengan urutan pendaftaran.
+// Method baca aman dipanggil pada Registry nil.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+	names     []string
+}
+
+// NewRegistry membuat Registry kosong
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register menambahkan provider. Nama yang sudah terdaftar atau tidak sesuai
+// config.ValidProviderName ditolak.
+func (r *Registry) Register(p Provider) error {
+	name := p.Name()
+	if name == "" {
+		return errors.New("nama provider wajib diisi")
+	}
+	if !config.ValidProviderName(name) {
+		return fmt.Errorf("nama provider %q hanya boleh berisi huruf kecil, angka dan -", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[name]; exists {
+		return fmt.Errorf("provider %q sudah terdaftar", name)
+	}
+	r.providers[name] = p
+	r.names = append(r.names, name)
+	return nil
+}
+
+// Get mengembalikan provider dengan nama yang diberikan
+func (r *Registry) Get(name string) (Provider, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names mengembalikan nama semua provider sesuai urutan pendaftaran
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string(nil), r.names...)
+}
@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kreasimaju/auth/config"
+	"golang.org/x/oauth2"
+)
+
+// Endpoint default OAuth 2.0 dan API v2 Twitter/X
+const (
+	DefaultTwitterAuthURL  = "https://twitter.com/i/oauth2/authorize"
+	DefaultTwitterTokenURL = "https://api.twitter.com/2/oauth2/token"
+	DefaultTwitterAPIURL   = "https://api.twitter.com"
+)
+
+// twitterUserFields adalah field tambahan yang diminta dari /2/users/me
+const twitterUserFields = "name,username,profile_image_url"
+
+// Twitter adalah provider OAuth 2.0 Twitter/X. Twitter mewajibkan PKCE dan
+// tidak mengembalikan email, sehingga pengguna dikenali dari ID akun Twitter
+// saja.
+type Twitter struct {
+	oauthConfig *oauth2.Config
+	apiURL      string
+}
+
+// NewTwitter membuat provider Twitter baru dengan konfigurasi yang diberikan.
+// AuthURL, TokenURL dan APIURL dapat diarahkan ke server tiruan untuk
+// pengujian.
+func NewTwitter(cfg config.OAuth) *Twitter {
+	endpoint := oauth2.Endpoint{
+		AuthURL:  DefaultTwitterAuthURL,
+		TokenURL: DefaultTwitterTokenURL,
+		// Client confidential mengirim kredensial dengan Basic auth
+		AuthStyle: oauth2.AuthStyleInHeader,
+	}
+	if cfg.AuthURL != "" {
+		endpoint.AuthURL = cfg.AuthURL
+	}
+	if cfg.TokenURL != "" {
+		endpoint.TokenURL = cfg.TokenURL
+	}
+
+	oauthConfig := &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.CallbackURL,
+		Scopes:       append([]string{"tweet.read", "users.read"}, cfg.Scopes...),
+		Endpoint:     endpoint,
+	}
+
+	apiURL := strings.TrimSuffix(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = DefaultTwitterAPIURL
+	}
+
+	return &Twitter{
+		oauthConfig: oauthConfig,
+		apiURL:      apiURL,
+	}
+}
+
+// Name mengembalikan nama provider
+func (t *Twitter) Name() string {
+	return config.ProviderTwitter
+}
+
+// AuthURL mengembalikan URL untuk login melalui Twitter. opts harus berisi
+// oauth2.S256ChallengeOption karena Twitter mewajibkan PKCE.
+func (t *Twitter) AuthURL(state string, opts ...oauth2.AuthCodeOption) (string, error) {
+	return t.oauthConfig.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange menukar authorization code dengan token. opts harus berisi
+// oauth2.VerifierOption dengan code verifier yang sama seperti pada AuthURL.
+func (t *Twitter) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return t.oauthConfig.Exchange(ctx, code, opts...)
+}
+
+// TwitterUser mewakili field data dari respons GET /2/users/me
+type TwitterUser struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Username        string `json:"username"`
+	ProfileImageURL string `json:"profile_image_url"`
+}
+
+// FetchProfile mengambil profil pengguna dari /2/users/me
+func (t *Twitter) FetchProfile(ctx context.Context, token *oauth2.Token) (*Profile, error) {
+	var resp struct {
+		Data TwitterUser `json:"data"`
+	}
+	if err := fetchJSON(ctx, t.apiURL+"/2/users/me?user.fields="+twitterUserFields, token.AccessToken, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %s", err.Error())
+	}
+
+	// Twitter tidak memberikan email; aplikasi dapat melengkapinya nanti
+	// dengan Auth.SetEmail
+	profile := &Profile{
+		ID:  resp.Data.ID,
+		Raw: resp.Data,
+	}
+	profile.FirstName, profile.LastName = splitName(resp.Data.Name)
+	if profile.FirstName == "" {
+		profile.FirstName = resp.Data.Username
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"gorm.io/gorm"
+)
+
+// Error yang dikembalikan oleh RefreshTokens
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token tidak valid atau sudah kedaluwarsa")
+	ErrRefreshTokenReused  = errors.New("refresh token sudah pernah digunakan, semua token terkait dicabut")
+)
+
+// refreshTokenBytes adalah jumlah byte acak dalam satu refresh token
+const refreshTokenBytes = 32
+
+// TokenPair berisi access token JWT dan refresh token opaque
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // masa berlaku access token dalam detik
+}
+
+// IssueTokens menerbitkan access token dan refresh token untuk pengguna.
+// Setiap pemanggilan memulai family refresh token baru.
+func (a *Auth) IssueTokens(user *models.User) (*TokenPair, error) {
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return a.issueTokens(a.DB(), user, familyID)
+}
+
+// issueTokens menerbitkan pasangan token dalam family yang diberikan
+func (a *Auth) issueTokens(db *gorm.DB, user *models.User, familyID string) (*TokenPair, error) {
+	accessToken, err := a.GenerateToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(time.Duration(a.refreshExpiresIn()) * time.Second),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    a.config.JWT.ExpiresIn,
+	}, nil
+}
+
+// refreshExpiresIn mengembalikan masa berlaku refresh token dalam detik
+func (a *Auth) refreshExpiresIn() int64 {
+	if a.config.JWT.RefreshExpiresIn > 0 {
+		return a.config.JWT.RefreshExpiresIn
+	}
+	return config.DefaultRefreshExpiresIn
+}
+
+// RefreshTokens menukar refresh token dengan pasangan token baru. Refresh
+// token lama langsung dirotasi; jika token yang sudah dirotasi dipakai lagi,
+// seluruh family dicabut dan ErrRefreshTokenReused dikembalikan.
+func (a *Auth) RefreshTokens(refreshToken string) (*TokenPair, error) {
+	db := a.DB()
+
+	var stored models.RefreshToken
+	err := db.Where("token_hash = ?", utils.HashToken(refreshToken)).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Token yang sudah dirotasi dipakai lagi: kemungkinan besar token dicuri
+	if stored.UsedAt != nil {
+		return nil, a.revokeReusedFamily(stored.FamilyID)
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var user models.User
+	if err := db.First(&user, stored.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	var pair *TokenPair
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Kondisi used_at IS NULL memastikan hanya satu permintaan bersamaan yang berhasil
+		res := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", stored.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var err error
+		pair, err = a.issueTokens(tx, &user, stored.FamilyID)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return nil, a.revokeReusedFamily(stored.FamilyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// revokeReusedFamily mencabut family refresh token yang terdeteksi dipakai ulang
+func (a *Auth) revokeReusedFamily(familyID string) error {
+	if err := models.RevokeRefreshTokenFamily(a.DB(), familyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
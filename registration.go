@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"gorm.io/gorm"
+)
+
+// Error yang dikembalikan oleh fungsi registrasi OTP
+var (
+	ErrContactRegistered   = errors.New("kontak sudah terdaftar")
+	ErrInvalidContact      = errors.New("kontak tidak valid untuk tipe OTP ini")
+	ErrInvalidRegistration = errors.New("kode OTP tidak valid atau registrasi sudah kedaluwarsa")
+)
+
+// registerPurpose adalah nilai Purpose models.OTPCode untuk registrasi
+const registerPurpose = "register"
+
+// RequestOTPRegistration menyimpan registrasi tertunda untuk email atau
+// nomor telepon yang belum terdaftar dan mengirim kode OTP ke kontak
+// tersebut. Permintaan baru untuk kontak yang sama menggantikan registrasi
+// sebelumnya. password boleh kosong untuk akun yang hanya masuk dengan OTP.
+func (a *Auth) RequestOTPRegistration(contact, otpType, defaultRegion, password, firstName, lastName string) (*models.OTPCode, error) {
+	contact, err := registrationContact(contact, otpType, defaultRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.ensureContactAvailable(a.DB(), contact, otpType); err != nil {
+		return nil, err
+	}
+
+	var hashedPassword string
+	if password != "" {
+		if len(password) < MinPasswordLength {
+			return nil, ErrWeakPassword
+		}
+		if hashedPassword, err = utils.HashPassword(password); err != nil {
+			return nil, err
+		}
+	}
+
+	expiresIn := a.config.OTP.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = config.DefaultOTPExpiresIn
+	}
+	now := time.Now()
+
+	pending := models.PendingRegistration{
+		Contact:   contact,
+		Type:      otpType,
+		Password:  hashedPassword,
+		FirstName: firstName,
+		LastName:  lastName,
+		ExpiresAt: now.Add(time.Duration(expiresIn) * time.Second),
+	}
+	err = a.DB().Transaction(func(tx *gorm.DB) error {
+		// Registrasi tertunda yang kedaluwarsa ikut dibersihkan
+		if err := tx.Where("contact = ? OR expires_at <= ?", contact, now).
+			Delete(&models.PendingRegistration{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&pending).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	otpCode, err := a.GenerateOTP(0, otpType, contact, registerPurpose)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.SendOTP(otpCode); err != nil {
+		return nil, err
+	}
+
+	return otpCode, nil
+}
+
+// VerifyOTPRegistration memverifikasi kode dari RequestOTPRegistration lalu
+// membuat pengguna dengan IsVerified true dari registrasi tertunda
+func (a *Auth) VerifyOTPRegistration(contact, otpType, code, defaultRegion string) (*models.User, error) {
+	contact, err := registrationContact(contact, otpType, defaultRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := a.otpKey()
+	if err != nil {
+		return nil, err
+	}
+	valid, err := utils.VerifyTargetOTP(a.DB(), key, contact, code, otpType, registerPurpose, a.otpMaxAttempts())
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidRegistration
+	}
+
+	var user models.User
+	err = a.DB().Transaction(func(tx *gorm.DB) error {
+		var pending models.PendingRegistration
+		err := tx.Where("contact = ? AND type = ? AND expires_at > ?", contact, otpType, time.Now()).
+			First(&pending).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRegistration
+		} else if err != nil {
+			return err
+		}
+
+		// Kontak dapat didaftarkan dengan cara lain selama OTP belum diverifikasi
+		if err := a.ensureContactAvailable(tx, contact, otpType); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		user = models.User{
+			Password:      pending.Password,
+			FirstName:     pending.FirstName,
+			LastName:      pending.LastName,
+			Role:          "user",
+			IsVerified:    true,
+			EmailVerified: otpType == "email",
+			LastLogin:     &now,
+		}
+		if otpType == "email" {
+			user.Email = contact
+		} else {
+			user.Phone = contact
+		}
+
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&pending).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// registrationContact menormalkan kontak registrasi: email apa adanya untuk
+// tipe "email", atau nomor telepon E.164 untuk "sms" dan "whatsapp"
+func registrationContact(contact, otpType, defaultRegion string) (string, error) {
+	contact = strings.TrimSpace(contact)
+
+	switch otpType {
+	case "email":
+		if !strings.Contains(contact, "@") {
+			return "", ErrInvalidContact
+		}
+		return contact, nil
+	case "sms", "whatsapp":
+		if defaultRegion == "" {
+			defaultRegion = "ID"
+		}
+		phone, err := utils.FormatPhoneNumber(contact, defaultRegion)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidContact, err)
+		}
+		return phone, nil
+	}
+
+	return "", fmt.Errorf("tipe OTP tidak valid")
+}
+
+// ensureContactAvailable mengembalikan ErrContactRegistered jika kontak
+// sudah dipakai pengguna lain
+func (a *Auth) ensureContactAvailable(db *gorm.DB, contact, otpType string) error {
+	column := "phone"
+	if otpType == "email" {
+		column = "email"
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where(column+" = ?", contact).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrContactRegistered
+	}
+	return nil
+}
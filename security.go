@@ -0,0 +1,46 @@
+package auth
+
+import "github.com/kreasimaju/auth/models"
+
+// SecurityOverview merangkum pengaturan keamanan akun pengguna
+type SecurityOverview struct {
+	IsVerified           bool  `json:"is_verified"`
+	EmailVerified        bool  `json:"email_verified"`
+	TOTPEnabled          bool  `json:"totp_enabled"`
+	BackupCodesRemaining int64 `json:"backup_codes_remaining"`
+	Passkeys             int64 `json:"passkeys"`
+	ActiveSessions       int   `json:"active_sessions"`
+}
+
+// GetSecurityOverview mengembalikan ringkasan keamanan akun pengguna
+func (a *Auth) GetSecurityOverview(userID uint) (*SecurityOverview, error) {
+	var user models.User
+	if err := a.DB().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	remaining, err := a.BackupCodesRemaining(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var passkeys int64
+	if err := a.DB().Model(&models.WebAuthnCredential{}).
+		Where("user_id = ?", userID).Count(&passkeys).Error; err != nil {
+		return nil, err
+	}
+
+	sessions, err := a.ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecurityOverview{
+		IsVerified:           user.IsVerified,
+		EmailVerified:        user.EmailVerified,
+		TOTPEnabled:          user.TOTPEnabled,
+		BackupCodesRemaining: remaining,
+		Passkeys:             passkeys,
+		ActiveSessions:       len(sessions),
+	}, nil
+}
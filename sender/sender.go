@@ -0,0 +1,71 @@
+// Package sender berisi implementasi auth.OTPSender untuk email (SMTP) dan
+// gateway SMS/WhatsApp berbasis HTTP seperti Twilio, Vonage dan Zenziva.
+//
+//	a.SetOTPSender("email", &sender.SMTP{Host: "smtp.example.com", Port: 587, From: "no-reply@example.com"})
+//	a.SetOTPSender("sms", &sender.Zenziva{UserKey: userKey, PassKey: passKey})
+//
+// Setiap sender HTTP memiliki field BaseURL sehingga dapat diarahkan ke
+// httptest.Server saat pengujian.
+package sender
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultClient dipakai oleh sender HTTP yang tidak memiliki Client sendiri
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// maxErrorBody membatasi isi response error yang disertakan di pesan error
+const maxErrorBody = 512
+
+// httpClient mengembalikan client jika diisi, atau defaultClient
+func httpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return defaultClient
+}
+
+// postForm mengirim form ke endpoint dan mengembalikan response. Status
+// selain 2xx dikembalikan sebagai error beserta potongan isi response.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, prepare func(*http.Request)) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if prepare != nil {
+		prepare(req)
+	}
+
+	return do(client, req)
+}
+
+// do menjalankan request dan memeriksa status response
+func do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := httpClient(client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
+
+// withoutPlus menghapus awalan + dari nomor E.164 untuk gateway yang
+// mengharapkan nomor tanpa tanda plus
+func withoutPlus(phone string) string {
+	return strings.TrimPrefix(phone, "+")
+}
@@ -0,0 +1,90 @@
+package sender
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTwilio menguji request ke Twilio Messaging API
+func TestTwilio(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r
+		if r.PostForm.Get("To") == "whatsapp:+620000" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":21211,"message":"Invalid 'To' Phone Number"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM123"}`))
+	}))
+	defer server.Close()
+
+	s := &Twilio{AccountSID: "AC123", AuthToken: "secret", From: "+15005550006", WhatsApp: true, BaseURL: server.URL}
+
+	err := s.Send(context.Background(), "+6281234567890", "Kode OTP Anda adalah: 123456")
+	assert.NoError(t, err)
+	assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", got.URL.Path)
+	assert.Equal(t, "whatsapp:+6281234567890", got.PostForm.Get("To"))
+	assert.Equal(t, "whatsapp:+15005550006", got.PostForm.Get("From"))
+	assert.Equal(t, "Kode OTP Anda adalah: 123456", got.PostForm.Get("Body"))
+	user, pass, _ := got.BasicAuth()
+	assert.Equal(t, "AC123", user)
+	assert.Equal(t, "secret", pass)
+
+	err = s.Send(context.Background(), "+620000", "test")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "status 400")
+}
+
+// TestVonage menguji SMS API Vonage, termasuk status gagal pada HTTP 200
+func TestVonage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		assert.Equal(t, "/sms/json", r.URL.Path)
+		assert.Equal(t, "key", r.PostForm.Get("api_key"))
+		assert.Equal(t, "6281234567890", r.PostForm.Get("to"))
+		if r.PostForm.Get("api_secret") != "secret" {
+			w.Write([]byte(`{"messages":[{"status":"4","error-text":"Bad Credentials"}]}`))
+			return
+		}
+		w.Write([]byte(`{"messages":[{"status":"0"}]}`))
+	}))
+	defer server.Close()
+
+	s := &Vonage{APIKey: "key", APISecret: "secret", From: "Kreasi", BaseURL: server.URL}
+	assert.NoError(t, s.Send(context.Background(), "+6281234567890", "test"))
+
+	s.APISecret = "wrong"
+	err := s.Send(context.Background(), "+6281234567890", "test")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Bad Credentials")
+}
+
+// TestZenziva menguji gateway Zenziva, termasuk status gagal pada HTTP 200
+func TestZenziva(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		assert.Equal(t, "user", r.PostForm.Get("userkey"))
+		assert.Equal(t, "6281234567890", r.PostForm.Get("to"))
+		if r.PostForm.Get("passkey") != "pass" {
+			w.Write([]byte(`{"status":"0","text":"Userkey / Passkey Salah"}`))
+			return
+		}
+		w.Write([]byte(`{"messageId":"1","to":"6281234567890","status":"1","text":"Success"}`))
+	}))
+	defer server.Close()
+
+	s := &Zenziva{UserKey: "user", PassKey: "pass", BaseURL: server.URL}
+	assert.NoError(t, s.Send(context.Background(), "+6281234567890", "test"))
+
+	s.PassKey = "wrong"
+	err := s.Send(context.Background(), "+6281234567890", "test")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Passkey Salah")
+}
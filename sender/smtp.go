@@ -0,0 +1,150 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// DefaultSMTPSubject adalah subjek email jika SMTP.Subject kosong
+const DefaultSMTPSubject = "Kode Verifikasi"
+
+// SMTP mengirim pesan sebagai email teks biasa melalui server SMTP.
+// Koneksi ditingkatkan dengan STARTTLS jika server mendukungnya.
+type SMTP struct {
+	Host        string
+	Port        int // default 587
+	Username    string
+	Password    string
+	From        string // alamat pengirim, misalnya "Aplikasi <no-reply@example.com>"
+	Subject     string // default DefaultSMTPSubject
+	ImplicitTLS bool   // TLS sejak awal koneksi (umumnya port 465) sebagai pengganti STARTTLS
+}
+
+// Send mengirim pesan ke alamat email to
+func (s *SMTP) Send(ctx context.Context, to, message string) error {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("smtp: alamat pengirim tidak valid: %w", err)
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("smtp: alamat tujuan tidak valid: %w", err)
+	}
+
+	msg, err := s.buildMessage(from, rcpt, message)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer client.Close()
+
+	if !s.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+				return fmt.Errorf("smtp: %w", err)
+			}
+		}
+	}
+
+	// smtp.PlainAuth menolak mengirim password melalui koneksi tanpa TLS
+	// kecuali ke localhost
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("smtp: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if err := client.Rcpt(rcpt.Address); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial membuka koneksi ke server SMTP dengan batas waktu dari ctx
+func (s *SMTP) dial(ctx context.Context) (*smtp.Client, error) {
+	port := s.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+
+	var conn net.Conn
+	var err error
+	if s.ImplicitTLS {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: s.Host}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// buildMessage menyusun email teks biasa dengan body quoted-printable
+func (s *SMTP) buildMessage(from, to *mail.Address, message string) ([]byte, error) {
+	subject := s.Subject
+	if subject == "" {
+		subject = DefaultSMTPSubject
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(message)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+
+	return buf.Bytes(), nil
+}
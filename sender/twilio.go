@@ -0,0 +1,52 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DefaultTwilioBaseURL adalah alamat REST API Twilio
+const DefaultTwilioBaseURL = "https://api.twilio.com"
+
+// Twilio mengirim SMS atau pesan WhatsApp melalui Programmable Messaging API
+type Twilio struct {
+	AccountSID string
+	AuthToken  string
+	From       string // nomor pengirim dalam format E.164, misalnya "+15005550006"
+	WhatsApp   bool   // kirim melalui WhatsApp; From harus nomor WhatsApp yang terdaftar di Twilio
+
+	BaseURL string       // default DefaultTwilioBaseURL
+	Client  *http.Client // default client dengan timeout 10 detik
+}
+
+// Send mengirim pesan ke nomor to dalam format E.164
+func (t *Twilio) Send(ctx context.Context, to, message string) error {
+	from := t.From
+	if t.WhatsApp {
+		to = "whatsapp:" + to
+		from = "whatsapp:" + from
+	}
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", from)
+	form.Set("Body", message)
+
+	base := t.BaseURL
+	if base == "" {
+		base = DefaultTwilioBaseURL
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", base, url.PathEscape(t.AccountSID))
+
+	resp, err := postForm(ctx, t.Client, endpoint, form, func(req *http.Request) {
+		req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	})
+	if err != nil {
+		return fmt.Errorf("twilio: %w", err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Alamat API Vonage untuk SMS API dan Messages API (WhatsApp)
+const (
+	DefaultVonageSMSURL      = "https://rest.nexmo.com"
+	DefaultVonageMessagesURL = "https://api.nexmo.com"
+)
+
+// Vonage mengirim SMS melalui SMS API, atau pesan WhatsApp melalui Messages API
+type Vonage struct {
+	APIKey    string
+	APISecret string
+	From      string // nama atau nomor pengirim
+	WhatsApp  bool   // kirim melalui WhatsApp; From harus nomor WhatsApp Business
+
+	BaseURL string       // default DefaultVonageSMSURL atau DefaultVonageMessagesURL
+	Client  *http.Client // default client dengan timeout 10 detik
+}
+
+// vonageSMSResponse adalah isi response SMS API; status "0" berarti berhasil
+type vonageSMSResponse struct {
+	Messages []struct {
+		Status    string `json:"status"`
+		ErrorText string `json:"error-text"`
+	} `json:"messages"`
+}
+
+// Send mengirim pesan ke nomor to dalam format E.164
+func (v *Vonage) Send(ctx context.Context, to, message string) error {
+	if v.WhatsApp {
+		return v.sendWhatsApp(ctx, to, message)
+	}
+
+	form := url.Values{}
+	form.Set("api_key", v.APIKey)
+	form.Set("api_secret", v.APISecret)
+	form.Set("from", v.From)
+	form.Set("to", withoutPlus(to))
+	form.Set("text", message)
+
+	base := v.BaseURL
+	if base == "" {
+		base = DefaultVonageSMSURL
+	}
+
+	resp, err := postForm(ctx, v.Client, base+"/sms/json", form, nil)
+	if err != nil {
+		return fmt.Errorf("vonage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// SMS API mengembalikan HTTP 200 juga untuk pengiriman yang gagal
+	var result vonageSMSResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("vonage: response tidak valid: %w", err)
+	}
+	if len(result.Messages) == 0 {
+		return fmt.Errorf("vonage: response tidak berisi pesan")
+	}
+	for _, m := range result.Messages {
+		if m.Status != "0" {
+			return fmt.Errorf("vonage: status %s: %s", m.Status, m.ErrorText)
+		}
+	}
+
+	return nil
+}
+
+// sendWhatsApp mengirim pesan teks WhatsApp melalui Messages API
+func (v *Vonage) sendWhatsApp(ctx context.Context, to, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"message_type": "text",
+		"channel":      "whatsapp",
+		"to":           withoutPlus(to),
+		"from":         withoutPlus(v.From),
+		"text":         message,
+	})
+	if err != nil {
+		return err
+	}
+
+	base := v.BaseURL
+	if base == "" {
+		base = DefaultVonageMessagesURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(v.APIKey, v.APISecret)
+
+	resp, err := do(v.Client, req)
+	if err != nil {
+		return fmt.Errorf("vonage: %w", err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
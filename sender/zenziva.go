@@ -0,0 +1,65 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Alamat API Zenziva untuk SMS reguler dan WhatsApp
+const (
+	DefaultZenzivaSMSURL      = "https://console.zenziva.net/reguler/api/sendsms/"
+	DefaultZenzivaWhatsAppURL = "https://console.zenziva.net/wareguler/api/sendWA/"
+)
+
+// Zenziva mengirim SMS atau pesan WhatsApp melalui gateway Zenziva (Indonesia)
+type Zenziva struct {
+	UserKey  string
+	PassKey  string
+	WhatsApp bool // kirim melalui WhatsApp reguler
+
+	BaseURL string       // default DefaultZenzivaSMSURL atau DefaultZenzivaWhatsAppURL
+	Client  *http.Client // default client dengan timeout 10 detik
+}
+
+// zenzivaResponse adalah isi response API Zenziva; status "1" berarti berhasil
+type zenzivaResponse struct {
+	Status string `json:"status"`
+	Text   string `json:"text"`
+}
+
+// Send mengirim pesan ke nomor to dalam format E.164
+func (z *Zenziva) Send(ctx context.Context, to, message string) error {
+	form := url.Values{}
+	form.Set("userkey", z.UserKey)
+	form.Set("passkey", z.PassKey)
+	form.Set("to", withoutPlus(to))
+	form.Set("message", message)
+
+	endpoint := z.BaseURL
+	if endpoint == "" {
+		endpoint = DefaultZenzivaSMSURL
+		if z.WhatsApp {
+			endpoint = DefaultZenzivaWhatsAppURL
+		}
+	}
+
+	resp, err := postForm(ctx, z.Client, endpoint, form, nil)
+	if err != nil {
+		return fmt.Errorf("zenziva: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Zenziva mengembalikan HTTP 200 juga untuk pengiriman yang gagal
+	var result zenzivaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("zenziva: response tidak valid: %w", err)
+	}
+	if result.Status != "1" {
+		return fmt.Errorf("zenziva: %s", result.Text)
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/middleware"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Error yang dikembalikan oleh fungsi sesi
+var (
+	ErrInvalidSession  = errors.New("sesi tidak valid atau sudah kedaluwarsa")
+	ErrSessionNotFound = errors.New("sesi tidak ditemukan")
+)
+
+// sessionTokenBytes adalah jumlah byte acak dalam satu token sesi
+const sessionTokenBytes = 32
+
+// sessionTouchInterval membatasi seberapa sering LastSeenAt diperbarui,
+// agar tidak setiap permintaan menulis ke database
+const sessionTouchInterval = time.Minute
+
+// CreateSession membuat sesi baru untuk pengguna dan mengembalikan nilai
+// cookie-nya. Nilai ini hanya dikembalikan sekali; database menyimpan hash-nya.
+func (a *Auth) CreateSession(userID uint, userAgent, ip string) (*models.Session, string, error) {
+	token, err := utils.GenerateRandomToken(sessionTokenBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:     userID,
+		Token:      utils.HashToken(token),
+		UserAgent:  truncate(userAgent, 255),
+		IP:         truncate(ip, 45),
+		ExpiresAt:  now.Add(time.Duration(a.sessionExpiresIn()) * time.Second),
+		LastSeenAt: now,
+	}
+	if err := a.DB().Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &session, token, nil
+}
+
+// ValidateSession memeriksa nilai cookie sesi terhadap batas absolut dan
+// batas tidak aktif, lalu memperpanjang sesi (sliding expiry)
+func (a *Auth) ValidateSession(token string) (*models.Session, error) {
+	var session models.Session
+	err := a.DB().Where("token = ?", utils.HashToken(token)).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidSession
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !session.IsActive(a.sessionIdleTimeout()) {
+		return nil, ErrInvalidSession
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := a.DB().Model(&session).Update("last_seen_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &session, nil
+}
+
+// EndSession mencabut sesi berdasarkan nilai cookie-nya
+func (a *Auth) EndSession(token string) error {
+	return a.DB().Model(&models.Session{}).
+		Where("token = ? AND revoked_at IS NULL", utils.HashToken(token)).
+		Update("revoked_at", time.Now()).Error
+}
+
+// endSessionByID mencabut sesi berdasarkan ID
+func (a *Auth) endSessionByID(id uint) error {
+	return a.DB().Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// sessionClaims memvalidasi cookie sesi dan membangun klaim pengguna seperti
+// pada access token, sehingga handler tidak perlu membedakan kedua mode
+func (a *Auth) sessionClaims(token string) (*Claims, error) {
+	session, err := a.ValidateSession(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := a.DB().First(&user, session.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+
+	claims, err := a.newClaims(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Klaim sesi tidak memiliki jti; pencabutannya melalui tabel sesi
+	claims.ID = ""
+	claims.SessionID = session.ID
+	claims.ExpiresAt = jwt.NewNumericDate(session.ExpiresAt)
+	claims.IssuedAt = &jwt.NumericDate{Time: session.CreatedAt}
+
+	return claims, nil
+}
+
+// SessionInfo adalah ringkasan sesi aktif untuk ditampilkan ke pengguna
+type SessionInfo struct {
+	ID         uint         `json:"id"`
+	Device     utils.Device `json:"device"`
+	DeviceName string       `json:"device_name"`
+	IP         string       `json:"ip"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastSeenAt time.Time    `json:"last_seen_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	Current    bool         `json:"current"`
+}
+
+// ListSessions mengembalikan sesi aktif milik pengguna, diurutkan dari yang
+// terakhir dipakai
+func (a *Auth) ListSessions(userID uint) ([]SessionInfo, error) {
+	var sessions []models.Session
+	err := a.DB().
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.IsActive(a.sessionIdleTimeout()) {
+			continue
+		}
+
+		device := utils.ParseUserAgent(session.UserAgent)
+		infos = append(infos, SessionInfo{
+			ID:         session.ID,
+			Device:     device,
+			DeviceName: device.Name(),
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+		})
+	}
+
+	return infos, nil
+}
+
+// RevokeSession mencabut satu sesi milik pengguna. ErrSessionNotFound
+// dikembalikan jika sesi bukan milik pengguna atau sudah tidak aktif.
+func (a *Auth) RevokeSession(userID, sessionID uint) error {
+	res := a.DB().Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions mencabut semua sesi pengguna kecuali currentSessionID.
+// Jika currentSessionID bernilai nol, semua sesi dicabut.
+func (a *Auth) RevokeOtherSessions(userID, currentSessionID uint) error {
+	return a.DB().Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, currentSessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// startSession membuat sesi untuk permintaan saat ini dan memasang cookie-nya
+func (a *Auth) startSession(c echo.Context, user *models.User) (*models.Session, error) {
+	session, token, err := a.CreateSession(user.ID, c.Request().UserAgent(), c.RealIP())
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetCookie(a.sessionCookie(token, session.ExpiresAt))
+
+	return session, nil
+}
+
+// sessionCookie membuat cookie sesi sesuai konfigurasi. Cookie selalu
+// HttpOnly sehingga tidak dapat dibaca JavaScript.
+func (a *Auth) sessionCookie(value string, expires time.Time) *http.Cookie {
+	cfg := a.config.Session
+
+	path := cfg.CookiePath
+	if path == "" {
+		path = "/"
+	}
+
+	cookie := &http.Cookie{
+		Name:     a.sessionCookieName(),
+		Value:    value,
+		Path:     path,
+		Domain:   cfg.CookieDomain,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   !cfg.CookieInsecure,
+	}
+
+	switch strings.ToLower(cfg.CookieSameSite) {
+	case "strict":
+		cookie.SameSite = http.SameSiteStrictMode
+	case "none":
+		cookie.SameSite = http.SameSiteNoneMode
+	default:
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+
+	return cookie
+}
+
+// clearSessionCookie menghapus cookie sesi dari browser
+func (a *Auth) clearSessionCookie(c echo.Context) {
+	cookie := a.sessionCookie("", time.Unix(0, 0))
+	cookie.MaxAge = -1
+	c.SetCookie(cookie)
+}
+
+// sessionCookieName mengembalikan nama cookie sesi
+func (a *Auth) sessionCookieName() string {
+	if a.config.Session.CookieName != "" {
+		return a.config.Session.CookieName
+	}
+	return config.DefaultSessionCookie
+}
+
+// sessionExpiresIn mengembalikan masa berlaku absolut sesi dalam detik
+func (a *Auth) sessionExpiresIn() int64 {
+	if a.config.Session.ExpiresIn > 0 {
+		return a.config.Session.ExpiresIn
+	}
+	return config.DefaultSessionExpiresIn
+}
+
+// sessionIdleTimeout mengembalikan batas tidak aktif sesi
+func (a *Auth) sessionIdleTimeout() time.Duration {
+	return time.Duration(a.config.Session.IdleTimeout) * time.Second
+}
+
+// truncate memotong string agar muat di kolom database
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// sessionMiddlewareOptions menambahkan origin tepercaya dari konfigurasi sesi
+// pada option middleware instance
+func (a *Auth) sessionMiddlewareOptions() []middleware.Option {
+	return append(a.middlewareOptions(), middleware.WithTrustedOrigins(a.config.Session.TrustedOrigins...))
+}
+
+// SessionMiddleware mengembalikan middleware Echo yang menerima sesi cookie
+// maupun bearer token. Permintaan POST, PUT, PATCH dan DELETE dengan cookie
+// sesi dari origin lain ditolak kecuali origin ada di session.trusted_origins.
+func (a *Auth) SessionMiddleware(opts ...middleware.Option) echo.MiddlewareFunc {
+	return middleware.EchoSessionMiddleware(a.sessionCookieName(), a.sessionClaims, append(a.sessionMiddlewareOptions(), opts...)...)
+}
+
+// GinSessionMiddleware mengembalikan middleware Gin yang menerima sesi cookie
+// maupun bearer token
+func (a *Auth) GinSessionMiddleware(opts ...middleware.Option) gin.HandlerFunc {
+	return middleware.GinSessionMiddleware(a.sessionCookieName(), a.sessionClaims, append(a.sessionMiddlewareOptions(), opts...)...)
+}
+
+// FiberSessionMiddleware mengembalikan middleware Fiber yang menerima sesi
+// cookie maupun bearer token
+func (a *Auth) FiberSessionMiddleware(opts ...middleware.Option) fiber.Handler {
+	return middleware.FiberSessionMiddleware(a.sessionCookieName(), a.sessionClaims, append(a.sessionMiddlewareOptions(), opts...)...)
+}
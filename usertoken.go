@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"gorm.io/gorm"
+)
+
+// userTokenBytes adalah jumlah byte acak dalam token reset password dan
+// verifikasi email
+const userTokenBytes = 32
+
+// errUserTokenInvalid dikembalikan consumeUserToken; pemanggil mengubahnya
+// menjadi error yang sesuai dengan alurnya
+var errUserTokenInvalid = errors.New("token tidak valid atau sudah kedaluwarsa")
+
+// issueUserToken membuat models.Token baru bertipe tokenType untuk pengguna
+// dan membatalkan token sejenis yang belum dipakai. Token hanya dikembalikan
+// sekali; database menyimpan hash-nya.
+func (a *Auth) issueUserToken(userID uint, tokenType string, expiresIn int64) (string, time.Time, error) {
+	token, err := utils.GenerateRandomToken(userTokenBytes)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(expiresIn) * time.Second)
+
+	err = a.DB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Token{}).
+			Where("owner_id = ? AND type = ? AND used_at IS NULL", userID, tokenType).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		// OwnerType sama dengan polymorphicValue relasi di models.User
+		return tx.Create(&models.Token{
+			OwnerID:   userID,
+			OwnerType: tokenType,
+			Token:     utils.HashToken(token),
+			Type:      tokenType,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// consumeUserToken menandai token sebagai sudah dipakai dan mengembalikannya.
+// Update bersyarat memastikan token tidak dapat dipakai dua kali secara bersamaan.
+func consumeUserToken(db *gorm.DB, token, tokenType string) (*models.Token, error) {
+	var stored models.Token
+	err := db.Where("token = ? AND type = ?", utils.HashToken(token), tokenType).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errUserTokenInvalid
+	} else if err != nil {
+		return nil, err
+	}
+
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, errUserTokenInvalid
+	}
+
+	now := time.Now()
+	res := db.Model(&models.Token{}).
+		Where("id = ? AND used_at IS NULL", stored.ID).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, errUserTokenInvalid
+	}
+
+	stored.UsedAt = &now
+	return &stored, nil
+}
+
+// tokenLink menambahkan token sebagai query ?token= ke URL frontend. Jika
+// URL tidak dikonfigurasi, token dikirim apa adanya.
+func tokenLink(base, token string) string {
+	if base == "" {
+		return token
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
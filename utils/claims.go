@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+)
+
+// Claims adalah klaim access token yang diterbitkan package auth.
+// Klaim tambahan dari aplikasi (misalnya tenant_id) disimpan di Custom.
+type Claims struct {
+	UserID     uint   `json:"user_id"`
+	Email      string `json:"email"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Role       string `json:"role"`
+	IsVerified bool   `json:"is_verified"`
+	SessionID  uint   `json:"sid,omitempty"` // diisi jika pengguna terautentikasi melalui sesi cookie
+	jwt.RegisteredClaims
+
+	// Custom berisi klaim tambahan. Kunci yang bentrok dengan klaim di atas diabaikan.
+	Custom map[string]interface{} `json:"-"`
+}
+
+// plainClaims dipakai untuk (un)marshal field Claims tanpa memanggil method JSON-nya
+type plainClaims Claims
+
+// reservedClaims adalah nama klaim yang tidak boleh ditimpa oleh Custom
+var reservedClaims = map[string]bool{
+	"user_id": true, "email": true, "first_name": true, "last_name": true,
+	"role": true, "is_verified": true, "sid": true,
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+}
+
+// NewClaims membuat klaim untuk pengguna berdasarkan konfigurasi JWT.
+// Setiap token mendapat jti acak agar dapat dicabut sebelum kedaluwarsa.
+func NewClaims(user models.User, cfg config.JWT) (*Claims, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	claims := &Claims{
+		UserID:     user.ID,
+		Email:      user.Email,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		Role:       user.Role,
+		IsVerified: user.IsVerified,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    cfg.Issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * time.Duration(cfg.ExpiresIn))),
+			NotBefore: jwt.NewNumericDate(now),
+			// iat memakai presisi sub-detik agar pencabutan per pengguna tidak
+			// ikut menolak token yang diterbitkan pada detik yang sama setelahnya
+			IssuedAt: &jwt.NumericDate{Time: now},
+		},
+	}
+	if len(cfg.Audience) > 0 {
+		claims.Audience = jwt.ClaimStrings(cfg.Audience)
+	}
+
+	return claims, nil
+}
+
+// Set menambahkan klaim tambahan ke token
+func (c *Claims) Set(key string, value interface{}) {
+	if c.Custom == nil {
+		c.Custom = make(map[string]interface{})
+	}
+	c.Custom[key] = value
+}
+
+// Map mengembalikan klaim dalam bentuk jwt.MapClaims, sama seperti isi
+// konteks "user" pada versi sebelumnya
+func (c *Claims) Map() jwt.MapClaims {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return jwt.MapClaims{}
+	}
+	var m jwt.MapClaims
+	if err := json.Unmarshal(data, &m); err != nil {
+		return jwt.MapClaims{}
+	}
+	return m
+}
+
+// ClaimsFromMap mengubah jwt.MapClaims, misalnya dari TokenValidator
+// kustom, menjadi Claims
+func ClaimsFromMap(m jwt.MapClaims) (*Claims, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(data, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ClaimsFromToken mengambil Claims dari token hasil parse
+func ClaimsFromToken(token *jwt.Token) (*Claims, error) {
+	switch claims := token.Claims.(type) {
+	case *Claims:
+		return claims, nil
+	case jwt.MapClaims:
+		return ClaimsFromMap(claims)
+	default:
+		return nil, errors.New("could not parse token claims")
+	}
+}
+
+// MarshalJSON menggabungkan klaim standar dengan Custom dan menulis iat
+// dengan presisi sub-detik
+func (c Claims) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(plainClaims(c))
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for key, value := range c.Custom {
+		if reservedClaims[key] {
+			continue
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("klaim %s tidak valid: %w", key, err)
+		}
+		fields[key] = raw
+	}
+
+	// jwt.NumericDate membulatkan ke detik saat di-marshal
+	if c.IssuedAt != nil {
+		iat := float64(c.IssuedAt.UnixNano()) / float64(time.Second)
+		fields["iat"] = json.RawMessage(strconv.FormatFloat(iat, 'f', -1, 64))
+	}
+
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON membaca klaim standar, menyimpan klaim lain di Custom dan
+// mempertahankan presisi sub-detik iat
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*plainClaims)(c)); err != nil {
+		return err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for key, value := range fields {
+		if !reservedClaims[key] {
+			c.Set(key, value)
+		}
+	}
+
+	if iat, ok := fields["iat"].(float64); ok {
+		sec, frac := math.Modf(iat)
+		c.IssuedAt = &jwt.NumericDate{Time: time.Unix(int64(sec), int64(frac*float64(time.Second)))}
+	}
+
+	return nil
+}
+
+// ValidationRules berisi aturan tambahan saat memvalidasi token
+type ValidationRules struct {
+	Issuer   string        // jika diisi, klaim iss harus sama
+	Audience []string      // jika diisi, klaim aud harus memuat salah satunya
+	Leeway   time.Duration // toleransi selisih jam untuk exp, nbf dan iat
+}
+
+// ValidationRulesFromConfig membuat ValidationRules dari konfigurasi JWT
+func ValidationRulesFromConfig(cfg config.JWT) ValidationRules {
+	return ValidationRules{
+		Issuer:   cfg.Issuer,
+		Audience: cfg.Audience,
+		Leeway:   time.Duration(cfg.Leeway) * time.Second,
+	}
+}
+
+// ParseJWT mem-parse token ke Claims dengan keyfunc dan menerapkan rules
+func ParseJWT(tokenString string, keyfunc jwt.Keyfunc, rules ValidationRules) (*jwt.Token, error) {
+	opts := []jwt.ParserOption{jwt.WithIssuedAt()}
+	if rules.Leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(rules.Leeway))
+	}
+	if rules.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(rules.Issuer))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyfunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.Claims.(*Claims)
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: klaim exp wajib ada", jwt.ErrTokenInvalidClaims)
+	}
+	if len(rules.Audience) > 0 && !hasAudience(claims.Audience, rules.Audience) {
+		return nil, jwt.ErrTokenInvalidAudience
+	}
+
+	return token, nil
+}
+
+// hasAudience memeriksa apakah aud token memuat salah satu audience yang diharapkan
+func hasAudience(aud jwt.ClaimStrings, expected []string) bool {
+	for _, a := range aud {
+		for _, e := range expected {
+			if a == e {
+				return true
+			}
+		}
+	}
+	return false
+}
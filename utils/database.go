@@ -15,7 +15,21 @@ import (
 var DB *gorm.DB
 
 // InitDB menginisialisasi koneksi database berdasarkan konfigurasi
+// dan menyimpannya sebagai koneksi global DB
 func InitDB(config config.Database) (*gorm.DB, error) {
+	db, err := OpenDB(config)
+	if err != nil {
+		return nil, err
+	}
+
+	DB = db
+
+	return db, nil
+}
+
+// OpenDB membuka koneksi database baru berdasarkan konfigurasi tanpa
+// mengubah koneksi global DB
+func OpenDB(config config.Database) (*gorm.DB, error) {
 	var err error
 	var db *gorm.DB
 
@@ -38,8 +52,6 @@ func InitDB(config config.Database) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	DB = db
-
 	// Auto migrate database jika diminta
 	if config.AutoMigrate {
 		err = MigrateDB(db)
@@ -55,6 +67,10 @@ func InitDB(config config.Database) (*gorm.DB, error) {
 func MigrateDB(db *gorm.DB) error {
 	log.Println("Running automatic database migrations...")
 
+	// Versi lama tidak membedakan verifikasi email dari verifikasi akun
+	backfillEmailVerified := db.Migrator().HasTable(&models.User{}) &&
+		!db.Migrator().HasColumn(&models.User{}, "email_verified")
+
 	// Daftar model untuk migrasi
 	err := db.AutoMigrate(
 		&models.User{},
@@ -62,12 +78,37 @@ func MigrateDB(db *gorm.DB) error {
 		&models.Session{},
 		&models.Token{},
 		&models.OTPCode{},
+		&models.OTPRequest{},
+		&models.OTPQuotaLock{},
+		&models.PendingRegistration{},
+		&models.MFAChallenge{},
+		&models.BackupCode{},
+		&models.WebAuthnCredential{},
+		&models.WebAuthnSession{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.UserTokenRevocation{},
 	)
 
 	if err != nil {
 		return err
 	}
 
+	if backfillEmailVerified {
+		if err := db.Model(&models.User{}).
+			Where("is_verified = ? AND email IS NOT NULL AND email <> ''", true).
+			Update("email_verified", true).Error; err != nil {
+			return err
+		}
+	}
+
+	// Versi lama menyimpan kode OTP tanpa hash di kolom code
+	if db.Migrator().HasColumn(&models.OTPCode{}, "code") {
+		if err := db.Migrator().DropColumn(&models.OTPCode{}, "code"); err != nil {
+			return err
+		}
+	}
+
 	log.Println("Database migration completed successfully")
 	return nil
 }
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// JWK adalah representasi JSON Web Key (RFC 7517) untuk public key
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS adalah kumpulan JWK yang dipublikasikan di /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK mengubah public key dari SigningKey menjadi JWK.
+// Kunci HMAC tidak pernah dipublikasikan sehingga menghasilkan error.
+func PublicJWK(key *SigningKey) (JWK, error) {
+	if key.IsSymmetric() {
+		return JWK{}, errors.New("kunci HMAC tidak dapat dipublikasikan")
+	}
+
+	jwk := JWK{Use: "sig", Alg: key.Method.Alg(), Kid: key.ID}
+
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBigInt(pub.N)
+		jwk.E = encodeBigInt(big.NewInt(int64(pub.E)))
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = encodeFixed(pub.X, size)
+		jwk.Y = encodeFixed(pub.Y, size)
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, fmt.Errorf("tipe public key %T tidak didukung", key.Public)
+	}
+
+	return jwk, nil
+}
+
+// VerificationKey mengubah JWK menjadi SigningKey yang hanya dapat
+// memverifikasi token. Jika alg kosong, algoritma ditebak dari tipe kunci.
+func (j JWK) VerificationKey() (*SigningKey, error) {
+	var public crypto.PublicKey
+	alg := j.Alg
+
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("nilai n tidak valid: %w", err)
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("nilai e tidak valid: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("nilai e terlalu besar")
+		}
+		public = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		if alg == "" {
+			alg = AlgRS256
+		}
+	case "EC":
+		curve, defaultAlg, err := curveByName(j.Crv)
+		if err != nil {
+			return nil, err
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("nilai x tidak valid: %w", err)
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("nilai y tidak valid: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("titik tidak berada pada kurva")
+		}
+		public = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if alg == "" {
+			alg = defaultAlg
+		}
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("kurva OKP %q tidak didukung", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("nilai x tidak valid")
+		}
+		public = ed25519.PublicKey(x)
+		if alg == "" {
+			alg = AlgEdDSA
+		}
+	default:
+		return nil, fmt.Errorf("tipe kunci %q tidak didukung", j.Kty)
+	}
+
+	return NewVerificationKey(alg, public, j.Kid)
+}
+
+// JWKS mengembalikan public key dari semua kunci asimetris yang masih berlaku
+func (r *KeyRing) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range r.Keys() {
+		if jwk, err := PublicJWK(key); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// curveByName mengembalikan kurva elliptic dan algoritma default untuk nama crv JWK
+func curveByName(name string) (elliptic.Curve, string, error) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), AlgES256, nil
+	case "P-384":
+		return elliptic.P384(), AlgES384, nil
+	case "P-521":
+		return elliptic.P521(), AlgES512, nil
+	default:
+		return nil, "", fmt.Errorf("kurva %q tidak didukung", name)
+	}
+}
+
+// decodeBigInt mendekode bilangan bulat base64url
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("nilai kosong")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -9,71 +9,125 @@ import (
 	"github.com/kreasimaju/auth/models"
 )
 
-var jwtSecret string
+var (
+	jwtSecret  string
+	jwtKeyRing *KeyRing
+	jwtRules   ValidationRules
+)
 
 // SetJWTSecret menetapkan secret untuk JWT
 func SetJWTSecret(secret string) {
 	jwtSecret = secret
 }
 
-// GenerateJWT menghasilkan token JWT untuk pengguna
+// SetKeyRing menetapkan KeyRing global yang dipakai ValidateJWT. Jika diatur,
+// KeyRing lebih diutamakan daripada secret dari SetJWTSecret.
+func SetKeyRing(ring *KeyRing) {
+	jwtKeyRing = ring
+}
+
+// SetValidationRules menetapkan aturan issuer, audience dan leeway yang
+// diterapkan oleh ValidateJWT
+func SetValidationRules(rules ValidationRules) {
+	jwtRules = rules
+}
+
+// GenerateJWT menghasilkan token JWT HS256 untuk pengguna dengan cfg.Secret.
+// Untuk algoritma lain gunakan GenerateJWTWithKey.
 func GenerateJWT(user models.User, cfg config.JWT) (string, error) {
-	// Buat token JWT dengan klaim
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":     user.ID,
-		"email":       user.Email,
-		"first_name":  user.FirstName,
-		"last_name":   user.LastName,
-		"role":        user.Role,
-		"is_verified": user.IsVerified,
-		"exp":         time.Now().Add(time.Second * time.Duration(cfg.ExpiresIn)).Unix(),
-		"iat":         time.Now().Unix(),
-	})
-
-	// Tandatangani token dengan secret
-	tokenString, err := token.SignedString([]byte(cfg.Secret))
+	key := &SigningKey{
+		ID:      cfg.KeyID,
+		Method:  jwt.SigningMethodHS256,
+		Private: []byte(cfg.Secret),
+		Public:  []byte(cfg.Secret),
+	}
+	return GenerateJWTWithKey(user, cfg, key)
+}
+
+// GenerateJWTWithKey menghasilkan token JWT untuk pengguna yang ditandatangani
+// dengan kunci yang diberikan. Header kid diisi dengan ID kunci.
+func GenerateJWTWithKey(user models.User, cfg config.JWT, key *SigningKey) (string, error) {
+	claims, err := NewClaims(user, cfg)
 	if err != nil {
 		return "", err
 	}
-
-	return tokenString, nil
+	return SignJWT(claims, key)
 }
 
-// ValidateJWT memvalidasi token JWT
+// ValidateJWT memvalidasi token JWT menggunakan KeyRing atau secret global
 func ValidateJWT(tokenString string) (*jwt.Token, error) {
-	if jwtSecret == "" {
+	if jwtKeyRing != nil {
+		return ParseJWT(tokenString, jwtKeyRing.Keyfunc, jwtRules)
+	}
+	return validateWithSecret(tokenString, jwtSecret, jwtRules)
+}
+
+// ValidateJWTWithSecret memvalidasi token JWT menggunakan secret yang diberikan
+func ValidateJWTWithSecret(tokenString, secret string) (*jwt.Token, error) {
+	return validateWithSecret(tokenString, secret, ValidationRules{})
+}
+
+// validateWithSecret memvalidasi token HMAC dengan secret dan rules
+func validateWithSecret(tokenString, secret string, rules ValidationRules) (*jwt.Token, error) {
+	if secret == "" {
 		return nil, errors.New("JWT secret not initialized")
 	}
 
-	// Parse token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	return ParseJWT(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Validasi metode signing
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(jwtSecret), nil
-	})
+		return []byte(secret), nil
+	}, rules)
+}
 
-	if err != nil {
-		return nil, err
-	}
+// ValidateJWTWithKeyRing memvalidasi token JWT dengan kunci dari KeyRing yang
+// dipilih berdasarkan header kid
+func ValidateJWTWithKeyRing(tokenString string, ring *KeyRing) (*jwt.Token, error) {
+	return ValidateJWTWithRules(tokenString, ring, ValidationRules{})
+}
 
-	return token, nil
+// ValidateJWTWithRules memvalidasi token JWT dengan KeyRing dan menerapkan
+// aturan issuer, audience dan leeway
+func ValidateJWTWithRules(tokenString string, ring *KeyRing, rules ValidationRules) (*jwt.Token, error) {
+	if ring == nil {
+		return nil, errors.New("JWT key ring not initialized")
+	}
+	return ParseJWT(tokenString, ring.Keyfunc, rules)
 }
 
 // GetUserIDFromToken mengambil ID pengguna dari token JWT
 func GetUserIDFromToken(token *jwt.Token) (uint, error) {
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return 0, errors.New("could not parse token claims")
+	claims, err := ClaimsFromToken(token)
+	if err != nil {
+		return 0, err
 	}
 
-	userID, ok := claims["user_id"].(float64)
-	if !ok {
+	if claims.UserID == 0 {
 		return 0, errors.New("user_id claim not found or invalid")
 	}
 
-	return uint(userID), nil
+	return claims.UserID, nil
+}
+
+// GetTokenID mengambil ID unik (jti) dari token JWT
+func GetTokenID(token *jwt.Token) string {
+	claims, err := ClaimsFromToken(token)
+	if err != nil {
+		return ""
+	}
+	return claims.ID
+}
+
+// IsTokenRevoked memeriksa klaim token terhadap RevocationStore
+func IsTokenRevoked(store RevocationStore, claims *Claims) (bool, error) {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	return store.IsRevoked(claims.ID, claims.UserID, issuedAt)
 }
 
 // GetConfig mengambil konfigurasi dari package auth
@@ -0,0 +1,498 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kreasimaju/auth/config"
+)
+
+// Algoritma penandatanganan JWT yang didukung
+const (
+	AlgHS256 = "HS256"
+	AlgHS384 = "HS384"
+	AlgHS512 = "HS512"
+	AlgRS256 = "RS256"
+	AlgRS384 = "RS384"
+	AlgRS512 = "RS512"
+	AlgES256 = "ES256"
+	AlgES384 = "ES384"
+	AlgES512 = "ES512"
+	AlgEdDSA = "EdDSA"
+)
+
+// rsaKeyBits adalah ukuran kunci RSA yang dibuat otomatis
+const rsaKeyBits = 2048
+
+// SigningKey adalah kunci untuk menandatangani dan memverifikasi JWT.
+// Untuk HMAC, Private dan Public berisi secret yang sama. Kunci yang hanya
+// dipakai untuk verifikasi memiliki Private bernilai nil.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// IsSymmetric memeriksa apakah kunci memakai algoritma HMAC
+func (k *SigningKey) IsSymmetric() bool {
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// NewHMACKey membuat SigningKey HMAC dari secret. Jika id kosong, kid
+// diturunkan dari hash secret sehingga stabil antar restart.
+func NewHMACKey(alg, secret, id string) (*SigningKey, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("algoritma %s bukan algoritma HMAC", alg)
+	}
+	if secret == "" {
+		return nil, errors.New("JWT secret not initialized")
+	}
+
+	if id == "" {
+		sum := sha256.Sum256([]byte("kreasimaju-auth-hmac:" + secret))
+		id = hex.EncodeToString(sum[:8])
+	}
+
+	return &SigningKey{
+		ID:      id,
+		Method:  method,
+		Private: []byte(secret),
+		Public:  []byte(secret),
+	}, nil
+}
+
+// GenerateSigningKey membuat pasangan kunci asimetris baru untuk algoritma yang diberikan
+func GenerateSigningKey(alg string) (*SigningKey, error) {
+	var private crypto.Signer
+	var err error
+
+	switch alg {
+	case AlgRS256, AlgRS384, AlgRS512:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgES384:
+		private, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case AlgES512:
+		private, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("algoritma %s tidak dapat dibuat otomatis", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAsymmetricKey(alg, private, "")
+}
+
+// NewAsymmetricKey membuat SigningKey dari private key RSA, ECDSA atau Ed25519.
+// Jika id kosong, kid diisi dengan JWK thumbprint (RFC 7638) dari public key.
+func NewAsymmetricKey(alg string, private crypto.Signer, id string) (*SigningKey, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{
+		ID:      id,
+		Method:  method,
+		Private: private,
+		Public:  private.Public(),
+	}
+	if err := key.checkType(); err != nil {
+		return nil, err
+	}
+
+	if key.ID == "" {
+		key.ID, err = Thumbprint(key.Public)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// NewVerificationKey membuat SigningKey yang hanya dapat memverifikasi token
+func NewVerificationKey(alg string, public crypto.PublicKey, id string) (*SigningKey, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: id, Method: method, Public: public}
+	if err := key.checkType(); err != nil {
+		return nil, err
+	}
+
+	if key.ID == "" {
+		key.ID, err = Thumbprint(public)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// checkType memastikan tipe public key sesuai dengan algoritma
+func (k *SigningKey) checkType() error {
+	ok := false
+	switch k.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		_, ok = k.Public.(*rsa.PublicKey)
+	case *jwt.SigningMethodECDSA:
+		var pub *ecdsa.PublicKey
+		if pub, ok = k.Public.(*ecdsa.PublicKey); ok {
+			ok = pub.Curve.Params().BitSize == k.Method.(*jwt.SigningMethodECDSA).CurveBits
+		}
+	case *jwt.SigningMethodEd25519:
+		_, ok = k.Public.(ed25519.PublicKey)
+	}
+	if !ok {
+		return fmt.Errorf("tipe kunci %T tidak cocok dengan algoritma %s", k.Public, k.Method.Alg())
+	}
+	return nil
+}
+
+// LoadSigningKeyFile membaca private key PEM (PKCS#8, PKCS#1 atau SEC 1) dari file
+func LoadSigningKeyFile(alg, path, id string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca private key: %w", err)
+	}
+
+	private, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mem-parse private key %s: %w", path, err)
+	}
+
+	return NewAsymmetricKey(alg, private, id)
+}
+
+// ParsePrivateKeyPEM mem-parse private key RSA, ECDSA atau Ed25519 dalam format PEM
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("data PEM tidak ditemukan")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("tipe private key %T tidak didukung", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("tipe blok PEM %q tidak didukung", block.Type)
+	}
+}
+
+// LoadVerificationKeyFile membaca kunci verifikasi dari file PEM yang berisi
+// public key (PKIX) atau private key. Private key hanya dipakai bagian publiknya.
+func LoadVerificationKeyFile(alg, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca kunci verifikasi: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("data PEM tidak ditemukan di %s", path)
+	}
+
+	if block.Type == "PUBLIC KEY" {
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("gagal mem-parse public key %s: %w", path, err)
+		}
+		return NewVerificationKey(alg, public, "")
+	}
+
+	private, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mem-parse kunci %s: %w", path, err)
+	}
+	return NewVerificationKey(alg, private.Public(), "")
+}
+
+// EncodePrivateKeyPEM menyandikan private key ke PEM PKCS#8
+func EncodePrivateKeyPEM(private crypto.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// NewSigningKeyFromConfig membuat kunci penandatanganan aktif dari konfigurasi JWT.
+// Algoritma asimetris tanpa private_key_file akan memakai kunci yang dibuat
+// otomatis saat startup, sehingga token lama tidak berlaku setelah restart.
+func NewSigningKeyFromConfig(cfg config.JWT) (*SigningKey, error) {
+	alg := cfg.Algorithm
+	if alg == "" {
+		alg = AlgHS256
+	}
+
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		return NewHMACKey(alg, cfg.Secret, cfg.KeyID)
+	}
+
+	if cfg.PrivateKeyFile != "" {
+		return LoadSigningKeyFile(alg, cfg.PrivateKeyFile, cfg.KeyID)
+	}
+
+	key, err := GenerateSigningKey(alg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.KeyID != "" {
+		key.ID = cfg.KeyID
+	}
+	return key, nil
+}
+
+// signingMethod mengembalikan jwt.SigningMethod untuk nama algoritma
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgHS256, AlgHS384, AlgHS512, AlgRS256, AlgRS384, AlgRS512,
+		AlgES256, AlgES384, AlgES512, AlgEdDSA:
+		return jwt.GetSigningMethod(alg), nil
+	default:
+		return nil, fmt.Errorf("algoritma JWT tidak didukung: %s", alg)
+	}
+}
+
+// Thumbprint menghitung JWK thumbprint SHA-256 (RFC 7638) dari public key
+func Thumbprint(public crypto.PublicKey) (string, error) {
+	var members interface{}
+
+	// Anggota wajib disusun dalam urutan leksikografis sesuai RFC 7638
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{encodeBigInt(big.NewInt(int64(pub.E))), "RSA", encodeBigInt(pub.N)}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{pub.Curve.Params().Name, "EC", encodeFixed(pub.X, size), encodeFixed(pub.Y, size)}
+	case ed25519.PublicKey:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{"Ed25519", "OKP", base64.RawURLEncoding.EncodeToString(pub)}
+	default:
+		return "", fmt.Errorf("tipe public key %T tidak didukung", public)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// encodeBigInt menyandikan bilangan bulat ke base64url tanpa byte nol di depan
+func encodeBigInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+// encodeFixed menyandikan koordinat kurva ke base64url dengan panjang tetap
+func encodeFixed(n *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(n.FillBytes(make([]byte, size)))
+}
+
+// KeyRing menyimpan kunci penandatanganan aktif beserta kunci lama yang
+// masih diterima untuk verifikasi, dan memilih kunci berdasarkan header kid.
+// Kunci yang dipensiunkan hanya dipakai untuk verifikasi sampai masa
+// retensinya habis, sehingga token yang sudah terbit tetap berlaku.
+type KeyRing struct {
+	mu       sync.RWMutex
+	active   *SigningKey
+	keys     map[string]*SigningKey
+	retireAt map[string]time.Time // kid -> waktu kunci dihapus; tidak ada berarti permanen
+}
+
+// NewKeyRing membuat KeyRing dengan kunci aktif dan kunci verifikasi tambahan
+func NewKeyRing(active *SigningKey, verificationKeys ...*SigningKey) *KeyRing {
+	r := &KeyRing{
+		active:   active,
+		keys:     map[string]*SigningKey{active.ID: active},
+		retireAt: make(map[string]time.Time),
+	}
+	for _, key := range verificationKeys {
+		r.keys[key.ID] = key.verificationOnly()
+	}
+	return r
+}
+
+// verificationOnly mengembalikan salinan kunci tanpa private key
+func (k *SigningKey) verificationOnly() *SigningKey {
+	return &SigningKey{ID: k.ID, Method: k.Method, Public: k.Public}
+}
+
+// SigningKey mengembalikan kunci yang dipakai untuk menandatangani token baru
+func (r *KeyRing) SigningKey() *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// Lookup mencari kunci berdasarkan kid
+func (r *KeyRing) Lookup(kid string) (*SigningKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	if until, retiring := r.retireAt[kid]; retiring && time.Now().After(until) {
+		return nil, false
+	}
+	return key, true
+}
+
+// Keys mengembalikan semua kunci yang masih berlaku, dimulai dari kunci aktif
+func (r *KeyRing) Keys() []*SigningKey {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+
+	keys := []*SigningKey{r.active}
+	for kid, key := range r.keys {
+		if kid != r.active.ID {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Rotate menjadikan next sebagai kunci aktif. Kunci aktif sebelumnya tetap
+// diterima untuk verifikasi selama retention, lalu dihapus.
+func (r *KeyRing) Rotate(next *SigningKey, retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.active
+	r.keys[previous.ID] = previous.verificationOnly()
+	r.retireAt[previous.ID] = time.Now().Add(retention)
+
+	r.active = next
+	r.keys[next.ID] = next
+	delete(r.retireAt, next.ID)
+
+	r.prune()
+}
+
+// AddVerificationKey menambahkan kunci yang hanya dipakai untuk verifikasi.
+// Jika until bernilai nol, kunci disimpan tanpa batas waktu.
+func (r *KeyRing) AddVerificationKey(key *SigningKey, until time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key.ID == r.active.ID {
+		return
+	}
+
+	r.keys[key.ID] = key.verificationOnly()
+	if until.IsZero() {
+		delete(r.retireAt, key.ID)
+	} else {
+		r.retireAt[key.ID] = until
+	}
+}
+
+// prune menghapus kunci pensiun yang masa retensinya sudah habis.
+// Pemanggil harus memegang lock tulis.
+func (r *KeyRing) prune() {
+	now := time.Now()
+	for kid, until := range r.retireAt {
+		if now.After(until) {
+			delete(r.keys, kid)
+			delete(r.retireAt, kid)
+		}
+	}
+}
+
+// Keyfunc adalah jwt.Keyfunc yang memilih kunci verifikasi berdasarkan kid.
+// Token tanpa kid (diterbitkan sebelum kid diperkenalkan) diverifikasi
+// dengan kunci aktif. Algoritma token harus sama dengan algoritma kunci.
+func (r *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	var key *SigningKey
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		found, ok := r.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("kunci dengan kid %q tidak dikenal", kid)
+		}
+		key = found
+	} else {
+		key = r.SigningKey()
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.Public, nil
+}
+
+// SignJWT menandatangani klaim dengan kunci dan menambahkan header kid
+func SignJWT(claims jwt.Claims, key *SigningKey) (string, error) {
+	if key.Private == nil {
+		return "", errors.New("kunci hanya dapat dipakai untuk verifikasi")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.Private)
+}
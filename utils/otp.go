@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/kreasimaju/auth/config"
@@ -28,19 +31,51 @@ func GenerateOTP(length int) string {
 	return string(bytes)
 }
 
-// CreateOTP membuat kode OTP baru dan menyimpannya di database
-func CreateOTP(db *gorm.DB, userID uint, otpType, target, purpose string, cfg config.OTP) (*models.OTPCode, error) {
+// HashOTP menghitung HMAC-SHA256 kode OTP dengan key milik server, sehingga
+// kode tidak dapat ditebak dari isi database tanpa key tersebut
+func HashOTP(key []byte, code string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckOTP membandingkan kode dengan hash dari HashOTP secara constant-time
+func CheckOTP(key []byte, code, hash string) bool {
+	return hmac.Equal([]byte(HashOTP(key, code)), []byte(hash))
+}
+
+// otpOwner memilih OTP milik pengguna. OTP tanpa pengguna (userID nol),
+// misalnya untuk registrasi, dimiliki oleh target-nya.
+func otpOwner(db *gorm.DB, userID uint, target string) *gorm.DB {
+	if userID == 0 {
+		return db.Where("user_id IS NULL AND target = ?", target)
+	}
+	return db.Where("user_id = ?", userID)
+}
+
+// CreateOTP membuat kode OTP baru, menggantikan OTP aktif dengan tujuan yang
+// sama, dan menyimpan hash-nya di database. Kode asli hanya tersedia di
+// field Code pada nilai yang dikembalikan. userID boleh nol untuk target yang
+// belum terdaftar.
+func CreateOTP(db *gorm.DB, key []byte, userID uint, otpType, target, purpose string, cfg config.OTP) (*models.OTPCode, error) {
 	// Hapus kode OTP yang sudah ada untuk tujuan yang sama
-	db.Where("user_id = ? AND purpose = ? AND type = ? AND target = ? AND valid = ?", userID, purpose, otpType, target, true).
-		Delete(&models.OTPCode{})
+	err := otpOwner(db, userID, target).
+		Where("purpose = ? AND type = ? AND valid = ?", purpose, otpType, true).
+		Delete(&models.OTPCode{}).Error
+	if err != nil {
+		return nil, err
+	}
 
 	// Buat kode OTP baru
 	code := GenerateOTP(cfg.Length)
+	if code == "" {
+		return nil, errors.New("gagal membuat kode OTP")
+	}
 	expiresAt := time.Now().Add(time.Duration(cfg.ExpiresIn) * time.Second)
 
 	otp := models.OTPCode{
 		UserID:    userID,
-		Code:      code,
+		CodeHash:  HashOTP(key, code),
 		Type:      otpType,
 		Target:    target,
 		Purpose:   purpose,
@@ -48,19 +83,36 @@ func CreateOTP(db *gorm.DB, userID uint, otpType, target, purpose string, cfg co
 		Valid:     true,
 	}
 
-	err := db.Create(&otp).Error
-	if err != nil {
+	if err := db.Create(&otp).Error; err != nil {
 		return nil, err
 	}
 
+	otp.Code = code
 	return &otp, nil
 }
 
-// VerifyOTP memverifikasi kode OTP
-func VerifyOTP(db *gorm.DB, userID uint, code, otpType, purpose string) (bool, error) {
+// ErrTooManyOTPAttempts dikembalikan VerifyOTP saat kode OTP dibatalkan
+// karena terlalu banyak percobaan yang salah
+var ErrTooManyOTPAttempts = errors.New("terlalu banyak percobaan OTP, minta kode baru")
+
+// VerifyOTP memverifikasi kode terhadap OTP terakhir milik pengguna untuk
+// tipe dan tujuan tertentu. Setiap kode yang salah menambah Attempts; setelah
+// maxAttempts percobaan, OTP dibatalkan dan ErrTooManyOTPAttempts dikembalikan.
+func VerifyOTP(db *gorm.DB, key []byte, userID uint, code, otpType, purpose string, maxAttempts int) (bool, error) {
+	return verifyOTP(db, otpOwner(db, userID, ""), key, code, otpType, purpose, maxAttempts)
+}
+
+// VerifyTargetOTP sama dengan VerifyOTP untuk OTP tanpa pengguna yang dibuat
+// CreateOTP dengan userID nol, misalnya untuk registrasi
+func VerifyTargetOTP(db *gorm.DB, key []byte, target, code, otpType, purpose string, maxAttempts int) (bool, error) {
+	return verifyOTP(db, otpOwner(db, 0, target), key, code, otpType, purpose, maxAttempts)
+}
+
+// verifyOTP memverifikasi kode terhadap OTP terakhir yang dipilih owner
+func verifyOTP(db, owner *gorm.DB, key []byte, code, otpType, purpose string, maxAttempts int) (bool, error) {
 	var otp models.OTPCode
-	err := db.Where("user_id = ? AND code = ? AND type = ? AND purpose = ? AND valid = ?",
-		userID, code, otpType, purpose, true).First(&otp).Error
+	err := owner.Where("type = ? AND purpose = ?", otpType, purpose).
+		Order("created_at DESC").First(&otp).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -69,50 +121,46 @@ func VerifyOTP(db *gorm.DB, userID uint, code, otpType, purpose string) (bool, e
 		return false, err
 	}
 
-	// Periksa validitas OTP
-	if !otp.IsValid() {
+	// OTP yang sudah dipakai atau kedaluwarsa tidak perlu dihitung
+	if otp.UsedAt != nil || time.Now().After(otp.ExpiresAt) {
 		return false, nil
 	}
-
-	// Tandai sebagai digunakan
-	if err := otp.MarkAsUsed(db); err != nil {
-		return false, err
+	if otp.Attempts >= maxAttempts {
+		return false, ErrTooManyOTPAttempts
+	}
+	if !otp.Valid {
+		return false, nil
 	}
 
-	return true, nil
-}
+	if !CheckOTP(key, code, otp.CodeHash) {
+		if err := otp.IncrementAttempts(db, maxAttempts); err != nil {
+			return false, err
+		}
+		if otp.Attempts >= maxAttempts {
+			return false, ErrTooManyOTPAttempts
+		}
+		return false, nil
+	}
 
-// SendOTP mengirim kode OTP melalui channel yang dipilih
-func SendOTP(otpCode *models.OTPCode, cfg config.OTP) error {
-	switch otpCode.Type {
-	case "sms":
-		return sendSMS(otpCode, cfg.SMS)
-	case "email":
-		return sendEmail(otpCode, cfg.Email)
-	case "whatsapp":
-		return sendWhatsApp(otpCode, cfg.WhatsApp)
-	default:
-		return fmt.Errorf("tipe OTP tidak dikenal: %s", otpCode.Type)
+	// Update bersyarat agar kode yang sama tidak dapat dipakai dua kali secara
+	// bersamaan atau setelah batas percobaan tercapai
+	res := db.Model(&models.OTPCode{}).
+		Where("id = ? AND used_at IS NULL AND valid = ? AND attempts < ?", otp.ID, true, maxAttempts).
+		Updates(map[string]interface{}{"used_at": time.Now(), "valid": false})
+	if res.Error != nil {
+		return false, res.Error
 	}
-}
 
-// sendSMS mengirim kode OTP melalui SMS
-func sendSMS(otpCode *models.OTPCode, cfg config.OTPProvider) error {
-	// Implementasi pengiriman SMS
-	// Gunakan provider SMS seperti Twilio, Nexmo, dll.
-	return nil
+	return res.RowsAffected == 1, nil
 }
 
-// sendEmail mengirim kode OTP melalui email
-func sendEmail(otpCode *models.OTPCode, cfg config.OTPProvider) error {
-	// Implementasi pengiriman email
-	// Gunakan SMTP atau layanan email seperti SendGrid, Mailgun, dll.
-	return nil
-}
+// ErrOTPSenderRequired dikembalikan oleh SendOTP karena pengiriman OTP
+// membutuhkan OTPSender yang didaftarkan pada instance auth
+var ErrOTPSenderRequired = errors.New("pengiriman OTP membutuhkan OTPSender; gunakan Auth.SetOTPSender dan Auth.SendOTP")
 
-// sendWhatsApp mengirim kode OTP melalui WhatsApp
-func sendWhatsApp(otpCode *models.OTPCode, cfg config.OTPProvider) error {
-	// Implementasi pengiriman WhatsApp
-	// Gunakan API WhatsApp Business atau penyedia layanan seperti Twilio, dll.
-	return nil
+// SendOTP tidak lagi mengirim apa pun dan selalu mengembalikan ErrOTPSenderRequired.
+//
+// Deprecated: daftarkan OTPSender dengan Auth.SetOTPSender lalu gunakan Auth.SendOTP.
+func SendOTP(otpCode *models.OTPCode, cfg config.OTP) error {
+	return ErrOTPSenderRequired
 }
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kreasimaju/auth/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore menyimpan daftar JWT yang dicabut sebelum kedaluwarsa
+type RevocationStore interface {
+	// Revoke mencabut satu token berdasarkan jti sampai waktu kedaluwarsanya
+	Revoke(jti string, userID uint, expiresAt time.Time) error
+	// RevokeUser mencabut semua token pengguna yang diterbitkan sebelum waktu tertentu
+	RevokeUser(userID uint, before time.Time) error
+	// IsRevoked memeriksa apakah token dengan jti, pengguna dan waktu terbit tersebut sudah dicabut
+	IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error)
+}
+
+// MemoryRevocationStore adalah RevocationStore in-memory untuk satu proses,
+// cocok untuk pengujian atau aplikasi yang berjalan dalam satu instance
+type MemoryRevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[uint]time.Time
+}
+
+// NewMemoryRevocationStore membuat MemoryRevocationStore baru
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[uint]time.Time),
+	}
+}
+
+// Revoke mencabut satu token berdasarkan jti
+func (s *MemoryRevocationStore) Revoke(jti string, userID uint, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Buang entri yang sudah kedaluwarsa agar map tidak terus membesar
+	now := time.Now()
+	for id, exp := range s.tokens {
+		if now.After(exp) {
+			delete(s.tokens, id)
+		}
+	}
+
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+// RevokeUser mencabut semua token pengguna yang diterbitkan sebelum waktu tertentu
+func (s *MemoryRevocationStore) RevokeUser(userID uint, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if before.After(s.users[userID]) {
+		s.users[userID] = before
+	}
+	return nil
+}
+
+// IsRevoked memeriksa apakah token sudah dicabut
+func (s *MemoryRevocationStore) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if jti != "" {
+		if _, ok := s.tokens[jti]; ok {
+			return true, nil
+		}
+	}
+
+	if before, ok := s.users[userID]; ok && issuedAt.Before(before) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// DBRevocationStore adalah RevocationStore yang disimpan di database sehingga
+// pencabutan berlaku untuk semua instance aplikasi
+type DBRevocationStore struct {
+	db *gorm.DB
+}
+
+// NewDBRevocationStore membuat DBRevocationStore baru
+func NewDBRevocationStore(db *gorm.DB) *DBRevocationStore {
+	return &DBRevocationStore{db: db}
+}
+
+// Revoke mencabut satu token berdasarkan jti
+func (s *DBRevocationStore) Revoke(jti string, userID uint, expiresAt time.Time) error {
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// RevokeUser mencabut semua token pengguna yang diterbitkan sebelum waktu tertentu
+func (s *DBRevocationStore) RevokeUser(userID uint, before time.Time) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(&models.UserTokenRevocation{
+		UserID:        userID,
+		RevokedBefore: before,
+	}).Error
+}
+
+// IsRevoked memeriksa apakah token sudah dicabut
+func (s *DBRevocationStore) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		var count int64
+		if err := s.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	var revocation models.UserTokenRevocation
+	err := s.db.Where("user_id = ?", userID).First(&revocation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return issuedAt.Before(revocation.RevokedBefore), nil
+}
+
+// PurgeExpired menghapus entri token yang sudah melewati waktu kedaluwarsanya
+func (s *DBRevocationStore) PurgeExpired() error {
+	return s.db.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
@@ -0,0 +1,96 @@
+package utils
+
+import "strings"
+
+// Device berisi informasi perangkat yang dibaca dari header User-Agent
+type Device struct {
+	Browser string `json:"browser"` // misalnya "Chrome", "Firefox", "Safari"
+	OS      string `json:"os"`      // misalnya "Windows", "macOS", "Android"
+	Type    string `json:"type"`    // "desktop", "mobile", "tablet" atau "bot"
+}
+
+// Name mengembalikan nama perangkat yang ramah pengguna, misalnya "Chrome di Windows"
+func (d Device) Name() string {
+	switch {
+	case d.Browser != "" && d.OS != "":
+		return d.Browser + " di " + d.OS
+	case d.Browser != "":
+		return d.Browser
+	case d.OS != "":
+		return d.OS
+	default:
+		return "Perangkat tidak dikenal"
+	}
+}
+
+// browserPatterns diperiksa berurutan karena banyak browser menyertakan nama
+// browser lain di User-Agent (misalnya Edge menyertakan "Chrome" dan "Safari")
+var browserPatterns = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex"},
+	{"UCBrowser/", "UC Browser"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"okhttp/", "Android App"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+}
+
+// osPatterns diperiksa berurutan; iOS dan Android diperiksa sebelum macOS dan Linux
+var osPatterns = []struct {
+	token string
+	name  string
+}{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// ParseUserAgent membaca browser, sistem operasi dan jenis perangkat dari
+// User-Agent. Hasilnya hanya untuk ditampilkan, bukan untuk keputusan keamanan.
+func ParseUserAgent(ua string) Device {
+	var d Device
+
+	for _, p := range browserPatterns {
+		if strings.Contains(ua, p.token) {
+			d.Browser = p.name
+			break
+		}
+	}
+
+	for _, p := range osPatterns {
+		if strings.Contains(ua, p.token) {
+			d.OS = p.name
+			break
+		}
+	}
+
+	lower := strings.ToLower(ua)
+	switch {
+	case strings.Contains(lower, "bot") || strings.Contains(lower, "crawler") || strings.Contains(lower, "spider"):
+		d.Type = "bot"
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet") ||
+		(strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile")):
+		d.Type = "tablet"
+	case strings.Contains(ua, "Mobile") || strings.Contains(ua, "iPhone"):
+		d.Type = "mobile"
+	default:
+		d.Type = "desktop"
+	}
+
+	return d
+}
@@ -0,0 +1,502 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/kreasimaju/auth/config"
+	"github.com/kreasimaju/auth/models"
+	"github.com/kreasimaju/auth/utils"
+	"gorm.io/gorm"
+)
+
+// Error yang dikembalikan oleh fungsi passkey
+var (
+	ErrWebAuthnDisabled       = errors.New("webauthn belum diaktifkan")
+	ErrInvalidWebAuthnSession = errors.New("sesi WebAuthn tidak valid atau sudah kedaluwarsa")
+	ErrInvalidPasskey         = errors.New("respons passkey tidak valid")
+	ErrPasskeyNotFound        = errors.New("passkey tidak ditemukan")
+	ErrPasskeyCloned          = errors.New("sign count passkey mundur, authenticator mungkin digandakan")
+)
+
+// Nilai Purpose models.WebAuthnSession
+const (
+	webAuthnRegister = "register"
+	webAuthnLogin    = "login"
+	webAuthnMFA      = "mfa"
+)
+
+// Jumlah byte acak dalam user handle passkey dan token sesi ceremony
+const (
+	webAuthnHandleBytes  = 32
+	webAuthnSessionBytes = 32
+)
+
+// WebAuthnCeremony berisi opsi untuk navigator.credentials.create atau
+// navigator.credentials.get di browser, serta token yang dikirim kembali
+// pada langkah finish
+type WebAuthnCeremony struct {
+	Token     string      `json:"session_token"`
+	Options   interface{} `json:"options"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// BeginPasskeyRegistration memulai pendaftaran passkey untuk pengguna yang
+// sudah login. Passkey yang sudah terdaftar dikecualikan agar authenticator
+// yang sama tidak didaftarkan dua kali.
+func (a *Auth) BeginPasskeyRegistration(userID uint) (*WebAuthnCeremony, error) {
+	if a.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	var user models.User
+	if err := a.DB().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := a.ensureWebAuthnHandle(&user); err != nil {
+		return nil, err
+	}
+
+	owner, err := a.loadWebAuthnUser(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(owner.credentials))
+	for _, credential := range owner.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := a.webAuthn.BeginRegistration(owner,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+		webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.saveWebAuthnSession(user.ID, webAuthnRegister, session, creation)
+}
+
+// FinishPasskeyRegistration memverifikasi respons navigator.credentials.create
+// dan menyimpan passkey baru. response adalah JSON PublicKeyCredential dari
+// browser dan name adalah label yang dipilih pengguna, misalnya "Laptop kantor".
+func (a *Auth) FinishPasskeyRegistration(userID uint, sessionToken, name string, response []byte) (*models.WebAuthnCredential, error) {
+	if a.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	session, err := a.consumeWebAuthnSession(sessionToken, webAuthnRegister, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := a.DB().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	owner, err := a.loadWebAuthnUser(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+	credential, err := a.webAuthn.CreateCredential(owner, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+
+	record := models.WebAuthnCredential{
+		UserID:          user.ID,
+		Name:            strings.TrimSpace(name),
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := a.DB().Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// BeginPasskeyLogin memulai login tanpa password. Browser menawarkan passkey
+// yang tersimpan untuk domain ini sehingga pengguna tidak perlu mengetik email
+// atau nomor telepon.
+func (a *Auth) BeginPasskeyLogin() (*WebAuthnCeremony, error) {
+	if a.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	// Verifikasi pengguna (PIN atau biometrik) menggantikan password
+	assertion, session, err := a.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.saveWebAuthnSession(0, webAuthnLogin, session, assertion)
+}
+
+// FinishPasskeyLogin memverifikasi respons navigator.credentials.get dari
+// BeginPasskeyLogin dan mengembalikan pemilik passkey. Passkey dengan
+// verifikasi pengguna sudah mencakup dua faktor, sehingga TOTP tidak diminta.
+func (a *Auth) FinishPasskeyLogin(sessionToken string, response []byte) (*models.User, error) {
+	if a.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	session, err := a.consumeWebAuthnSession(sessionToken, webAuthnLogin, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	var owner *webAuthnUser
+	credential, err := a.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		var user models.User
+		if err := a.DB().Where("web_authn_handle = ?", string(userHandle)).First(&user).Error; err != nil {
+			return nil, err
+		}
+		loaded, err := a.loadWebAuthnUser(&user)
+		if err != nil {
+			return nil, err
+		}
+		owner = loaded
+		return owner, nil
+	}, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	if err := a.updatePasskey(owner.user.ID, credential); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	owner.user.LastLogin = &now
+	a.DB().Model(owner.user).Update("last_login", now)
+
+	return owner.user, nil
+}
+
+// BeginWebAuthnMFA memulai verifikasi passkey sebagai langkah kedua login
+// untuk tantangan dari MFARequiredError
+func (a *Auth) BeginWebAuthnMFA(challengeToken string) (*WebAuthnCeremony, error) {
+	if a.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	_, user, err := a.openMFAChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := a.loadWebAuthnUser(user)
+	if err != nil {
+		return nil, err
+	}
+	if len(owner.credentials) == 0 {
+		return nil, ErrPasskeyNotFound
+	}
+
+	assertion, session, err := a.webAuthn.BeginLogin(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.saveWebAuthnSession(user.ID, webAuthnMFA, session, assertion)
+}
+
+// VerifyWebAuthnMFA menyelesaikan login dua langkah dengan respons
+// navigator.credentials.get dari BeginWebAuthnMFA. Respons yang tidak valid
+// dihitung sebagai percobaan gagal seperti kode TOTP yang salah.
+func (a *Auth) VerifyWebAuthnMFA(challengeToken, sessionToken string, response []byte) (*models.User, error) {
+	if a.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	challenge, user, err := a.openMFAChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	session, err := a.consumeWebAuthnSession(sessionToken, webAuthnMFA, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := a.loadWebAuthnUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var credential *webauthn.Credential
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err == nil {
+		credential, err = a.webAuthn.ValidateLogin(owner, *session, parsed)
+	}
+	if err != nil {
+		if err := a.failMFAChallenge(challenge); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	if err := a.updatePasskey(user.ID, credential); err != nil {
+		return nil, err
+	}
+	if err := a.completeMFAChallenge(challenge, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListPasskeys mengembalikan passkey milik pengguna, yang terbaru lebih dulu
+func (a *Auth) ListPasskeys(userID uint) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	err := a.DB().Where("user_id = ?", userID).Order("created_at DESC").Find(&credentials).Error
+	return credentials, err
+}
+
+// DeletePasskey menghapus passkey milik pengguna
+func (a *Auth) DeletePasskey(userID, credentialID uint) error {
+	res := a.DB().Where("id = ? AND user_id = ?", credentialID, userID).Delete(&models.WebAuthnCredential{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// updatePasskey menyimpan sign count dan flag backup setelah assertion
+// berhasil. Sign count yang tidak naik menandakan authenticator mungkin
+// digandakan; passkey tersebut ditandai dan ditolak sampai dihapus.
+func (a *Auth) updatePasskey(userID uint, credential *webauthn.Credential) error {
+	query := a.DB().Model(&models.WebAuthnCredential{}).
+		Where("user_id = ? AND credential_id = ?", userID, base64.RawURLEncoding.EncodeToString(credential.ID))
+
+	if credential.Authenticator.CloneWarning {
+		if err := query.Update("clone_warning", true).Error; err != nil {
+			return err
+		}
+		return ErrPasskeyCloned
+	}
+
+	return query.Updates(map[string]interface{}{
+		"sign_count":   credential.Authenticator.SignCount,
+		"backup_state": credential.Flags.BackupState,
+		"last_used_at": time.Now(),
+	}).Error
+}
+
+// ensureWebAuthnHandle membuat user handle acak untuk pengguna yang belum
+// pernah mendaftarkan passkey. Handle dipakai authenticator untuk menemukan
+// pengguna saat login tanpa password dan tidak memuat data pribadi.
+func (a *Auth) ensureWebAuthnHandle(user *models.User) error {
+	if user.WebAuthnHandle != "" {
+		return nil
+	}
+
+	handle, err := utils.GenerateRandomToken(webAuthnHandleBytes)
+	if err != nil {
+		return err
+	}
+
+	// Update bersyarat agar permintaan bersamaan tidak menimpa handle
+	if err := a.DB().Model(&models.User{}).
+		Where("id = ? AND web_authn_handle IS NULL", user.ID).
+		Update("web_authn_handle", handle).Error; err != nil {
+		return err
+	}
+	return a.DB().Select("web_authn_handle").First(user, user.ID).Error
+}
+
+// saveWebAuthnSession menyimpan data ceremony dan menerbitkan token untuk
+// langkah finish
+func (a *Auth) saveWebAuthnSession(userID uint, purpose string, session *webauthn.SessionData, options interface{}) (*WebAuthnCeremony, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	token, err := utils.GenerateRandomToken(webAuthnSessionBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := a.config.WebAuthn.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultWebAuthnTimeout
+	}
+	now := time.Now()
+
+	record := models.WebAuthnSession{
+		Token:     utils.HashToken(token),
+		UserID:    userID,
+		Purpose:   purpose,
+		Data:      string(data),
+		ExpiresAt: now.Add(time.Duration(timeout) * time.Second),
+	}
+	if err := a.DB().Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	// Ceremony yang kedaluwarsa tidak dibutuhkan lagi
+	a.DB().Where("expires_at <= ?", now).Delete(&models.WebAuthnSession{})
+
+	return &WebAuthnCeremony{
+		Token:     token,
+		Options:   options,
+		ExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// consumeWebAuthnSession mengambil lalu menghapus data ceremony sehingga
+// setiap challenge hanya dapat diverifikasi sekali
+func (a *Auth) consumeWebAuthnSession(token, purpose string, userID uint) (*webauthn.SessionData, error) {
+	var record models.WebAuthnSession
+	err := a.DB().Where("token = ? AND purpose = ?", utils.HashToken(token), purpose).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidWebAuthnSession
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Hapus bersyarat agar satu ceremony tidak dapat diselesaikan dua kali secara bersamaan
+	res := a.DB().Where("id = ?", record.ID).Delete(&models.WebAuthnSession{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 || record.UserID != userID || time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidWebAuthnSession
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(record.Data), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// newWebAuthn membuat relying party WebAuthn dari konfigurasi
+func newWebAuthn(cfg config.WebAuthn) (*webauthn.WebAuthn, error) {
+	displayName := cfg.RPDisplayName
+	if displayName == "" {
+		displayName = config.DefaultWebAuthnRPDisplayName
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultWebAuthnTimeout
+	}
+
+	ceremony := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    time.Duration(timeout) * time.Second,
+		TimeoutUVD: time.Duration(timeout) * time.Second,
+	}
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: displayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts:      webauthn.TimeoutsConfig{Login: ceremony, Registration: ceremony},
+	})
+}
+
+// loadWebAuthnUser memuat passkey pengguna sebagai webauthn.User
+func (a *Auth) loadWebAuthnUser(user *models.User) (*webAuthnUser, error) {
+	owner := &webAuthnUser{user: user}
+	if err := a.DB().Where("user_id = ?", user.ID).Find(&owner.credentials).Error; err != nil {
+		return nil, err
+	}
+	return owner, nil
+}
+
+// webAuthnUser menghubungkan models.User dan passkey-nya dengan interface
+// webauthn.User
+type webAuthnUser struct {
+	user        *models.User
+	credentials []models.WebAuthnCredential
+}
+
+// WebAuthnID mengembalikan user handle pengguna
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(u.user.WebAuthnHandle)
+}
+
+// WebAuthnName mengembalikan email, atau nomor telepon jika email kosong
+func (u *webAuthnUser) WebAuthnName() string {
+	if u.user.Email != "" {
+		return u.user.Email
+	}
+	return u.user.Phone
+}
+
+// WebAuthnDisplayName mengembalikan nama lengkap pengguna
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if name := strings.TrimSpace(u.user.FirstName + " " + u.user.LastName); name != "" {
+		return name
+	}
+	return u.WebAuthnName()
+}
+
+// WebAuthnIcon tidak lagi dipakai oleh spesifikasi WebAuthn
+func (u *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// WebAuthnCredentials mengubah passkey yang tersimpan menjadi webauthn.Credential
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, c := range u.credentials {
+		id, err := base64.RawURLEncoding.DecodeString(c.CredentialID)
+		if err != nil {
+			continue
+		}
+
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range strings.Split(c.Transports, ",") {
+			if transport != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: c.BackupEligible,
+				BackupState:    c.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       c.AAGUID,
+				SignCount:    c.SignCount,
+				CloneWarning: c.CloneWarning,
+			},
+		})
+	}
+	return credentials
+}